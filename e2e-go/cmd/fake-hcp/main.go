@@ -0,0 +1,270 @@
+// Command fake-hcp is a stand-in for the hcp CLI that checks the invocations of the create/destroy
+// specs without provisioning anything. Build it as `hcp` and put it first on PATH:
+//
+//	go build -o bin/hcp ./cmd/fake-hcp && export PATH=$PWD/bin:$PATH
+//
+// It supports `hcp version`, `hcp create cluster <platform>` and `hcp destroy cluster <platform>`.
+// Flags are validated with the same rules as utils.HCPCreateCluster and utils.HCPDestroyCluster,
+// and create/destroy apply or delete the matching HostedCluster and NodePool on the hub pointed
+// to by KUBECONFIG instead of provisioning any infrastructure. The hub must serve the HyperShift
+// CRDs; run cmd/fakehub against it so the applied objects are reconciled and the specs get past
+// their waits.
+//
+// When FAKE_HCP_RECORD_FILE is set, every invocation is appended to it as a JSON array of
+// arguments, one per line, so the recorded invocations can be compared with golden files.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	recordFileEnv       = "FAKE_HCP_RECORD_FILE"
+	releaseImageEnv     = "FAKE_HCP_RELEASE_IMAGE"
+	defaultReleaseImage = "quay.io/openshift-release-dev/ocp-release:4.16.0-multi"
+	defaultNamespace    = "clusters"
+	defaultReplicas     = 2
+
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+var platforms = map[string]string{
+	"aws":      utils.TYPE_AWS,
+	"kubevirt": utils.TYPE_KUBEVIRT,
+	"agent":    utils.TYPE_AGENT,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, utils.NewDynamicClient))
+}
+
+func run(args []string, stdout, stderr io.Writer, newClient func() (dynamic.Interface, error)) int {
+	if err := record(args); err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to record the hcp invocation: %v\n", err)
+		return exitError
+	}
+
+	switch {
+	case len(args) >= 1 && args[0] == "version":
+		fmt.Fprintln(stdout, "Client Version: openshift/hypershift: fake-hcp. Latest supported OCP: 4.16.0")
+		return exitOK
+	case len(args) >= 3 && args[0] == "create" && args[1] == "cluster":
+		return createCluster(args[2], args[3:], stdout, stderr, newClient)
+	case len(args) >= 3 && args[0] == "destroy" && args[1] == "cluster":
+		return destroyCluster(args[2], args[3:], stdout, stderr, newClient)
+	}
+	fmt.Fprintf(stderr, "ERROR unsupported command: hcp %s\n", strings.Join(args, " "))
+	return exitUsage
+}
+
+// record appends the invocation to the file named by FAKE_HCP_RECORD_FILE, if set.
+func record(args []string) error {
+	recordFile := os.Getenv(recordFileEnv)
+	if recordFile == "" {
+		return nil
+	}
+	line, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(recordFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\n", line)
+	return err
+}
+
+func parsePlatform(platform string, stderr io.Writer) (string, bool) {
+	platformType, ok := platforms[platform]
+	if !ok {
+		fmt.Fprintf(stderr, "ERROR unsupported platform %q\n", platform)
+	}
+	return platformType, ok
+}
+
+func parseFlags(fs *flag.FlagSet, args []string, stderr io.Writer) bool {
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "ERROR unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return false
+	}
+	return true
+}
+
+func createCluster(platform string, args []string, stdout, stderr io.Writer, newClient func() (dynamic.Interface, error)) int {
+	platformType, ok := parsePlatform(platform, stderr)
+	if !ok {
+		return exitUsage
+	}
+
+	c := utils.HCPCreateCluster{Platform: platformType}
+	fs := flag.NewFlagSet("hcp create cluster "+platform, flag.ContinueOnError)
+	fs.StringVar(&c.Name, "name", "", "")
+	fs.StringVar(&c.Namespace, "namespace", "", "")
+	fs.StringVar(&c.PullSecret, "pull-secret", "", "")
+	fs.StringVar(&c.ReleaseImage, "release-image", "", "")
+	fs.StringVar(&c.NodePoolReplicas, "node-pool-replicas", "", "")
+	fs.StringVar(&c.Arch, "arch", "", "")
+	fs.StringVar(&c.InfraAvailabilityPolicy, "infra-availability-policy", "", "")
+	fs.StringVar(&c.ControlPlaneAvailabilityPolicy, "control-plane-availability-policy", "", "")
+	fs.StringVar(&c.PausedUntil, "pausedUntil", "", "")
+	fs.BoolVar(&c.FIPS, "fips", false, "")
+	fs.BoolVar(&c.GenerateSSH, "generate-ssh", false, "")
+	fs.StringVar(&c.STSCreds, "sts-creds", "", "")
+	fs.StringVar(&c.RoleArn, "role-arn", "", "")
+	fs.StringVar(&c.SecretCreds, "secret-creds", "", "")
+	fs.StringVar(&c.Region, "region", "", "")
+	fs.StringVar(&c.InstanceType, "instance-type", "", "")
+	fs.StringVar(&c.BaseDomain, "base-domain", "", "")
+	fs.StringVar(&c.Memory, "memory", "", "")
+	fs.StringVar(&c.Cores, "cores", "", "")
+	fs.StringVar(&c.AgentNamespace, "agent-namespace", "", "")
+	if !parseFlags(fs, args, stderr) {
+		return exitUsage
+	}
+	if err := c.Validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	replicas := int64(defaultReplicas)
+	if c.NodePoolReplicas != "" {
+		n, err := strconv.ParseInt(c.NodePoolReplicas, 10, 64)
+		if err != nil || n < 0 {
+			fmt.Fprintf(stderr, "ERROR invalid --node-pool-replicas %q\n", c.NodePoolReplicas)
+			return exitUsage
+		}
+		replicas = n
+	}
+	if c.Namespace == "" {
+		c.Namespace = defaultNamespace
+	}
+	if c.ReleaseImage == "" {
+		c.ReleaseImage = os.Getenv(releaseImageEnv)
+	}
+	if c.ReleaseImage == "" {
+		c.ReleaseImage = defaultReleaseImage
+	}
+
+	client, err := newClient()
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to create the hub client: %v\n", err)
+		return exitError
+	}
+	ctx := context.TODO()
+
+	hc := fakehub.NewHostedCluster(c.Namespace, c.Name, platformType, c.ReleaseImage, c.PausedUntil)
+	_ = unstructured.SetNestedField(hc.Object, c.FIPS, "spec", "fips")
+	_ = unstructured.SetNestedField(hc.Object, c.Name+"-pull-secret", "spec", "pullSecret", "name")
+	if c.BaseDomain != "" {
+		_ = unstructured.SetNestedField(hc.Object, c.BaseDomain, "spec", "dns", "baseDomain")
+	}
+	if c.Region != "" {
+		_ = unstructured.SetNestedField(hc.Object, c.Region, "spec", "platform", "aws", "region")
+	}
+	if c.ControlPlaneAvailabilityPolicy != "" {
+		_ = unstructured.SetNestedField(hc.Object, c.ControlPlaneAvailabilityPolicy, "spec", "controllerAvailabilityPolicy")
+	}
+	if c.InfraAvailabilityPolicy != "" {
+		_ = unstructured.SetNestedField(hc.Object, c.InfraAvailabilityPolicy, "spec", "infrastructureAvailabilityPolicy")
+	}
+	if _, err := client.Resource(utils.HostedClustersGVR).Namespace(c.Namespace).Create(ctx, hc, metav1.CreateOptions{}); err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to create HostedCluster %s/%s: %v\n", c.Namespace, c.Name, err)
+		return exitError
+	}
+	fmt.Fprintf(stdout, "Applied Kube resource kind=HostedCluster namespace=%s name=%s\n", c.Namespace, c.Name)
+
+	np := fakehub.NewNodePool(c.Namespace, c.Name, c.Name, platformType, c.ReleaseImage, replicas, c.PausedUntil)
+	if c.Arch != "" {
+		_ = unstructured.SetNestedField(np.Object, c.Arch, "spec", "arch")
+	}
+	if c.InstanceType != "" {
+		_ = unstructured.SetNestedField(np.Object, c.InstanceType, "spec", "platform", "aws", "instanceType")
+	}
+	if _, err := client.Resource(utils.NodePoolsGVR).Namespace(c.Namespace).Create(ctx, np, metav1.CreateOptions{}); err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to create NodePool %s/%s: %v\n", c.Namespace, c.Name, err)
+		return exitError
+	}
+	fmt.Fprintf(stdout, "Applied Kube resource kind=NodePool namespace=%s name=%s\n", c.Namespace, c.Name)
+	return exitOK
+}
+
+func destroyCluster(platform string, args []string, stdout, stderr io.Writer, newClient func() (dynamic.Interface, error)) int {
+	platformType, ok := parsePlatform(platform, stderr)
+	if !ok {
+		return exitUsage
+	}
+
+	c := utils.HCPDestroyCluster{Platform: platformType}
+	fs := flag.NewFlagSet("hcp destroy cluster "+platform, flag.ContinueOnError)
+	fs.StringVar(&c.Name, "name", "", "")
+	fs.StringVar(&c.Namespace, "namespace", "", "")
+	fs.BoolVar(&c.DestroyCloudResources, "destroy-cloud-resources", false, "")
+	fs.StringVar(&c.STSCreds, "sts-creds", "", "")
+	fs.StringVar(&c.RoleArn, "role-arn", "", "")
+	fs.StringVar(&c.SecretCreds, "secret-creds", "", "")
+	if !parseFlags(fs, args, stderr) {
+		return exitUsage
+	}
+	if err := c.Validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if c.Namespace == "" {
+		c.Namespace = defaultNamespace
+	}
+
+	client, err := newClient()
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to create the hub client: %v\n", err)
+		return exitError
+	}
+	ctx := context.TODO()
+
+	nodePools, err := utils.ListNodePoolsForHostedCluster(client, c.Namespace, c.Name)
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to list NodePools for %s/%s: %v\n", c.Namespace, c.Name, err)
+		return exitError
+	}
+	for _, np := range nodePools {
+		err := client.Resource(utils.NodePoolsGVR).Namespace(c.Namespace).Delete(ctx, np.GetName(), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			fmt.Fprintf(stderr, "ERROR failed to delete NodePool %s/%s: %v\n", c.Namespace, np.GetName(), err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "Deleted NodePool namespace=%s name=%s\n", c.Namespace, np.GetName())
+	}
+
+	err = client.Resource(utils.HostedClustersGVR).Namespace(c.Namespace).Delete(ctx, c.Name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		fmt.Fprintf(stdout, "HostedCluster %s/%s not found, nothing to destroy\n", c.Namespace, c.Name)
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to delete HostedCluster %s/%s: %v\n", c.Namespace, c.Name, err)
+		return exitError
+	}
+	fmt.Fprintf(stdout, "Deleted HostedCluster namespace=%s name=%s\n", c.Namespace, c.Name)
+	return exitOK
+}
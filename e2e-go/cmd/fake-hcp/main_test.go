@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestRecordedInvocations runs the invocations the create/destroy specs build, with the same
+// utils helpers, and compares what fake-hcp records with the golden files, so any change to the
+// flags the specs pass to hcp shows up as a diff. Each case creates the cluster, destroys it the
+// way the destroy-one spec does, creates it again and destroys it with DestroyHostedClusters the
+// way the destroy spec does. Run `go test ./cmd/fake-hcp -update` to accept an intended change.
+func TestRecordedInvocations(t *testing.T) {
+	aws := utils.ClusterConfig{
+		ClusterName:      "acmqe-hc-aws",
+		Namespace:        "clusters",
+		AWSStsCreds:      "/tmp/sts-creds.json",
+		AWSRoleArn:       "arn:aws:iam::123456789012:role/acmqe-hcp",
+		SecretCredsName:  "qe-hs-aws-secret",
+		PullSecret:       "/tmp/pull-secret.txt",
+		BaseDomain:       "dev09.red-chesterfield.com",
+		Region:           "us-east-1",
+		NodePoolReplicas: "2",
+		InstanceType:     "m6a.xlarge",
+		ReleaseImage:     "quay.io/openshift-release-dev/ocp-release:4.16.0-multi",
+		ClusterArch:      "amd64",
+		FIPS:             true,
+	}
+	awsCurator := aws
+	awsCurator.FIPS = false
+	awsCurator.Paused = true
+	kubevirt := utils.ClusterConfig{
+		ClusterName:      "acmqe-hc-kv",
+		Namespace:        "clusters",
+		PullSecret:       "/tmp/pull-secret.txt",
+		Memory:           "8Gi",
+		Cores:            "2",
+		NodePoolReplicas: "2",
+		ReleaseImage:     "quay.io/openshift-release-dev/ocp-release:4.16.0-multi",
+	}
+
+	tests := []struct {
+		name      string
+		platform  string
+		create    utils.HCPCreateCluster
+		destroy   utils.HCPDestroyCluster
+		bulkCreds utils.HCPDestroyCluster
+	}{
+		{
+			name:      "aws_fips_sts",
+			platform:  utils.TYPE_AWS,
+			create:    utils.AWSCreateCommand(aws),
+			destroy:   utils.AWSDestroyCommand(aws),
+			bulkCreds: utils.AWSBulkDestroyCreds(aws),
+		},
+		{
+			name:      "aws_curator",
+			platform:  utils.TYPE_AWS,
+			create:    utils.AWSCreateCommand(awsCurator),
+			destroy:   utils.AWSDestroyCommand(awsCurator),
+			bulkCreds: utils.AWSBulkDestroyCreds(awsCurator),
+		},
+		{
+			name:      "kubevirt",
+			platform:  utils.TYPE_KUBEVIRT,
+			create:    utils.KubeVirtCreateCommand(kubevirt),
+			destroy:   utils.KubeVirtDestroyCommand(kubevirt),
+			bulkCreds: utils.KubeVirtBulkDestroyCreds(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordFile := filepath.Join(t.TempDir(), "invocations")
+			t.Setenv(recordFileEnv, recordFile)
+			t.Setenv(utils.ArtifactsDirEnv, t.TempDir())
+			hub := fakehub.New(fakehub.Options{})
+			newClient := func() (dynamic.Interface, error) { return hub.Dynamic, nil }
+			runHCP := func(ctx context.Context, args []string) error {
+				var stdout, stderr bytes.Buffer
+				if code := run(args, &stdout, &stderr, newClient); code != exitOK {
+					return fmt.Errorf("hcp %v exited with %d: %s", args, code, stderr.String())
+				}
+				return nil
+			}
+			runCmd := func(cmd interface{ Args() ([]string, error) }) {
+				args, err := cmd.Args()
+				if err != nil {
+					t.Fatalf("unexpected error building args: %v", err)
+				}
+				if err := runHCP(context.TODO(), args); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			runCmd(tt.create)
+			runCmd(tt.destroy)
+			runCmd(tt.create)
+			summary, err := utils.DestroyHostedClusters(context.TODO(), hub.Dynamic, hub.Kube, "", tt.platform, utils.DestroyHostedClustersOptions{
+				Creds: tt.bulkCreds,
+				Run: func(ctx context.Context, cmd utils.HCPDestroyCluster) error {
+					args, err := cmd.Args()
+					if err != nil {
+						return err
+					}
+					return runHCP(ctx, args)
+				},
+			})
+			if err != nil || summary.Err() != nil || len(summary.Destroyed()) != 1 {
+				t.Fatalf("expected the bulk destroy to destroy the cluster, got %v (%v, %v)", summary, err, summary.Err())
+			}
+
+			got, err := os.ReadFile(recordFile)
+			if err != nil {
+				t.Fatalf("failed to read the recorded invocations: %v", err)
+			}
+			golden := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("failed to update %s: %v", golden, err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", golden, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("recorded invocations differ from %s\n got:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestCreateAndDestroy(t *testing.T) {
+	hub := fakehub.New(fakehub.Options{})
+	newClient := func() (dynamic.Interface, error) { return hub.Dynamic, nil }
+	ctx := context.TODO()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"create", "cluster", "aws", "--name", "acmqe-hc-1", "--namespace", "clusters",
+		"--secret-creds", "qe-hs-aws-secret", "--pull-secret", "/tmp/pull-secret.txt",
+		"--region", "us-east-1", "--node-pool-replicas", "3", "--fips", "--pausedUntil", "true"}
+	if code := run(args, &stdout, &stderr, newClient); code != exitOK {
+		t.Fatalf("create exited with %d: %s", code, stderr.String())
+	}
+
+	hc, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Get(ctx, "acmqe-hc-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("HostedCluster was not created: %v", err)
+	}
+	if platform, _, _ := unstructured.NestedString(hc.Object, "spec", "platform", "type"); platform != utils.TYPE_AWS {
+		t.Errorf("expected platform %s, got %s", utils.TYPE_AWS, platform)
+	}
+	if pausedUntil, _, _ := unstructured.NestedString(hc.Object, "spec", "pausedUntil"); pausedUntil != "true" {
+		t.Errorf("expected pausedUntil true, got %q", pausedUntil)
+	}
+	if fips, _, _ := unstructured.NestedBool(hc.Object, "spec", "fips"); !fips {
+		t.Errorf("expected fips to be enabled")
+	}
+	if release, _ := utils.GetHostedClusterSpecRelease(hub.Dynamic, "acmqe-hc-1", "clusters"); release != defaultReleaseImage {
+		t.Errorf("expected default release image %s, got %s", defaultReleaseImage, release)
+	}
+	nodePools, err := utils.ListNodePoolsForHostedCluster(hub.Dynamic, "clusters", "acmqe-hc-1")
+	if err != nil || len(nodePools) != 1 {
+		t.Fatalf("expected one NodePool, got %d (%v)", len(nodePools), err)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(nodePools[0].Object, "spec", "replicas"); replicas != 3 {
+		t.Errorf("expected 3 replicas, got %d", replicas)
+	}
+
+	args = []string{"destroy", "cluster", "aws", "--name", "acmqe-hc-1", "--namespace", "clusters", "--secret-creds", "qe-hs-aws-secret"}
+	if code := run(args, &stdout, &stderr, newClient); code != exitOK {
+		t.Fatalf("destroy exited with %d: %s", code, stderr.String())
+	}
+	if has, _ := utils.HasResource(hub.Dynamic, utils.HostedClustersGVR, "clusters", "acmqe-hc-1"); has {
+		t.Errorf("HostedCluster was not deleted")
+	}
+	if has, _ := utils.HasResource(hub.Dynamic, utils.NodePoolsGVR, "clusters", "acmqe-hc-1"); has {
+		t.Errorf("NodePool was not deleted")
+	}
+
+	// destroying a cluster that is already gone succeeds like the real CLI
+	if code := run(args, &stdout, &stderr, newClient); code != exitOK {
+		t.Fatalf("second destroy exited with %d: %s", code, stderr.String())
+	}
+}
+
+func TestInvalidInvocations(t *testing.T) {
+	newClient := func() (dynamic.Interface, error) {
+		t.Fatalf("the hub must not be contacted for an invalid invocation")
+		return nil, nil
+	}
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "version", args: []string{"version"}, want: exitOK},
+		{name: "unknown command", args: []string{"create", "nodepool", "aws"}, want: exitUsage},
+		{name: "unknown platform", args: []string{"create", "cluster", "azure", "--name", "hc"}, want: exitUsage},
+		{name: "unknown flag", args: []string{"create", "cluster", "kubevirt", "--name", "hc", "--pull-secret", "/tmp/ps", "--zone", "a"}, want: exitUsage},
+		{name: "flag of another platform", args: []string{"create", "cluster", "kubevirt", "--name", "hc", "--pull-secret", "/tmp/ps", "--region", "us-east-1"}, want: exitUsage},
+		{name: "missing aws creds", args: []string{"create", "cluster", "aws", "--name", "hc", "--pull-secret", "/tmp/ps", "--base-domain", "example.com"}, want: exitUsage},
+		{name: "invalid replicas", args: []string{"create", "cluster", "kubevirt", "--name", "hc", "--pull-secret", "/tmp/ps", "--node-pool-replicas", "two"}, want: exitUsage},
+		{name: "destroy without name", args: []string{"destroy", "cluster", "kubevirt"}, want: exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := run(tt.args, &stdout, &stderr, newClient); got != tt.want {
+				t.Errorf("expected exit code %d, got %d (stderr: %s)", tt.want, got, stderr.String())
+			}
+		})
+	}
+}
+
+// TestHermeticRun runs fake-hcp with the fakehub controllers against an envtest API server, the
+// hermetic run of the README: the cluster of the KubeVirt create spec invocation becomes
+// available and is imported with its add-ons available, and the KubeVirt destroy-one spec
+// invocation removes it, its ManagedCluster and its namespaces. It needs the envtest binaries, e.g.
+// KUBEBUILDER_ASSETS=$(setup-envtest use 1.28.x -p path).
+func TestHermeticRun(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	env := &envtest.Environment{}
+	cfg, err := env.Start()
+	if err != nil {
+		t.Fatalf("failed to start envtest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := env.Stop(); err != nil {
+			t.Errorf("failed to stop envtest: %v", err)
+		}
+	})
+	dynamicClient := dynamic.NewForConfigOrDie(cfg)
+	if err := fakehub.InstallCRDs(context.TODO(), apiextensionsclient.NewForConfigOrDie(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	kubeClient := kubernetes.NewForConfigOrDie(cfg)
+	hub := fakehub.NewForClients(dynamicClient, kubeClient, fakehub.Options{StepDelay: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		hub.Wait()
+	})
+	if err := hub.Seed(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	kubevirt := utils.ClusterConfig{
+		ClusterName:      "acmqe-hc-kv",
+		Namespace:        "clusters",
+		PullSecret:       "/tmp/pull-secret.txt",
+		Memory:           "8Gi",
+		Cores:            "2",
+		NodePoolReplicas: "2",
+	}
+	newClient := func() (dynamic.Interface, error) { return dynamicClient, nil }
+	runCmd := func(cmd interface{ Args() ([]string, error) }) {
+		t.Helper()
+		args, err := cmd.Args()
+		if err != nil {
+			t.Fatalf("unexpected error building args: %v", err)
+		}
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr, newClient); code != exitOK {
+			t.Fatalf("hcp %v exited with %d: %s", args, code, stderr.String())
+		}
+	}
+	waitFor := func(what string, check func() error) {
+		t.Helper()
+		deadline := time.Now().Add(time.Minute)
+		for err := check(); err != nil; err = check() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s: %v", what, err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	runCmd(utils.KubeVirtCreateCommand(kubevirt))
+	waitFor("the hosted control plane", func() error {
+		return utils.CheckHCPAvailable(dynamicClient, kubevirt.ClusterName, kubevirt.Namespace)
+	})
+	waitFor("the import", func() error { return utils.CheckClusterImported(dynamicClient, kubevirt.ClusterName) })
+	for _, addon := range utils.MceManagedClusterAddOns {
+		waitFor("the "+addon+" add-on", func() error {
+			return utils.ValidateClusterAddOnAvailable(dynamicClient, kubevirt.ClusterName, addon)
+		})
+	}
+
+	runCmd(utils.KubeVirtDestroyCommand(kubevirt))
+	waitFor("the ManagedCluster to be removed", func() error {
+		if has, err := utils.HasResource(dynamicClient, utils.ManagedClustersGVR, "", kubevirt.ClusterName); err != nil || has {
+			return fmt.Errorf("ManagedCluster %s still exists (%v)", kubevirt.ClusterName, err)
+		}
+		return nil
+	})
+	waitFor("the cleanup", func() error {
+		leftovers, err := utils.CheckClusterCleanup(context.TODO(), dynamicClient, kubeClient, kubevirt.Namespace, kubevirt.ClusterName)
+		if err == nil && len(leftovers) != 0 {
+			err = fmt.Errorf("left over: %v", leftovers)
+		}
+		return err
+	})
+}
@@ -0,0 +1,180 @@
+// Command fakehub runs the simulated controllers of pkg/fakehub against the API server of a
+// kubeconfig instead of in-memory fakes, so the create/destroy specs can run end to end against
+// an envtest or kind API server with fake-hcp as hcp:
+//
+//	go run ./cmd/fakehub run -kubeconfig /tmp/kind/kubeconfig -install-crds
+//
+// With -envtest it starts an envtest API server itself, from the binaries in KUBEBUILDER_ASSETS,
+// writes its admin kubeconfig to the given file for the specs and installs the CRDs:
+//
+//	go run ./cmd/fakehub run -envtest /tmp/fakehub.kubeconfig
+//
+// run installs the CRDs of fakehub.CRDs when -install-crds is set, seeds the hub the way
+// fakehub.New seeds its fakes (a MultiClusterEngine, a healthy hypershift operator, the
+// hypershift addon manager and an Available hypershift-addon on local-cluster) and reconciles the
+// HostedClusters, NodePools, ManagedClusters, add-ons and ClusterCurators until interrupted.
+// Do not point it at a real hub: it reports the HostedClusters available and imported.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// clients are the clients of the API server the hub runs against.
+type clients struct {
+	dynamic       dynamic.Interface
+	kube          kubernetes.Interface
+	apiextensions apiextensionsclient.Interface
+}
+
+type connectFunc func(kubeconfig string) (clients, error)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr, connect))
+}
+
+// connect returns the clients of the kubeconfig, else of KUBECONFIG or ~/.kube/config.
+func connect(kubeconfig string) (clients, error) {
+	var cfg *rest.Config
+	var err error
+	if kubeconfig != "" {
+		cfg, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	} else {
+		cfg, err = utils.NewKubeConfig()
+	}
+	if err != nil {
+		return clients{}, err
+	}
+	c := clients{}
+	if c.dynamic, err = dynamic.NewForConfig(cfg); err != nil {
+		return clients{}, err
+	}
+	if c.kube, err = kubernetes.NewForConfig(cfg); err != nil {
+		return clients{}, err
+	}
+	if c.apiextensions, err = apiextensionsclient.NewForConfig(cfg); err != nil {
+		return clients{}, err
+	}
+	return c, nil
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, connect connectFunc) int {
+	if len(args) == 0 || args[0] != "run" {
+		fmt.Fprintln(stderr, "usage: fakehub run [-kubeconfig <file> [-install-crds] | -envtest <kubeconfig>] [-step-delay 50ms] [-acm] [-mce-version <version>]")
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("fakehub run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	kubeconfig := fs.String("kubeconfig", "", "kubeconfig of the API server, default KUBECONFIG or ~/.kube/config")
+	installCRDs := fs.Bool("install-crds", false, "install the CRDs of the resources the hub reconciles")
+	envtestKubeconfig := fs.String("envtest", "", "start an envtest API server, write its kubeconfig to this file and install the CRDs")
+	stepDelay := fs.Duration("step-delay", 0, "pause between two simulated condition transitions, default 50ms")
+	acm := fs.Bool("acm", false, "seed a MultiClusterHub so the hub is detected as ACM")
+	mceVersion := fs.String("mce-version", "", "status.currentVersion of the seeded MultiClusterEngine")
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(stderr, "ERROR unexpected arguments: %v\n", fs.Args())
+		return exitUsage
+	}
+	if *envtestKubeconfig != "" && *kubeconfig != "" {
+		fmt.Fprintln(stderr, "ERROR -envtest and -kubeconfig are mutually exclusive")
+		return exitUsage
+	}
+
+	if *envtestKubeconfig != "" {
+		stop, err := startEnvtest(*envtestKubeconfig)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		defer func() {
+			if err := stop(); err != nil {
+				fmt.Fprintf(stderr, "ERROR failed to stop envtest: %v\n", err)
+			}
+		}()
+		fmt.Fprintf(stdout, "envtest API server running, kubeconfig written to %s\n", *envtestKubeconfig)
+		*kubeconfig = *envtestKubeconfig
+		*installCRDs = true
+	}
+
+	c, err := connect(*kubeconfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to create the clients: %v\n", err)
+		return exitError
+	}
+	if *installCRDs {
+		if err := fakehub.InstallCRDs(ctx, c.apiextensions); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "Installed %d CRDs\n", len(fakehub.CRDs()))
+	}
+
+	hub := fakehub.NewForClients(c.dynamic, c.kube, fakehub.Options{
+		StepDelay:  *stepDelay,
+		ACM:        *acm,
+		MCEVersion: *mceVersion,
+	})
+	if err := hub.Seed(ctx); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if err := hub.Start(ctx); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	fmt.Fprintln(stdout, "Fake hub running, interrupt to stop")
+	<-ctx.Done()
+	hub.Wait()
+	return exitOK
+}
+
+// startEnvtest starts an envtest API server and writes the kubeconfig of a cluster admin to the
+// file. The returned func stops the API server and removes the file.
+func startEnvtest(kubeconfig string) (func() error, error) {
+	env := &envtest.Environment{}
+	if _, err := env.Start(); err != nil {
+		return nil, fmt.Errorf("ERROR failed to start envtest, is KUBEBUILDER_ASSETS set? %v", err)
+	}
+	stop := func() error {
+		os.Remove(kubeconfig)
+		return env.Stop()
+	}
+	user, err := env.AddUser(envtest.User{Name: "fakehub", Groups: []string{"system:masters"}}, nil)
+	if err == nil {
+		var data []byte
+		if data, err = user.KubeConfig(); err == nil {
+			err = os.WriteFile(kubeconfig, data, 0600)
+		}
+	}
+	if err != nil {
+		stop()
+		return nil, fmt.Errorf("ERROR failed to write the envtest kubeconfig %s: %v", kubeconfig, err)
+	}
+	return stop, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"serve"}, {"run", "extra"}, {"run", "-unknown"}, {"run", "-envtest", "/tmp/a", "-kubeconfig", "/tmp/b"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(context.TODO(), args, &stdout, &stderr, nil); code != exitUsage {
+			t.Errorf("fakehub %v: expected exit %d, got %d", args, exitUsage, code)
+		}
+	}
+}
+
+// TestRunSeedsAndReconciles runs the hub against clients that start empty, as an API server
+// does, and checks that it seeds the hub objects and makes a HostedCluster available.
+func TestRunSeedsAndReconciles(t *testing.T) {
+	c := clients{
+		dynamic: dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), fakehub.ListKinds()),
+		kube:    kubefake.NewSimpleClientset(),
+	}
+	var gotKubeconfig string
+	connect := func(kubeconfig string) (clients, error) {
+		gotKubeconfig = kubeconfig
+		return c, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exited := make(chan int)
+	var stdout, stderr bytes.Buffer
+	go func() {
+		exited <- run(ctx, []string{"run", "-kubeconfig", "/tmp/kubeconfig", "-step-delay", "5ms", "-mce-version", "2.6.0"}, &stdout, &stderr, connect)
+	}()
+
+	waitFor := func(what string, check func() error) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for err := check(); err != nil; err = check() {
+			if time.Now().After(deadline) {
+				cancel()
+				t.Fatalf("timed out waiting for %s: %v (stderr: %s)", what, err, stderr.String())
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitFor("the hypershift operator", func() error { return utils.IsHypershiftOperatorHealthy(c.kube) })
+	waitFor("the hypershift-addon", func() error {
+		return utils.ValidateClusterAddOnAvailable(c.dynamic, utils.LocalClusterName, utils.HypershiftAddonName)
+	})
+	if namespace, err := utils.GetMCENamespace(c.dynamic); err != nil || namespace != "multicluster-engine" {
+		t.Errorf("expected the seeded MCE in multicluster-engine, got %q (%v)", namespace, err)
+	}
+	mce, err := c.dynamic.Resource(utils.MultiClusterEngineGVR).Get(context.TODO(), "multiclusterengine", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the seeded MCE: %v", err)
+	}
+	if version, _, _ := unstructured.NestedString(mce.Object, "status", "currentVersion"); version != "2.6.0" {
+		t.Errorf("expected the seeded MCE version 2.6.0, got %q", version)
+	}
+
+	hc := fakehub.NewHostedCluster("clusters", "acmqe-hc-1", utils.TYPE_KUBEVIRT, "quay.io/openshift-release-dev/ocp-release:4.16.0-multi", "")
+	if _, err := c.dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(context.TODO(), hc, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create the HostedCluster: %v", err)
+	}
+	waitFor("the HostedCluster", func() error { return utils.CheckHCPAvailable(c.dynamic, "acmqe-hc-1", "clusters") })
+	waitFor("the import", func() error { return utils.CheckClusterImported(c.dynamic, "acmqe-hc-1") })
+
+	cancel()
+	if code := <-exited; code != exitOK {
+		t.Errorf("expected exit %d once interrupted, got %d (stderr: %s)", exitOK, code, stderr.String())
+	}
+	if gotKubeconfig != "/tmp/kubeconfig" {
+		t.Errorf("expected the clients of -kubeconfig, got %q", gotKubeconfig)
+	}
+}
@@ -0,0 +1,132 @@
+// Command hc-janitor destroys the stale HostedClusters the suite left on a shared hub, e.g. after
+// an aborted run. Only HostedClusters carrying the owner label the create specs set are
+// considered, by default only those of the owner of the suite; -owner '*' selects every owner:
+//
+//	go run ./cmd/hc-janitor -max-age 24h -owner acmqe -dry-run
+//
+// Each stale HostedCluster is destroyed in turn with `hcp destroy cluster <platform>`, using the
+// AWS credentials of the flags for AWS clusters. -dry-run only lists them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type destroyFunc func(ctx context.Context, cmd utils.HCPDestroyCluster) error
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, utils.NewDynamicClient, destroyWithHCP))
+}
+
+func destroyWithHCP(ctx context.Context, cmd utils.HCPDestroyCluster) error {
+	_, err := cmd.Run(ctx)
+	return err
+}
+
+func run(args []string, stdout, stderr io.Writer, newClient func() (dynamic.Interface, error), destroy destroyFunc) int {
+	fs := flag.NewFlagSet("hc-janitor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	maxAge := fs.Duration("max-age", 24*time.Hour, "destroy the HostedClusters created at least this long ago")
+	owners := fs.String("owner", "", fmt.Sprintf("only destroy the HostedClusters of these owners, comma separated, default %s; '%s' for every owner", utils.GetOwner(), utils.AllOwners))
+	keepRunIDs := fs.String("keep-run-id", "", "keep the HostedClusters of these runs, comma separated")
+	selector := fs.String("selector", "", "extra label selector of the HostedClusters")
+	dryRun := fs.Bool("dry-run", false, "only list the stale HostedClusters")
+	timeout := fs.Duration("timeout", 30*time.Minute, "timeout of each hcp destroy")
+	stsCreds := fs.String("sts-creds", os.Getenv("AWS_STS_CREDS_FILE_PATH"), "hcp --sts-creds of AWS clusters")
+	roleArn := fs.String("role-arn", os.Getenv("AWS_ROLE_ARN"), "hcp --role-arn of AWS clusters")
+	secretCreds := fs.String("secret-creds", os.Getenv("SECRET_AWS_CRED_NAME"), "hcp --secret-creds of AWS clusters, instead of -sts-creds and -role-arn")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: hc-janitor [-max-age 24h] [-owner <owner>,...] [-keep-run-id <id>,...] [-selector <selector>] [-dry-run]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := newClient()
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR failed to create the hub client: %v\n", err)
+		return exitError
+	}
+	policy := utils.JanitorPolicy{
+		MaxAge:     *maxAge,
+		Owners:     splitList(*owners),
+		KeepRunIDs: splitList(*keepRunIDs),
+		Selector:   *selector,
+	}
+	stale, err := utils.FindStaleHostedClusters(client, policy, time.Now())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tPLATFORM\tOWNER\tRUN ID\tAGE")
+	for _, cluster := range stale {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", cluster.Namespace, cluster.Name, cluster.Platform,
+			cluster.Owner, cluster.RunID, cluster.Age.Round(time.Minute))
+	}
+	w.Flush()
+	if *dryRun {
+		fmt.Fprintf(stdout, "Dry run: %d stale HostedCluster(s) would be destroyed\n", len(stale))
+		return exitOK
+	}
+
+	creds := utils.HCPDestroyCluster{STSCreds: *stsCreds, RoleArn: *roleArn, SecretCreds: *secretCreds}
+	if *secretCreds != "" {
+		creds.STSCreds, creds.RoleArn = "", ""
+	}
+	failed := 0
+	for _, cluster := range stale {
+		cmd := cluster.DestroyCommand(creds)
+		if err := cmd.Validate(); err != nil {
+			fmt.Fprintf(stderr, "Cluster %s/%s: %v\n", cluster.Namespace, cluster.Name, err)
+			failed++
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err := destroy(ctx, cmd)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(stderr, "Cluster %s/%s: %v\n", cluster.Namespace, cluster.Name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "Cluster %s/%s: destroyed\n", cluster.Namespace, cluster.Name)
+	}
+	fmt.Fprintf(stdout, "Destroyed %d of %d stale HostedCluster(s)\n", len(stale)-failed, len(stale))
+	if failed > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+)
+
+func newHub(t *testing.T) *fakehub.Hub {
+	t.Helper()
+	hub := fakehub.New(fakehub.Options{})
+	clusters := []struct {
+		name, platform, owner, runID string
+		age                          time.Duration
+	}{
+		{name: "acmqe-hc-old", platform: utils.TYPE_AWS, owner: "acmqe", runID: "101", age: 50 * time.Hour},
+		{name: "acmqe-hc-kv", platform: utils.TYPE_KUBEVIRT, owner: "acmqe", runID: "102", age: 30 * time.Hour},
+		{name: "acmqe-hc-new", platform: utils.TYPE_AWS, owner: "acmqe", runID: "103", age: time.Hour},
+		{name: "acmqe-hc-running", platform: utils.TYPE_AWS, owner: "acmqe", runID: "104", age: 40 * time.Hour},
+		{name: "dev-hc", platform: utils.TYPE_AWS, owner: "dev", runID: "1", age: 60 * time.Hour},
+		{name: "manual-hc", platform: utils.TYPE_AWS, age: 90 * time.Hour},
+	}
+	for _, c := range clusters {
+		hc := fakehub.NewHostedCluster("clusters", c.name, c.platform, "quay.io/openshift-release-dev/ocp-release:4.16.0-multi", "")
+		if c.owner != "" {
+			hc.SetLabels(map[string]string{utils.OwnerLabel: c.owner, utils.RunIDLabel: c.runID})
+		}
+		hc.SetAnnotations(map[string]string{utils.CreatedAtAnnotation: time.Now().Add(-c.age).UTC().Format(time.RFC3339)})
+		if _, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(context.TODO(), hc, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return hub
+}
+
+func TestRun(t *testing.T) {
+	hub := newHub(t)
+	newClient := func() (dynamic.Interface, error) { return hub.Dynamic, nil }
+	destroyed := [][]string{}
+	destroy := func(ctx context.Context, cmd utils.HCPDestroyCluster) error {
+		args, err := cmd.Args()
+		if err != nil {
+			return err
+		}
+		destroyed = append(destroyed, args)
+		return hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace(cmd.Namespace).Delete(ctx, cmd.Name, metav1.DeleteOptions{})
+	}
+	args := []string{"-max-age", "24h", "-owner", "acmqe", "-keep-run-id", "104", "-secret-creds", "qe-hs-aws-secret"}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(append(args, "-dry-run"), &stdout, &stderr, newClient, destroy); code != exitOK {
+		t.Fatalf("dry run exited with %d: %s", code, stderr.String())
+	}
+	if len(destroyed) != 0 {
+		t.Fatalf("a dry run must not destroy anything, destroyed %v", destroyed)
+	}
+	for _, want := range []string{"acmqe-hc-old", "acmqe-hc-kv", "2 stale HostedCluster(s) would be destroyed"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in the dry run output:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := run(args, &stdout, &stderr, newClient, destroy); code != exitOK {
+		t.Fatalf("janitor exited with %d: %s", code, stderr.String())
+	}
+	want := [][]string{
+		{"destroy", "cluster", "aws", "--name", "acmqe-hc-old", "--namespace", "clusters", "--secret-creds", "qe-hs-aws-secret"},
+		{"destroy", "cluster", "kubevirt", "--name", "acmqe-hc-kv", "--namespace", "clusters"},
+	}
+	if !reflect.DeepEqual(destroyed, want) {
+		t.Errorf("expected the oldest stale clusters to be destroyed first\n got: %v\nwant: %v", destroyed, want)
+	}
+	for _, name := range []string{"acmqe-hc-new", "acmqe-hc-running", "dev-hc", "manual-hc"} {
+		if has, _ := utils.HasResource(hub.Dynamic, utils.HostedClustersGVR, "clusters", name); !has {
+			t.Errorf("expected %s to be kept", name)
+		}
+	}
+}
+
+func TestRunOwners(t *testing.T) {
+	hub := newHub(t)
+	newClient := func() (dynamic.Interface, error) { return hub.Dynamic, nil }
+	for _, tc := range []struct {
+		owner string
+		want  string
+	}{
+		// the owner of the suite, who created none of them
+		{owner: "", want: "0 stale HostedCluster(s) would be destroyed"},
+		{owner: "*", want: "4 stale HostedCluster(s) would be destroyed"},
+		{owner: "dev,acmqe", want: "4 stale HostedCluster(s) would be destroyed"},
+	} {
+		var stdout, stderr bytes.Buffer
+		args := []string{"-max-age", "24h", "-dry-run"}
+		if tc.owner != "" {
+			args = append(args, "-owner", tc.owner)
+		}
+		if code := run(args, &stdout, &stderr, newClient, nil); code != exitOK {
+			t.Fatalf("-owner %q exited with %d: %s", tc.owner, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tc.want) {
+			t.Errorf("-owner %q: expected %q in the output:\n%s", tc.owner, tc.want, stdout.String())
+		}
+	}
+}
+
+func TestRunDestroyFailure(t *testing.T) {
+	hub := newHub(t)
+	newClient := func() (dynamic.Interface, error) { return hub.Dynamic, nil }
+	destroy := func(ctx context.Context, cmd utils.HCPDestroyCluster) error {
+		return errors.New("hcp destroy cluster failed")
+	}
+
+	var stdout, stderr bytes.Buffer
+	// AWS clusters cannot be destroyed without credentials, the others are still attempted
+	code := run([]string{"-max-age", "24h", "-owner", "acmqe", "-sts-creds", "", "-role-arn", "", "-secret-creds", ""}, &stdout, &stderr, newClient, destroy)
+	if code != exitError {
+		t.Fatalf("expected exit code %d, got %d", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), "require --sts-creds and --role-arn") || !strings.Contains(stderr.String(), "clusters/acmqe-hc-kv: hcp destroy cluster failed") {
+		t.Errorf("expected every failure to be reported:\n%s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Destroyed 0 of 3 stale HostedCluster(s)") {
+		t.Errorf("unexpected summary:\n%s", stdout.String())
+	}
+}
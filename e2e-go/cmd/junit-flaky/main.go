@@ -0,0 +1,96 @@
+// Command junit-flaky reads the JUnit reports of past runs, as written by
+// utils.GenerateJUnitReport, and reports the pass/fail/skip history and the duration trend of
+// every spec:
+//
+//	go run ./cmd/junit-flaky -format markdown -o results/flaky.md <dir>...
+//
+// Reports are searched recursively. A spec is flaky when it both passed and failed on the same hub
+// build, identified by the mce.version and acm.version properties of the reports.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+	// exitFlaky is returned with -fail-on-flaky when a flaky spec was found
+	exitFlaky = 3
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("junit-flaky", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "markdown", "report format: markdown or json")
+	output := fs.String("o", "", "path of the report, default stdout")
+	failOnFlaky := fs.Bool("fail-on-flaky", false, "exit with code 3 when a flaky spec is found")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: junit-flaky [-format markdown|json] [-o <report>] <dir>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 || (*format != "markdown" && *format != "json") {
+		fs.Usage()
+		return exitUsage
+	}
+
+	runs := []utils.SpecRun{}
+	files := 0
+	for _, dir := range fs.Args() {
+		dirRuns, dirFiles, err := utils.ReadSpecRuns(dir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		runs = append(runs, dirRuns...)
+		files += dirFiles
+	}
+	report := utils.AnalyzeSpecRuns(runs, files)
+
+	var data []byte
+	if *format == "json" {
+		var err error
+		if data, err = json.MarshalIndent(report, "", "  "); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		data = append(data, '\n')
+	} else {
+		data = []byte(report.Markdown())
+	}
+
+	if *output == "" {
+		_, _ = stdout.Write(data)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(*output), 0o755); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		if err := os.WriteFile(*output, data, 0o644); err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "Wrote the history of %d specs from %d files to %s: %d flaky\n",
+			len(report.Specs), report.Files, *output, len(report.Flaky()))
+	}
+
+	if *failOnFlaky && len(report.Flaky()) > 0 {
+		return exitFlaky
+	}
+	return exitOK
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+)
+
+func writeRuns(t *testing.T, states ...types.SpecState) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, state := range states {
+		report := types.Report{SuiteDescription: "Hypershift E2e Suite", StartTime: time.Date(2024, 4, i+1, 2, 0, 0, 0, time.UTC), SpecReports: types.SpecReports{
+			{LeafNodeType: types.NodeTypeIt, LeafNodeText: "Creates a KubeVirt Hosted Cluster", State: state, RunTime: time.Minute},
+		}}
+		dst := filepath.Join(dir, time.Duration(i).String(), "create_cluster_result.xml")
+		if err := utils.GenerateJUnitReport(report, dst, utils.RunMetadata{MCEVersion: "2.6.0"}.Properties()...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeRuns(t, types.SpecStatePassed, types.SpecStateFailed, types.SpecStatePassed)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{dir}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "| Creates a KubeVirt Hosted Cluster | `PFP` | 2 | mce.version=2.6.0 |") {
+		t.Errorf("expected the flaky spec in the markdown report:\n%s", stdout.String())
+	}
+
+	output := filepath.Join(t.TempDir(), "flaky", "flaky.json")
+	stdout.Reset()
+	if code := run([]string{"-format", "json", "-o", output, "-fail-on-flaky", dir}, &stdout, &stderr); code != exitFlaky {
+		t.Fatalf("expected exit code %d, got %d: %s", exitFlaky, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Wrote the history of 1 specs from 3 files") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report utils.FlakyReport
+	if err := json.Unmarshal(data, &report); err != nil || len(report.Flaky()) != 1 {
+		t.Errorf("expected one flaky spec in the JSON report, got %s (%v)", data, err)
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no input", args: []string{}, code: exitUsage},
+		{name: "unknown format", args: []string{"-format", "html", "results"}, code: exitUsage},
+		{name: "missing input", args: []string{"does-not-exist"}, code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.code {
+				t.Errorf("expected exit code %d, got %d: %s", tt.code, code, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Command junit-merge combines the JUnit reports of the create, e2e and destroy stages into a
+// single <testsuites> document with aggregate counts:
+//
+//	go run ./cmd/junit-merge -o results/merged/junit.xml results/*.xml
+//
+// Each input may hold one or more <testsuite> elements, as written by utils.GenerateJUnitReport,
+// or a <testsuites> document. Suites are kept in the order of the inputs, with their properties.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("junit-merge", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "path of the merged JUnit report")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: junit-merge -o <merged.xml> <report.xml>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *output == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	// skip the output when a glob such as results/*.xml matches a previous merge
+	inputs := []string{}
+	for _, input := range fs.Args() {
+		if filepath.Clean(input) != filepath.Clean(*output) {
+			inputs = append(inputs, input)
+		}
+	}
+
+	merged, err := utils.MergeJUnitReports(*output, inputs...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	fmt.Fprintf(stdout, "Merged %d test suites from %d files into %s: %d tests, %d failures, %d errors, %d skipped\n",
+		len(merged.TestSuites), len(inputs), *output, merged.Tests, merged.Failures, merged.Errors, merged.Skipped)
+	return exitOK
+}
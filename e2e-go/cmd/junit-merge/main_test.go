@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	for _, stage := range []string{"create_cluster_result", "destroy_cluster_result"} {
+		report := types.Report{SuiteDescription: "Hypershift E2e Suite", SpecReports: types.SpecReports{
+			{LeafNodeType: types.NodeTypeIt, LeafNodeText: stage, State: types.SpecStatePassed, RunTime: time.Second},
+		}}
+		if err := utils.GenerateJUnitReport(report, filepath.Join(dir, stage+".xml")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := filepath.Join(dir, "junit.xml")
+	if err := os.WriteFile(output, []byte("previous merge"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run(append([]string{"-o", output}, inputs...), &stdout, &stderr); code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Merged 2 test suites from 2 files") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+	suites, err := utils.ReadJUnitReport(output)
+	if err != nil || len(suites) != 2 {
+		t.Errorf("expected 2 merged suites, got %v (%v)", suites, err)
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{name: "no output", args: []string{"results/create_cluster_result.xml"}, code: exitUsage},
+		{name: "no input", args: []string{"-o", "results/junit.xml"}, code: exitUsage},
+		{name: "unknown flag", args: []string{"-x"}, code: exitUsage},
+		{name: "missing input", args: []string{"-o", filepath.Join(t.TempDir(), "junit.xml"), "does-not-exist.xml"}, code: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.code {
+				t.Errorf("expected exit code %d, got %d: %s", tt.code, code, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Command tower-stub serves a stand-in for the Ansible Tower/AAP API, so the curator hooks can run
+// without a shared AAP. Run it locally or in the hub (see pkg/resources/towerstub/script.yaml):
+//
+//	go run ./cmd/tower-stub -addr :8080 -script script.yaml
+//
+// Every job and workflow template exists. The script sets how their jobs end, e.g.
+//
+//	default:
+//	  status: successful
+//	templates:
+//	  Auto_CLC_Sample_Template:
+//	    status: failed
+//	    duration: 2m
+//
+// and PUT /stub/outcomes/<template> changes it while the stub runs. The token the requests must
+// carry is -token, else TOWER_STUB_TOKEN, else towerstub.DefaultToken.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/towerstub"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	tokenEnv = "TOWER_STUB_TOKEN"
+
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type serveFunc func(addr string, handler http.Handler) error
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, http.ListenAndServe))
+}
+
+func run(args []string, stdout, stderr io.Writer, serve serveFunc) int {
+	fs := flag.NewFlagSet("tower-stub", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	token := fs.String("token", os.Getenv(tokenEnv), "bearer token the requests must carry, default "+towerstub.DefaultToken)
+	scriptFile := fs.String("script", "", "YAML file with the outcome of the jobs of each template")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tower-stub [-addr :8080] [-token <token>] [-script <file>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	script := towerstub.Script{}
+	if *scriptFile != "" {
+		data, err := os.ReadFile(*scriptFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "ERROR failed to read the script: %v\n", err)
+			return exitError
+		}
+		if err := yaml.UnmarshalStrict(data, &script); err != nil {
+			fmt.Fprintf(stderr, "ERROR failed to parse the script %s: %v\n", *scriptFile, err)
+			return exitError
+		}
+		if err := script.Validate(); err != nil {
+			fmt.Fprintf(stderr, "ERROR invalid script %s: %v\n", *scriptFile, err)
+			return exitError
+		}
+	}
+
+	fmt.Fprintf(stdout, "tower-stub listening on %s, %d scripted template(s)\n", *addr, len(script.Templates))
+	if err := serve(*addr, towerstub.New(towerstub.Options{Token: *token, Script: script})); err != nil {
+		fmt.Fprintf(stderr, "ERROR %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
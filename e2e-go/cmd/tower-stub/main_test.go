@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeScript := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	valid := writeScript("valid.yaml", "default:\n  status: successful\ntemplates:\n  Auto_CLC_Sample_Template:\n    status: failed\n    duration: 2m\n")
+	badStatus := writeScript("bad-status.yaml", "templates:\n  Auto_CLC_Sample_Template:\n    status: flaky\n")
+	badField := writeScript("bad-field.yaml", "templates:\n  Auto_CLC_Sample_Template:\n    result: failed\n")
+
+	tests := []struct {
+		name       string
+		args       []string
+		serveErr   error
+		wantCode   int
+		wantAddr   string
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "defaults", wantCode: exitOK, wantAddr: ":8080", wantStdout: "tower-stub listening on :8080, 0 scripted template(s)"},
+		{name: "script", args: []string{"-addr", ":9090", "-script", valid}, wantCode: exitOK, wantAddr: ":9090", wantStdout: "1 scripted template(s)"},
+		{name: "invalid status", args: []string{"-script", badStatus}, wantCode: exitError, wantStderr: `unknown job status "flaky"`},
+		{name: "unknown field", args: []string{"-script", badField}, wantCode: exitError, wantStderr: "failed to parse the script"},
+		{name: "missing script", args: []string{"-script", filepath.Join(dir, "missing.yaml")}, wantCode: exitError, wantStderr: "failed to read the script"},
+		{name: "serve error", serveErr: errors.New("address already in use"), wantCode: exitError, wantAddr: ":8080", wantStderr: "ERROR address already in use"},
+		{name: "extra argument", args: []string{"now"}, wantCode: exitUsage, wantStderr: "usage: tower-stub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			servedAddr := ""
+			serve := func(addr string, handler http.Handler) error {
+				servedAddr = addr
+				// the stub answers with the default token
+				req := httptest.NewRequest(http.MethodGet, "/api/v2/me/", nil)
+				req.Header.Set("Authorization", "Bearer tower-stub-token")
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					t.Errorf("GET /api/v2/me/ = %d", rec.Code)
+				}
+				return tt.serveErr
+			}
+			t.Setenv(tokenEnv, "")
+			code := run(tt.args, &stdout, &stderr, serve)
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			if servedAddr != tt.wantAddr {
+				t.Errorf("served on %q, want %q", servedAddr, tt.wantAddr)
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ module github.com/stolostron/hypershift-addon-e2e-tests/e2e-go
 go 1.20
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/onsi/ginkgo/v2 v2.17.1
 	github.com/onsi/gomega v1.30.0
 	github.com/openshift/client-go v0.0.0-20231005121823-e81400b97c46
@@ -21,8 +22,8 @@ require (
 
 require (
 	github.com/Masterminds/goutils v1.1.1 // indirect
-	github.com/Masterminds/semver/v3 v3.1.1 // indirect
 	github.com/Masterminds/sprig/v3 v3.2.2 // indirect
+	github.com/evanphx/json-patch v4.12.0+incompatible // indirect
 	github.com/evanphx/json-patch/v5 v5.6.0 // indirect
 	github.com/ghodss/yaml v1.0.0 // indirect
 	github.com/huandu/xstrings v1.3.2 // indirect
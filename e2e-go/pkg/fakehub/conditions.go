@@ -0,0 +1,78 @@
+package fakehub
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newCondition(condType string, status metav1.ConditionStatus, reason, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":               condType,
+		"status":             string(status),
+		"reason":             reason,
+		"message":            message,
+		"lastTransitionTime": time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func getCondition(obj *unstructured.Unstructured, condType string) map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == condType {
+			return condition
+		}
+	}
+	return nil
+}
+
+func isConditionTrue(obj *unstructured.Unstructured, condType string) bool {
+	condition := getCondition(obj, condType)
+	return condition != nil && condition["status"] == string(metav1.ConditionTrue)
+}
+
+// setCondition adds or updates the condition on the object status and reports whether
+// anything changed. lastTransitionTime only moves when the status flips.
+func setCondition(obj *unstructured.Unstructured, condType string, status metav1.ConditionStatus, reason, message string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for i, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != condType {
+			continue
+		}
+		if condition["status"] == string(status) && condition["reason"] == reason && condition["message"] == message {
+			return false
+		}
+		updated := newCondition(condType, status, reason, message)
+		if condition["status"] == string(status) {
+			updated["lastTransitionTime"] = condition["lastTransitionTime"]
+		}
+		conditions[i] = updated
+		_ = unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions")
+		return true
+	}
+	conditions = append(conditions, newCondition(condType, status, reason, message))
+	_ = unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions")
+	return true
+}
+
+// int64Field reads a numeric field regardless of whether it was decoded as an int or a float.
+func int64Field(obj map[string]interface{}, fields ...string) (int64, bool) {
+	v, found, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if err != nil || !found {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
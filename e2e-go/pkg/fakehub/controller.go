@@ -0,0 +1,154 @@
+package fakehub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// reconcileFunc performs at most one simulated transition for the named object. Returning
+// requeue=true reconciles the object again after the step delay.
+type reconcileFunc func(ctx context.Context, namespace, name string) (requeue bool, err error)
+
+// controller watches a single resource and feeds changed objects to its reconcile function
+// through a delaying work queue, so every transition is separated by the hub step delay.
+type controller struct {
+	gvr       schema.GroupVersionResource
+	client    dynamic.Interface
+	reconcile reconcileFunc
+	delay     time.Duration
+	queue     workqueue.DelayingInterface
+}
+
+func (h *Hub) newController(gvr schema.GroupVersionResource, reconcile reconcileFunc) *controller {
+	return &controller{
+		gvr:    gvr,
+		client: h.Dynamic,
+		reconcile: func(ctx context.Context, namespace, name string) (bool, error) {
+			h.writeMu.Lock()
+			defer h.writeMu.Unlock()
+			return reconcile(ctx, namespace, name)
+		},
+		delay: h.opts.StepDelay,
+		queue: workqueue.NewDelayingQueue(),
+	}
+}
+
+func (c *controller) start(ctx context.Context, wg *sync.WaitGroup) error {
+	w, err := c.watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer c.queue.ShutDown()
+		for {
+			c.forward(ctx, w)
+			w.Stop()
+			// an API server ends watches after a while, the fakes only when stopped
+			if w = c.rewatch(ctx); w == nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for c.processNextItem(ctx) {
+		}
+	}()
+	return nil
+}
+
+// watch starts watching the resource and queues every existing object, so nothing changed
+// before the watch started is missed.
+func (c *controller) watch(ctx context.Context) (watch.Interface, error) {
+	w, err := c.client.Resource(c.gvr).Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	existing, err := c.client.Resource(c.gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		w.Stop()
+		return nil, err
+	}
+	for _, item := range existing.Items {
+		c.queue.AddAfter(objectKey(item.GetNamespace(), item.GetName()), c.delay)
+	}
+	return w, nil
+}
+
+// rewatch starts watching again once a step has passed, until it succeeds or ctx ends.
+func (c *controller) rewatch(ctx context.Context) watch.Interface {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.delay):
+		}
+		w, err := c.watch(ctx)
+		if err == nil {
+			return w
+		}
+		fmt.Printf("fakehub: failed to watch %s again: %v\n", c.gvr.Resource, err)
+	}
+}
+
+// forward queues the objects of the watch events until the watch or ctx ends.
+func (c *controller) forward(ctx context.Context, w watch.Interface) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			if obj, ok := event.Object.(*unstructured.Unstructured); ok {
+				c.queue.AddAfter(objectKey(obj.GetNamespace(), obj.GetName()), c.delay)
+			}
+		}
+	}
+}
+
+func (c *controller) processNextItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	namespace, name := splitObjectKey(item.(string))
+	requeue, err := c.reconcile(ctx, namespace, name)
+	if err != nil {
+		// a conflict only means the object changed since it was read, it is reconciled again
+		if ctx.Err() == nil && !errors.IsConflict(err) {
+			fmt.Printf("fakehub: failed to reconcile %s %s: %v\n", c.gvr.Resource, item, err)
+		}
+		requeue = true
+	}
+	if requeue && ctx.Err() == nil {
+		c.queue.AddAfter(item, c.delay)
+	}
+	return true
+}
+
+func objectKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func splitObjectKey(key string) (string, string) {
+	parts := strings.SplitN(key, "/", 2)
+	return parts[0], parts[1]
+}
@@ -0,0 +1,107 @@
+package fakehub
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// crdShapes is the scope of every resource registered with the fake hub, and whether its
+// status is written through the status subresource, as the simulated controllers expect.
+var crdShapes = map[schema.GroupVersionResource]struct {
+	namespaced bool
+	status     bool
+}{
+	utils.HostedClustersGVR:      {namespaced: true, status: true},
+	utils.NodePoolsGVR:           {namespaced: true, status: true},
+	utils.ManagedClustersGVR:     {namespaced: false, status: true},
+	utils.ManagedClusterAddonGVR: {namespaced: true, status: true},
+	utils.ClusterCuratorGVR:      {namespaced: true, status: true},
+	utils.AnsibleJobGVR:          {namespaced: true},
+	utils.MultiClusterEngineGVR:  {namespaced: false},
+	utils.MultiClusterHubGVR:     {namespaced: true},
+	utils.ConsoleCLIDownloadGVR:  {namespaced: false},
+	utils.InfrastructuresGVR:     {namespaced: false},
+	utils.ManifestWorkGVR:        {namespaced: true},
+	utils.KlusterletGVR:          {namespaced: false},
+}
+
+// CRDs returns a schemaless CustomResourceDefinition for every resource registered with the
+// fake hub, for an API server the hub runs against with NewForClients, e.g. envtest.
+func CRDs() []*apiextensionsv1.CustomResourceDefinition {
+	preserveUnknownFields := true
+	crds := []*apiextensionsv1.CustomResourceDefinition{}
+	for gvr, listKind := range ListKinds() {
+		shape := crdShapes[gvr]
+		kind := strings.TrimSuffix(listKind, "List")
+		scope := apiextensionsv1.ClusterScoped
+		if shape.namespaced {
+			scope = apiextensionsv1.NamespaceScoped
+		}
+		version := apiextensionsv1.CustomResourceDefinitionVersion{
+			Name:    gvr.Version,
+			Served:  true,
+			Storage: true,
+			Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+				Type:                   "object",
+				XPreserveUnknownFields: &preserveUnknownFields,
+			}},
+		}
+		if shape.status {
+			version.Subresources = &apiextensionsv1.CustomResourceSubresources{Status: &apiextensionsv1.CustomResourceSubresourceStatus{}}
+		}
+		crds = append(crds, &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: gvr.Resource + "." + gvr.Group},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: gvr.Group,
+				Names: apiextensionsv1.CustomResourceDefinitionNames{
+					Plural:   gvr.Resource,
+					Singular: strings.ToLower(kind),
+					Kind:     kind,
+					ListKind: listKind,
+				},
+				Scope:    scope,
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{version},
+			},
+		})
+	}
+	sort.Slice(crds, func(i, j int) bool { return crds[i].Name < crds[j].Name })
+	return crds
+}
+
+// InstallCRDs creates the CRDs that do not exist yet and waits until every one is established.
+func InstallCRDs(ctx context.Context, client apiextensionsclient.Interface) error {
+	crdClient := client.ApiextensionsV1().CustomResourceDefinitions()
+	for _, crd := range CRDs() {
+		_, err := crdClient.Create(ctx, crd, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("ERROR failed to create the CRD %s: %v", crd.Name, err)
+		}
+		err = wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, time.Minute, true, func(ctx context.Context) (bool, error) {
+			current, err := crdClient.Get(ctx, crd.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, err
+			}
+			for _, condition := range current.Status.Conditions {
+				if condition.Type == apiextensionsv1.Established && condition.Status == apiextensionsv1.ConditionTrue {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+		if err != nil {
+			return fmt.Errorf("ERROR the CRD %s is not established: %v", crd.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package fakehub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/retry"
+)
+
+const releaseImagePrefix = "quay.io/openshift-release-dev/ocp-release:"
+
+// reconcileClusterCurator starts a curation run whenever spec.desiredCuration changes, or, after a
+// failed curation, whenever the spec changes. Each run is simulated in its own goroutine, like
+// the curator job the real controller launches.
+func (h *Hub) reconcileClusterCurator(ctx context.Context, namespace, name string) (bool, error) {
+	key := objectKey(namespace, name)
+	curator, err := h.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		h.mu.Lock()
+		delete(h.curations, key)
+		delete(h.failedCurations, key)
+		h.mu.Unlock()
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	desiredCuration, _, _ := unstructured.NestedString(curator.Object, "spec", "desiredCuration")
+	spec, err := json.Marshal(curator.Object["spec"])
+	if err != nil {
+		return false, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if desiredCuration == "" {
+		delete(h.curations, key)
+		delete(h.failedCurations, key)
+		return false, nil
+	}
+	if h.curations[key] == desiredCuration {
+		// a failed curation is retried once its spec is fixed
+		if failedSpec, failed := h.failedCurations[key]; !failed || failedSpec == string(spec) {
+			return false, nil
+		}
+	}
+	h.curations[key] = desiredCuration
+	delete(h.failedCurations, key)
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		if err := h.runCuration(ctx, namespace, name, desiredCuration, string(spec)); err != nil && ctx.Err() == nil {
+			fmt.Printf("fakehub: ClusterCurator %s/%s: %s curation failed: %v\n", namespace, name, desiredCuration, err)
+		}
+	}()
+	return false, nil
+}
+
+func (h *Hub) runCuration(ctx context.Context, namespace, name, curation, spec string) error {
+	jobName := "curator-job-" + rand.String(5)
+	jobMessage := fmt.Sprintf("%s DesiredCuration: %s", jobName, curation)
+	if err := h.setCuratorCondition(ctx, namespace, name, "clustercurator-job", metav1.ConditionFalse, "Job_has_started", jobMessage); err != nil {
+		return err
+	}
+
+	curator, err := h.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	stage, _, _ := unstructured.NestedMap(curator.Object, "spec", curation)
+
+	if err := h.runHooks(ctx, namespace, name, stage, "prehook"); err != nil {
+		return h.failCuration(ctx, namespace, name, spec, jobMessage, err)
+	}
+	switch curation {
+	case "install":
+		err = h.curateInstall(ctx, namespace, name, jobName)
+	case "destroy":
+		err = h.curateDestroy(ctx, namespace, name, jobName)
+	case "upgrade":
+		err = h.curateUpgrade(ctx, namespace, name, jobName, stage)
+	}
+	if err != nil {
+		return h.failCuration(ctx, namespace, name, spec, jobMessage, err)
+	}
+	if curation != "destroy" {
+		if err := h.runHooks(ctx, namespace, name, stage, "posthook"); err != nil {
+			return h.failCuration(ctx, namespace, name, spec, jobMessage, err)
+		}
+	}
+	return h.setCuratorCondition(ctx, namespace, name, "clustercurator-job", metav1.ConditionTrue, "Job_has_finished", jobMessage)
+}
+
+// failCuration reports the failed curation on the clustercurator-job condition. The curation is
+// retried once the spec of the ClusterCurator changes.
+func (h *Hub) failCuration(ctx context.Context, namespace, name, spec, jobMessage string, cause error) error {
+	if ctx.Err() != nil {
+		return cause
+	}
+	h.mu.Lock()
+	h.failedCurations[objectKey(namespace, name)] = spec
+	h.mu.Unlock()
+	if err := h.setCuratorCondition(ctx, namespace, name, "clustercurator-job", metav1.ConditionFalse, "Job_failed", jobMessage+": "+cause.Error()); err != nil {
+		return err
+	}
+	return cause
+}
+
+// runHooks runs every prehook or posthook of the curation stage as an AnsibleJob, one after
+// the other, and reports the outcome on the <hookType>-ansiblejob condition. The first job that
+// fails or exceeds the jobMonitorTimeout of the stage fails the hook.
+func (h *Hub) runHooks(ctx context.Context, namespace, name string, stage map[string]interface{}, hookType string) error {
+	hooks, _, _ := unstructured.NestedSlice(stage, hookType)
+	if len(hooks) == 0 {
+		return nil
+	}
+	condType := hookType + "-ansiblejob"
+	if err := h.setCuratorCondition(ctx, namespace, name, condType, metav1.ConditionFalse, "Job_has_started", "Executing init container"); err != nil {
+		return err
+	}
+	towerAuthSecret, _, _ := unstructured.NestedString(stage, "towerAuthSecret")
+
+	for _, hook := range hooks {
+		hookSpec, _ := hook.(map[string]interface{})
+		jobName := hookType + "job-" + rand.String(5)
+		if err := h.createAnsibleJob(ctx, namespace, name, jobName, towerAuthSecret, hookSpec); err != nil {
+			return err
+		}
+		if err := h.setCuratorCondition(ctx, namespace, name, "current-ansiblejob", metav1.ConditionFalse, "Job_has_started", jobName); err != nil {
+			return err
+		}
+		if err := h.step(ctx); err != nil {
+			return err
+		}
+
+		status := h.opts.HookStatuses[fmt.Sprint(hookSpec["name"])]
+		if status == "" {
+			status = utils.AnsibleJobSuccessful
+		}
+		if status == "running" {
+			// the job never finishes, the curator gives up after jobMonitorTimeout
+			for minute := int64(1); minute < jobMonitorTimeout(stage); minute++ {
+				if err := h.step(ctx); err != nil {
+					return err
+				}
+			}
+			return h.failHook(ctx, namespace, name, condType, utils.CuratorHookTimeoutMessage)
+		}
+		err := h.modify(ctx, utils.AnsibleJobGVR, namespace, jobName, func(job *unstructured.Unstructured) {
+			_ = unstructured.SetNestedField(job.Object, true, "status", "isFinished")
+			_ = unstructured.SetNestedField(job.Object, status, "status", "ansibleJobResult", "status")
+			_ = unstructured.SetNestedField(job.Object, time.Now().UTC().Format(time.RFC3339), "status", "ansibleJobResult", "finished")
+		})
+		if err != nil {
+			return err
+		}
+		if err := h.setCuratorCondition(ctx, namespace, name, "current-ansiblejob", metav1.ConditionFalse, "Job_has_finished", jobName); err != nil {
+			return err
+		}
+		if status != utils.AnsibleJobSuccessful {
+			return h.failHook(ctx, namespace, name, condType, fmt.Sprintf("AnsibleJob %s/%s %s", namespace, jobName, utils.CuratorHookFailedMessage))
+		}
+	}
+	return h.setCuratorCondition(ctx, namespace, name, condType, metav1.ConditionTrue, "Job_has_finished", "Completed executing init container")
+}
+
+// failHook reports the failed hook on its <hookType>-ansiblejob condition and returns the failure.
+func (h *Hub) failHook(ctx context.Context, namespace, name, condType, message string) error {
+	if err := h.setCuratorCondition(ctx, namespace, name, condType, metav1.ConditionTrue, "Job_failed", message); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s", message)
+}
+
+// jobMonitorTimeout returns the jobMonitorTimeout of the curation stage in minutes, 5 when unset.
+func jobMonitorTimeout(stage map[string]interface{}) int64 {
+	if timeout, ok := int64Field(stage, "jobMonitorTimeout"); ok {
+		return timeout
+	}
+	return 5
+}
+
+func (h *Hub) createAnsibleJob(ctx context.Context, namespace, curatorName, jobName, towerAuthSecret string, hook map[string]interface{}) error {
+	spec := map[string]interface{}{"tower_auth_secret": towerAuthSecret}
+	templateField := "job_template_name"
+	if hook["type"] == "Workflow" {
+		templateField = "workflow_template_name"
+	}
+	spec[templateField] = hook["name"]
+	if extraVars, ok := hook["extra_vars"]; ok {
+		spec["extra_vars"] = extraVars
+	}
+	job := newUnstructured(utils.AnsibleJobGVR, "AnsibleJob", namespace, jobName, map[string]interface{}{
+		"spec": spec,
+		"status": map[string]interface{}{
+			"isFinished": false,
+			"ansibleJobResult": map[string]interface{}{
+				"status":  "running",
+				"started": time.Now().UTC().Format(time.RFC3339),
+			},
+		},
+	})
+	job.SetLabels(map[string]string{"cluster.open-cluster-management.io/curator": curatorName})
+	_, err := h.Dynamic.Resource(utils.AnsibleJobGVR).Namespace(namespace).Create(ctx, job, metav1.CreateOptions{})
+	return err
+}
+
+// curateInstall unpauses the HostedCluster and its NodePools and waits for the hosted control
+// plane to become available.
+func (h *Hub) curateInstall(ctx context.Context, namespace, name, jobName string) error {
+	message := jobName + "-provision"
+	if err := h.setCuratorCondition(ctx, namespace, name, "hypershift-provisioning-job", metav1.ConditionFalse, "Job_has_started", message); err != nil {
+		return err
+	}
+	unpause := func(obj *unstructured.Unstructured) {
+		unstructured.RemoveNestedField(obj.Object, "spec", "pausedUntil")
+	}
+	if err := h.modify(ctx, utils.HostedClustersGVR, namespace, name, unpause); err != nil {
+		return err
+	}
+	if err := h.modifyNodePools(ctx, namespace, name, unpause); err != nil {
+		return err
+	}
+	err := h.waitFor(ctx, func() (bool, error) {
+		hc, err := h.Dynamic.Resource(utils.HostedClustersGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isConditionTrue(hc, "Available"), nil
+	})
+	if err != nil {
+		return err
+	}
+	return h.setCuratorCondition(ctx, namespace, name, "hypershift-provisioning-job", metav1.ConditionTrue, "Job_has_finished", message)
+}
+
+// curateDestroy deletes the HostedCluster and waits for it and its ManagedCluster to be gone.
+func (h *Hub) curateDestroy(ctx context.Context, namespace, name, jobName string) error {
+	message := jobName + "-uninstall"
+	if err := h.setCuratorCondition(ctx, namespace, name, "hypershift-uninstalling-job", metav1.ConditionFalse, "Job_has_started", message); err != nil {
+		return err
+	}
+	err := h.Dynamic.Resource(utils.HostedClustersGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	err = h.waitFor(ctx, func() (bool, error) {
+		_, err := h.Dynamic.Resource(utils.ManagedClustersGVR).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return err
+	}
+	return h.setCuratorCondition(ctx, namespace, name, "hypershift-uninstalling-job", metav1.ConditionTrue, "Job_has_finished", message)
+}
+
+// curateUpgrade applies spec.upgrade.channel and spec.upgrade.desiredUpdate to the HostedCluster
+// and, depending on spec.upgrade.upgradeType, to its NodePools.
+func (h *Hub) curateUpgrade(ctx context.Context, namespace, name, jobName string, stage map[string]interface{}) error {
+	message := jobName + "-upgrade"
+	if err := h.setCuratorCondition(ctx, namespace, name, "hypershift-upgrade-job", metav1.ConditionFalse, "Job_has_started", message); err != nil {
+		return err
+	}
+	channel, _, _ := unstructured.NestedString(stage, "channel")
+	desiredUpdate, _, _ := unstructured.NestedString(stage, "desiredUpdate")
+	upgradeType, _, _ := unstructured.NestedString(stage, "upgradeType")
+	releaseImage := ""
+	if desiredUpdate != "" {
+		releaseImage = releaseImagePrefix + desiredUpdate + "-multi"
+	}
+
+	err := h.modify(ctx, utils.HostedClustersGVR, namespace, name, func(hc *unstructured.Unstructured) {
+		if channel != "" {
+			_ = unstructured.SetNestedField(hc.Object, channel, "spec", "channel")
+		}
+		if releaseImage != "" && upgradeType != "NodePools" {
+			_ = unstructured.SetNestedField(hc.Object, releaseImage, "spec", "release", "image")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if releaseImage != "" && upgradeType != "ControlPlane" {
+		err := h.modifyNodePools(ctx, namespace, name, func(np *unstructured.Unstructured) {
+			_ = unstructured.SetNestedField(np.Object, releaseImage, "spec", "release", "image")
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := h.step(ctx); err != nil {
+		return err
+	}
+	return h.setCuratorCondition(ctx, namespace, name, "hypershift-upgrade-job", metav1.ConditionTrue, "Job_has_finished", message)
+}
+
+func (h *Hub) setCuratorCondition(ctx context.Context, namespace, name, condType string, status metav1.ConditionStatus, reason, message string) error {
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		curator, err := h.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !setCondition(curator, condType, status, reason, message) {
+			return nil
+		}
+		_, err = h.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(namespace).UpdateStatus(ctx, curator, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// modify applies mutate to the named object and writes it back, again on conflict.
+func (h *Hub) modify(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string, mutate func(*unstructured.Unstructured)) error {
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := h.Dynamic.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		mutate(obj)
+		_, err = h.Dynamic.Resource(gvr).Namespace(namespace).Update(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (h *Hub) modifyNodePools(ctx context.Context, namespace, clusterName string, mutate func(*unstructured.Unstructured)) error {
+	nodePools, err := h.Dynamic.Resource(utils.NodePoolsGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, np := range nodePools.Items {
+		if npClusterName, _, _ := unstructured.NestedString(np.Object, "spec", "clusterName"); npClusterName != clusterName {
+			continue
+		}
+		if err := h.modify(ctx, utils.NodePoolsGVR, namespace, np.GetName(), mutate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// step pauses for one simulated transition.
+func (h *Hub) step(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(h.opts.StepDelay):
+		return nil
+	}
+}
+
+// waitFor polls done once per step until it reports true, returns an error or ctx ends.
+func (h *Hub) waitFor(ctx context.Context, done func() (bool, error)) error {
+	for {
+		if err := h.step(ctx); err != nil {
+			return err
+		}
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+}
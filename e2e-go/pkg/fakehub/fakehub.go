@@ -0,0 +1,294 @@
+// Package fakehub provides a hub that can stand in for a real MCE/ACM hub when exercising the
+// helpers in pkg/utils and the specs in pkg/test offline.
+//
+// New builds the hub on the client-go dynamic and kube fakes. It registers the HostedCluster,
+// NodePool, ManagedCluster, ManagedClusterAddOn, ClusterCurator, AnsibleJob, MultiClusterEngine
+// and MultiClusterHub kinds and runs small simulated controllers that move the objects through
+// the same condition transitions the real hypershift operator, import controller, addon manager
+// and cluster-curator-controller produce.
+//
+// NewForClients runs the same controllers against any clients, e.g. of an envtest API server
+// serving the CRDs of CRDs, so the specs can run end to end with fake-hcp as hcp; see
+// cmd/fakehub.
+package fakehub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	defaultStepDelay    = 50 * time.Millisecond
+	defaultMCENamespace = "multicluster-engine"
+	defaultACMNamespace = "open-cluster-management"
+	// defaultClusterNamespace is where hcp creates the HostedClusters when --namespace is not set
+	defaultClusterNamespace = "clusters"
+)
+
+// Options configures the fake hub.
+type Options struct {
+	// StepDelay is the pause between two simulated condition transitions. Defaults to 50ms.
+	StepDelay time.Duration
+	// ACM seeds a MultiClusterHub so the hub is detected as ACM instead of MCE-only.
+	ACM bool
+	// MCENamespace is the MultiClusterEngine target namespace. Defaults to multicluster-engine.
+	MCENamespace string
+	// MCEVersion is reported in the MultiClusterEngine status.currentVersion.
+	MCEVersion string
+	// HookStatuses is the status.ansibleJobResult.status the AnsibleJobs of each hook template end
+	// with; the jobs of the templates not listed are successful. A job left "running" never
+	// finishes and fails its hook once the jobMonitorTimeout of the stage, one step per minute,
+	// has passed.
+	HookStatuses map[string]string
+}
+
+// Hub is a hub with simulated controllers.
+type Hub struct {
+	Dynamic dynamic.Interface
+	Kube    kubernetes.Interface
+
+	opts Options
+	wg   sync.WaitGroup
+	// writeMu serialises the read-modify-write cycles of the simulated controllers, since the
+	// fake object tracker does not enforce resourceVersion conflicts. An API server does: the
+	// writes of the curations are retried and the reconciles requeued on conflict.
+	writeMu   sync.Mutex
+	mu        sync.Mutex
+	curations map[string]string
+	// failedCurations holds the spec each ClusterCurator had when its last curation failed
+	failedCurations map[string]string
+}
+
+// ListKinds returns the list kinds of every resource registered with the fake hub.
+func ListKinds() map[schema.GroupVersionResource]string {
+	return map[schema.GroupVersionResource]string{
+		utils.HostedClustersGVR:      "HostedClusterList",
+		utils.NodePoolsGVR:           "NodePoolList",
+		utils.ManagedClustersGVR:     "ManagedClusterList",
+		utils.ManagedClusterAddonGVR: "ManagedClusterAddOnList",
+		utils.ClusterCuratorGVR:      "ClusterCuratorList",
+		utils.AnsibleJobGVR:          "AnsibleJobList",
+		utils.MultiClusterEngineGVR:  "MultiClusterEngineList",
+		utils.MultiClusterHubGVR:     "MultiClusterHubList",
+		utils.ConsoleCLIDownloadGVR:  "ConsoleCLIDownloadList",
+		utils.InfrastructuresGVR:     "InfrastructureList",
+		utils.ManifestWorkGVR:        "ManifestWorkList",
+		utils.KlusterletGVR:          "KlusterletList",
+	}
+}
+
+// New returns a fake hub seeded with a MultiClusterEngine (and a MultiClusterHub when
+// Options.ACM is set), a healthy hypershift operator, the hypershift addon manager and an
+// Available hypershift-addon on local-cluster. Call Start to run the simulated controllers.
+func New(opts Options) *Hub {
+	opts = withDefaults(opts)
+	objects := []runtime.Object{}
+	for _, seed := range seedObjects(opts) {
+		objects = append(objects, seed.obj)
+	}
+	return NewForClients(
+		dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), ListKinds(), objects...),
+		kubefake.NewSimpleClientset(seedKubeObjects(opts)...),
+		opts)
+}
+
+// NewForClients returns a hub whose simulated controllers drive the given clients instead of
+// fakes. Nothing is seeded, call Seed to create what New seeds the fakes with.
+func NewForClients(dynamicClient dynamic.Interface, kubeClient kubernetes.Interface, opts Options) *Hub {
+	return &Hub{
+		Dynamic:         dynamicClient,
+		Kube:            kubeClient,
+		opts:            withDefaults(opts),
+		curations:       map[string]string{},
+		failedCurations: map[string]string{},
+	}
+}
+
+func withDefaults(opts Options) Options {
+	if opts.StepDelay == 0 {
+		opts.StepDelay = defaultStepDelay
+	}
+	if opts.MCENamespace == "" {
+		opts.MCENamespace = defaultMCENamespace
+	}
+	return opts
+}
+
+type seedObject struct {
+	gvr schema.GroupVersionResource
+	obj *unstructured.Unstructured
+}
+
+func seedObjects(opts Options) []seedObject {
+	objects := []seedObject{
+		{utils.MultiClusterEngineGVR, newUnstructured(utils.MultiClusterEngineGVR, "MultiClusterEngine", "", "multiclusterengine", map[string]interface{}{
+			"spec":   map[string]interface{}{"targetNamespace": opts.MCENamespace},
+			"status": map[string]interface{}{"currentVersion": opts.MCEVersion, "phase": "Available"},
+		})},
+		{utils.ManagedClusterAddonGVR, newUnstructured(utils.ManagedClusterAddonGVR, "ManagedClusterAddOn", utils.LocalClusterName, utils.HypershiftAddonName, map[string]interface{}{
+			"status": map[string]interface{}{"conditions": []interface{}{
+				newCondition("Available", metav1.ConditionTrue, "ManagedClusterAddOnLeaseUpdated", "hypershift-addon add-on is available."),
+			}},
+		})},
+		{utils.ConsoleCLIDownloadGVR, newUnstructured(utils.ConsoleCLIDownloadGVR, "ConsoleCLIDownload", "", utils.HCPCliDownloadName, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"displayName": "hcp - Hosted Control Plane Command Line Interface (CLI)",
+				"links":       []interface{}{},
+			},
+		})},
+	}
+	if opts.ACM {
+		objects = append(objects, seedObject{utils.MultiClusterHubGVR, newUnstructured(utils.MultiClusterHubGVR, "MultiClusterHub", defaultACMNamespace, "multiclusterhub", map[string]interface{}{
+			"spec":   map[string]interface{}{"targetNamespace": defaultACMNamespace},
+			"status": map[string]interface{}{"phase": "Running"},
+		})})
+	}
+	return objects
+}
+
+func seedKubeObjects(opts Options) []runtime.Object {
+	namespaces := []string{utils.HypershiftOperatorNamespace, opts.MCENamespace, utils.LocalClusterName, defaultClusterNamespace}
+	if opts.ACM {
+		namespaces = append(namespaces, defaultACMNamespace)
+	}
+	objects := []runtime.Object{}
+	for _, ns := range namespaces {
+		objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})
+	}
+	return append(objects,
+		newAvailableDeployment(utils.HypershiftOperatorNamespace, utils.HypershiftOperatorName),
+		newAvailableDeployment(opts.MCENamespace, utils.HypershiftAddonMgrName))
+}
+
+// newAvailableDeployment returns a single replica Deployment reported available.
+func newAvailableDeployment(namespace, name string) *appsv1.Deployment {
+	replicas := int32(1)
+	labels := map[string]string{"app": name}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: name, Image: name}}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:          replicas,
+			UpdatedReplicas:   replicas,
+			ReadyReplicas:     replicas,
+			AvailableReplicas: replicas,
+		},
+	}
+}
+
+// Seed creates the objects New seeds the fakes with through the hub clients and sets their
+// status, which an API server drops on create. Objects that already exist only get their
+// status set.
+func (h *Hub) Seed(ctx context.Context) error {
+	for _, obj := range seedKubeObjects(h.opts) {
+		var err error
+		switch o := obj.(type) {
+		case *corev1.Namespace:
+			err = h.ensureNamespace(ctx, o.Name)
+		case *appsv1.Deployment:
+			err = h.seedDeployment(ctx, o)
+		}
+		if err != nil {
+			return fmt.Errorf("ERROR failed to seed %T %s: %v", obj, obj.(metav1.Object).GetName(), err)
+		}
+	}
+	for _, seed := range seedObjects(h.opts) {
+		if err := h.seedObject(ctx, seed.gvr, seed.obj); err != nil {
+			return fmt.Errorf("ERROR failed to seed %s %s: %v", seed.obj.GetKind(), seed.obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+func (h *Hub) seedDeployment(ctx context.Context, deployment *appsv1.Deployment) error {
+	client := h.Kube.AppsV1().Deployments(deployment.Namespace)
+	created, err := client.Create(ctx, deployment, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		created, err = client.Get(ctx, deployment.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(created.Status, deployment.Status) {
+		return nil
+	}
+	created.Status = deployment.Status
+	_, err = client.UpdateStatus(ctx, created, metav1.UpdateOptions{})
+	return err
+}
+
+func (h *Hub) seedObject(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	client := h.Dynamic.Resource(gvr).Namespace(obj.GetNamespace())
+	created, err := client.Create(ctx, obj, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		created, err = client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	}
+	if err != nil {
+		return err
+	}
+	status, ok := obj.Object["status"]
+	if !ok || equality.Semantic.DeepEqual(created.Object["status"], status) {
+		return nil
+	}
+	created.Object["status"] = status
+	_, err = client.UpdateStatus(ctx, created, metav1.UpdateOptions{})
+	return err
+}
+
+// Start runs the simulated controllers until ctx is cancelled. It returns once every
+// controller is watching, so objects created afterwards are always reconciled.
+func (h *Hub) Start(ctx context.Context) error {
+	controllers := []*controller{
+		h.newController(utils.HostedClustersGVR, h.reconcileHostedCluster),
+		h.newController(utils.NodePoolsGVR, h.reconcileNodePool),
+		h.newController(utils.ManagedClustersGVR, h.reconcileManagedCluster),
+		h.newController(utils.ManagedClusterAddonGVR, h.reconcileManagedClusterAddOn),
+		h.newController(utils.ClusterCuratorGVR, h.reconcileClusterCurator),
+	}
+	for _, c := range controllers {
+		if err := c.start(ctx, &h.wg); err != nil {
+			return fmt.Errorf("ERROR failed to start the fake %s controller: %v", c.gvr.Resource, err)
+		}
+	}
+	return nil
+}
+
+// Wait blocks until every controller started by Start has stopped.
+func (h *Hub) Wait() {
+	h.wg.Wait()
+}
+
+func newUnstructured(gvr schema.GroupVersionResource, kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetAPIVersion(gvr.GroupVersion().String())
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
@@ -0,0 +1,326 @@
+package fakehub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	testNamespace    = "clusters"
+	testReleaseImage = "quay.io/openshift-release-dev/ocp-release:4.15.5-multi"
+	testTimeout      = 10 * time.Second
+	testInterval     = 20 * time.Millisecond
+)
+
+func startHub(t *testing.T, opts Options) *Hub {
+	t.Helper()
+	opts.StepDelay = 5 * time.Millisecond
+	hub := New(opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := hub.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("failed to start the fake hub: %v", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		hub.Wait()
+	})
+	return hub
+}
+
+func createHostedCluster(t *testing.T, hub *Hub, name, platform, pausedUntil string) {
+	t.Helper()
+	ctx := context.TODO()
+	hc := NewHostedCluster(testNamespace, name, platform, testReleaseImage, pausedUntil)
+	if _, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace(testNamespace).Create(ctx, hc, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create HostedCluster: %v", err)
+	}
+	np := NewNodePool(testNamespace, name, name, platform, testReleaseImage, 2, pausedUntil)
+	if _, err := hub.Dynamic.Resource(utils.NodePoolsGVR).Namespace(testNamespace).Create(ctx, np, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create NodePool: %v", err)
+	}
+}
+
+func TestHostedClusterImport(t *testing.T) {
+	g := gomega.NewWithT(t)
+	gomega.RegisterTestingT(t)
+	hub := startHub(t, Options{})
+	createHostedCluster(t, hub, "acmqe-hc-1", utils.TYPE_AWS, "")
+
+	g.Eventually(func() error {
+		return utils.CheckHCPAvailable(hub.Dynamic, "acmqe-hc-1", testNamespace)
+	}, testTimeout, testInterval).Should(gomega.BeNil())
+	g.Eventually(func() error {
+		return utils.CheckClusterImported(hub.Dynamic, "acmqe-hc-1")
+	}, testTimeout, testInterval).Should(gomega.BeNil())
+	for _, addon := range utils.MceManagedClusterAddOns {
+		g.Eventually(func() error {
+			return utils.ValidateClusterAddOnAvailable(hub.Dynamic, "acmqe-hc-1", addon)
+		}, testTimeout, testInterval).Should(gomega.BeNil())
+	}
+	g.Eventually(func() (int64, error) {
+		np, err := hub.Dynamic.Resource(utils.NodePoolsGVR).Namespace(testNamespace).Get(context.TODO(), "acmqe-hc-1", metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		replicas, _ := int64Field(np.Object, "status", "replicas")
+		return replicas, nil
+	}, testTimeout, testInterval).Should(gomega.Equal(int64(2)))
+
+	// the live hub waiters return on their first poll once the state has been reached
+	utils.WaitForHCPAvailable(hub.Dynamic, "acmqe-hc-1", testNamespace)
+	utils.WaitForClusterImported(hub.Dynamic, "acmqe-hc-1")
+	g.Expect(utils.WaitForClusterAddonsAvailable(hub.Dynamic, "acmqe-hc-1")).To(gomega.Succeed())
+
+	mc, err := hub.Dynamic.Resource(utils.ManagedClustersGVR).Get(context.TODO(), "acmqe-hc-1", metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(mc.GetLabels()).To(gomega.HaveKeyWithValue("cloud", "Amazon"))
+	g.Expect(mc.GetAnnotations()).To(gomega.HaveKeyWithValue("import.open-cluster-management.io/klusterlet-deploy-mode", "Hosted"))
+
+	// everything of a live cluster is a leftover once it is destroyed
+	g.Eventually(func() ([]string, error) {
+		leftovers, err := utils.CheckClusterCleanup(context.TODO(), hub.Dynamic, hub.Kube, testNamespace, "acmqe-hc-1")
+		names := []string{}
+		for _, leftover := range leftovers {
+			names = append(names, leftover.String())
+		}
+		return names, err
+	}, testTimeout, testInterval).Should(gomega.ContainElements(
+		"Namespace clusters-acmqe-hc-1 (hypershift operator)",
+		"Namespace acmqe-hc-1 (registration)",
+		"Namespace klusterlet-acmqe-hc-1 (klusterlet operator)",
+		"Secret clusters/acmqe-hc-1-admin-kubeconfig (hypershift operator)",
+		"ManagedClusterAddOn acmqe-hc-1/work-manager (addon manager)",
+		"ManifestWork acmqe-hc-1/addon-work-manager-deploy-0 (work)",
+		"ManifestWork local-cluster/acmqe-hc-1-hosted-klusterlet (managedcluster-import-controller)",
+		"Klusterlet klusterlet-acmqe-hc-1 (klusterlet operator)",
+	))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = utils.WaitForClusterCleanup(ctx, hub.Dynamic, hub.Kube, testNamespace, "acmqe-hc-1")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("  - Klusterlet klusterlet-acmqe-hc-1 (klusterlet operator)")))
+
+	err = hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace(testNamespace).Delete(context.TODO(), "acmqe-hc-1", metav1.DeleteOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Eventually(func() (bool, error) {
+		return utils.HasResource(hub.Dynamic, utils.ManagedClustersGVR, "", "acmqe-hc-1")
+	}, testTimeout, testInterval).Should(gomega.BeFalse())
+	g.Eventually(func() (bool, error) {
+		return utils.HasResource(hub.Dynamic, utils.NodePoolsGVR, testNamespace, "acmqe-hc-1")
+	}, testTimeout, testInterval).Should(gomega.BeFalse())
+	g.Eventually(func() ([]utils.ClusterLeftover, error) {
+		return utils.CheckClusterCleanup(context.TODO(), hub.Dynamic, hub.Kube, testNamespace, "acmqe-hc-1")
+	}, testTimeout, testInterval).Should(gomega.BeEmpty())
+	g.Expect(utils.WaitForClusterCleanup(context.TODO(), hub.Dynamic, hub.Kube, testNamespace, "acmqe-hc-1")).To(gomega.Succeed())
+}
+
+func TestACMAddOns(t *testing.T) {
+	g := gomega.NewWithT(t)
+	gomega.RegisterTestingT(t)
+	hub := startHub(t, Options{ACM: true})
+	createHostedCluster(t, hub, "acmqe-hc-2", utils.TYPE_KUBEVIRT, "")
+
+	g.Eventually(func() error {
+		return utils.CheckClusterImported(hub.Dynamic, "acmqe-hc-2")
+	}, testTimeout, testInterval).Should(gomega.BeNil())
+	for _, addon := range utils.AcmManagedClusterAddOns {
+		g.Eventually(func() error {
+			return utils.ValidateClusterAddOnAvailable(hub.Dynamic, "acmqe-hc-2", addon)
+		}, testTimeout, testInterval).Should(gomega.BeNil())
+	}
+	g.Expect(utils.WaitForClusterAddonsAvailable(hub.Dynamic, "acmqe-hc-2")).To(gomega.Succeed())
+}
+
+func TestPausedHostedClusterDoesNotRollOut(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := startHub(t, Options{})
+	createHostedCluster(t, hub, "acmqe-hc-3", utils.TYPE_AWS, "true")
+
+	g.Eventually(func() (string, error) {
+		hc, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace(testNamespace).Get(context.TODO(), "acmqe-hc-3", metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		condition := getCondition(hc, "ReconciliationActive")
+		if condition == nil {
+			return "", nil
+		}
+		return condition["status"].(string), nil
+	}, testTimeout, testInterval).Should(gomega.Equal(string(metav1.ConditionFalse)))
+	g.Consistently(func() error {
+		return utils.CheckHCPAvailable(hub.Dynamic, "acmqe-hc-3", testNamespace)
+	}, 200*time.Millisecond, testInterval).ShouldNot(gomega.BeNil())
+}
+
+func TestClusterCuratorLifecycle(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := startHub(t, Options{})
+	createHostedCluster(t, hub, "acmqe-hc-4", utils.TYPE_AWS, "true")
+
+	hook := func(stage, hookType string) interface{} {
+		return map[string]interface{}{
+			"name":       "Auto_CLC_Sample_Template",
+			"type":       "Job",
+			"extra_vars": map[string]interface{}{"stage": stage, "hook": hookType},
+		}
+	}
+	curator := newUnstructured(utils.ClusterCuratorGVR, "ClusterCurator", testNamespace, "acmqe-hc-4", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"desiredCuration": "install",
+			"install": map[string]interface{}{
+				"towerAuthSecret": "ansible-tower-secret",
+				"prehook":         []interface{}{hook("install", "pre")},
+				"posthook":        []interface{}{hook("install", "post")},
+			},
+		},
+	})
+	_, err := hub.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(testNamespace).Create(context.TODO(), curator, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	checkCurator := func(conType, expectedMsg string) {
+		t.Helper()
+		g.Eventually(func() error {
+			return utils.CheckCuratorCondition(hub.Dynamic, "acmqe-hc-4", testNamespace, conType, "True", expectedMsg, "Job_has_finished")
+		}, testTimeout, testInterval).Should(gomega.BeNil())
+	}
+
+	checkCurator("prehook-ansiblejob", "Completed executing init container")
+	job, err := utils.GetCurrentAnsibleJob(hub.Dynamic, "acmqe-hc-4", testNamespace)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(job.GetName()).To(gomega.HavePrefix("prehookjob-"))
+	checkCurator("hypershift-provisioning-job", "-provision")
+	checkCurator("posthook-ansiblejob", "Completed executing init container")
+	checkCurator("clustercurator-job", "DesiredCuration: install")
+	g.Expect(utils.CheckHCPAvailable(hub.Dynamic, "acmqe-hc-4", testNamespace)).To(gomega.Succeed())
+
+	job, err = utils.GetCurrentAnsibleJob(hub.Dynamic, "acmqe-hc-4", testNamespace)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	result, _, _ := unstructured.NestedString(job.Object, "status", "ansibleJobResult", "status")
+	g.Expect(result).To(gomega.Equal("successful"))
+
+	g.Expect(utils.SetClusterCuratorUpgradeChannel(hub.Dynamic, "acmqe-hc-4", testNamespace, "stable-4.16")).To(gomega.Succeed())
+	g.Expect(utils.SetClusterCuratorUpgradeDesiredUpdateAndType(hub.Dynamic, "acmqe-hc-4", testNamespace, "4.16.1", "ControlPlane")).To(gomega.Succeed())
+	g.Expect(utils.SetDesiredCuration(hub.Dynamic, "acmqe-hc-4", testNamespace, "upgrade")).To(gomega.Succeed())
+	checkCurator("clustercurator-job", "DesiredCuration: upgrade")
+
+	release, err := utils.GetHostedClusterSpecRelease(hub.Dynamic, "acmqe-hc-4", testNamespace)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(release).To(gomega.ContainSubstring("4.16.1"))
+	g.Eventually(func() ([]string, error) {
+		return utils.GetHostedClusterAvailableChannels(hub.Dynamic, "acmqe-hc-4", testNamespace)
+	}, testTimeout, testInterval).Should(gomega.ContainElement("stable-4.16"))
+	nodePools, err := utils.ListNodePoolsForHostedCluster(hub.Dynamic, testNamespace, "acmqe-hc-4")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(nodePools).To(gomega.HaveLen(1))
+	npRelease, err := utils.GetNodePoolSpecRelease(nodePools[0])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(npRelease).To(gomega.Equal(testReleaseImage))
+
+	g.Expect(utils.SetDesiredCuration(hub.Dynamic, "acmqe-hc-4", testNamespace, "destroy")).To(gomega.Succeed())
+	checkCurator("hypershift-uninstalling-job", "-uninstall")
+	checkCurator("clustercurator-job", "DesiredCuration: destroy")
+	_, err = hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace(testNamespace).Get(context.TODO(), "acmqe-hc-4", metav1.GetOptions{})
+	g.Expect(errors.IsNotFound(err)).To(gomega.BeTrue())
+}
+
+func TestClusterCuratorHookFailure(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := startHub(t, Options{HookStatuses: map[string]string{
+		utils.TowerStubFailingTemplate: "failed",
+		utils.TowerStubSlowTemplate:    "running",
+	}})
+	createHostedCluster(t, hub, "acmqe-hc-5", utils.TYPE_AWS, "true")
+	ctx, cancel := context.WithTimeout(context.TODO(), testTimeout)
+	defer cancel()
+
+	apply := func(desiredCuration, template string) {
+		t.Helper()
+		spec := utils.ClusterCuratorSpec{
+			DesiredCuration: desiredCuration,
+			Install: &utils.CuratorStage{
+				TowerAuthSecret:   "ansible-tower-secret",
+				Prehook:           []utils.CuratorHook{{Name: template}},
+				JobMonitorTimeout: 2,
+			},
+		}
+		curator, err := utils.NewClusterCurator("acmqe-hc-5", testNamespace, spec)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		existing, err := hub.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(testNamespace).Get(ctx, "acmqe-hc-5", metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = hub.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(testNamespace).Create(ctx, curator, metav1.CreateOptions{})
+		} else {
+			existing.Object["spec"] = curator.Object["spec"]
+			_, err = hub.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(testNamespace).Update(ctx, existing, metav1.UpdateOptions{})
+		}
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+
+	// the prehook job fails, the cluster is never provisioned
+	apply("install", utils.TowerStubFailingTemplate)
+	g.Expect(utils.WaitForCuratorHookFailure(ctx, hub.Dynamic, "acmqe-hc-5", testNamespace, "install", utils.CuratorPrehook, utils.CuratorHookFailedMessage)).To(gomega.Succeed())
+	job, err := utils.GetCurrentAnsibleJob(hub.Dynamic, "acmqe-hc-5", testNamespace)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(utils.GetAnsibleJobResult(job).Status).To(gomega.Equal("failed"))
+	g.Expect(utils.CheckHostedClusterPaused(hub.Dynamic, "acmqe-hc-5", testNamespace)).To(gomega.Succeed())
+
+	// the retried prehook job never finishes
+	apply("", utils.TowerStubSlowTemplate)
+	g.Expect(utils.SetDesiredCuration(hub.Dynamic, "acmqe-hc-5", testNamespace, "install")).To(gomega.Succeed())
+	g.Expect(utils.WaitForCuratorHookFailure(ctx, hub.Dynamic, "acmqe-hc-5", testNamespace, "install", utils.CuratorPrehook, utils.CuratorHookTimeoutMessage)).To(gomega.Succeed())
+	g.Expect(utils.CheckHostedClusterPaused(hub.Dynamic, "acmqe-hc-5", testNamespace)).To(gomega.Succeed())
+
+	// the curation is not retried until the spec changes
+	g.Consistently(func() error {
+		return utils.CheckCuratorCondition(hub.Dynamic, "acmqe-hc-5", testNamespace, "clustercurator-job", "", "", "Job_failed")
+	}, 20*hub.opts.StepDelay, testInterval).Should(gomega.Succeed())
+
+	// the retry with a working prehook provisions the cluster
+	apply("", "Auto_CLC_Sample_Template")
+	g.Expect(utils.SetDesiredCuration(hub.Dynamic, "acmqe-hc-5", testNamespace, "install")).To(gomega.Succeed())
+	g.Expect(utils.WaitForCuratorCondition(hub.Dynamic, "acmqe-hc-5", testNamespace, "clustercurator-job", "True", "DesiredCuration: install", "Job_has_finished", testTimeout)).To(gomega.Succeed())
+	g.Expect(utils.CheckHCPAvailable(hub.Dynamic, "acmqe-hc-5", testNamespace)).To(gomega.Succeed())
+	g.Expect(utils.CheckHostedClusterPaused(hub.Dynamic, "acmqe-hc-5", testNamespace)).NotTo(gomega.Succeed())
+}
+
+func TestClusterCuratorScale(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := startHub(t, Options{})
+	createHostedCluster(t, hub, "acmqe-hc-6", utils.TYPE_KUBEVIRT, "")
+	g.Eventually(func() error {
+		return utils.CheckHCPAvailable(hub.Dynamic, "acmqe-hc-6", testNamespace)
+	}, testTimeout, testInterval).Should(gomega.BeNil())
+
+	curator, err := utils.NewClusterCurator("acmqe-hc-6", testNamespace, utils.DefaultClusterCuratorSpec("", "hc-"+utils.TYPE_KUBEVIRT, "ansible-tower-secret"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = hub.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace(testNamespace).Create(context.TODO(), curator, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(utils.SetDesiredCuration(hub.Dynamic, "acmqe-hc-6", testNamespace, utils.CurationScale)).To(gomega.Succeed())
+	g.Expect(utils.WaitForCuratorCondition(hub.Dynamic, "acmqe-hc-6", testNamespace, "clustercurator-job", "True", "DesiredCuration: scale", "Job_has_finished", testTimeout)).To(gomega.Succeed())
+	g.Expect(utils.CheckCuratorCondition(hub.Dynamic, "acmqe-hc-6", testNamespace, "prehook-ansiblejob", "True", "", "Job_has_finished")).To(gomega.Succeed())
+	g.Expect(utils.CheckCuratorCondition(hub.Dynamic, "acmqe-hc-6", testNamespace, "posthook-ansiblejob", "True", "", "Job_has_finished")).To(gomega.Succeed())
+	job, err := utils.GetCurrentAnsibleJob(hub.Dynamic, "acmqe-hc-6", testNamespace)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(utils.GetAnsibleJobResult(job).Stage).To(gomega.Equal(utils.CurationScale))
+
+	// the curation only runs the hooks, the NodePool is scaled directly
+	g.Expect(utils.ScaleNodePool(hub.Dynamic, testNamespace, "acmqe-hc-6", 4)).To(gomega.Succeed())
+	g.Eventually(func() int64 {
+		np, err := hub.Dynamic.Resource(utils.NodePoolsGVR).Namespace(testNamespace).Get(context.TODO(), "acmqe-hc-6", metav1.GetOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		_, statusReplicas := utils.GetNodePoolReplicaCounts(np)
+		return statusReplicas
+	}, testTimeout, testInterval).Should(gomega.Equal(int64(4)))
+	ctx, cancel := context.WithTimeout(context.TODO(), testTimeout)
+	defer cancel()
+	g.Expect(utils.WaitForNodePoolsScaled(ctx, hub.Dynamic, testNamespace, "acmqe-hc-6", map[string]int64{"acmqe-hc-6": 4})).To(gomega.Succeed())
+	err = utils.WaitForNodePoolsScaled(ctx, hub.Dynamic, testNamespace, "acmqe-hc-6", map[string]int64{"missing": 1})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("NodePool clusters/missing of HostedCluster acmqe-hc-6 not found")))
+}
@@ -0,0 +1,288 @@
+package fakehub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// hostedClusterRollout is the order in which the hypershift operator reports a new hosted
+// control plane healthy. Available is always last.
+var hostedClusterRollout = []struct {
+	condType string
+	reason   string
+	message  string
+}{
+	{"ValidConfiguration", "AsExpected", "Configuration passes validation"},
+	{"ValidReleaseImage", "AsExpected", "Release image is valid"},
+	{"InfrastructureReady", "AsExpected", "All is well"},
+	{"EtcdAvailable", "QuorumAvailable", "Etcd cluster is available"},
+	{"KubeAPIServerAvailable", "AsExpected", "Kube APIServer deployment is available"},
+	{"Available", "AsExpected", "The hosted control plane is available"},
+}
+
+// NewHostedCluster returns a HostedCluster of the given platform the way the hcp CLI renders it.
+// A non-empty pausedUntil keeps the hosted control plane from rolling out until it is cleared.
+func NewHostedCluster(namespace, name, platform, releaseImage, pausedUntil string) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"platform": map[string]interface{}{"type": platform},
+		"release":  map[string]interface{}{"image": releaseImage},
+	}
+	if pausedUntil != "" {
+		spec["pausedUntil"] = pausedUntil
+	}
+	return newUnstructured(utils.HostedClustersGVR, "HostedCluster", namespace, name, map[string]interface{}{"spec": spec})
+}
+
+// NewNodePool returns a NodePool for the HostedCluster clusterName with the given replicas.
+func NewNodePool(namespace, name, clusterName, platform, releaseImage string, replicas int64, pausedUntil string) *unstructured.Unstructured {
+	spec := map[string]interface{}{
+		"clusterName": clusterName,
+		"replicas":    replicas,
+		"platform":    map[string]interface{}{"type": platform},
+		"release":     map[string]interface{}{"image": releaseImage},
+	}
+	if pausedUntil != "" {
+		spec["pausedUntil"] = pausedUntil
+	}
+	return newUnstructured(utils.NodePoolsGVR, "NodePool", namespace, name, map[string]interface{}{"spec": spec})
+}
+
+func (h *Hub) reconcileHostedCluster(ctx context.Context, namespace, name string) (bool, error) {
+	hc, err := h.Dynamic.Resource(utils.HostedClustersGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, h.cleanupHostedCluster(ctx, namespace, name)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if getCondition(hc, "Available") == nil {
+		if err := h.ensureNamespace(ctx, namespace+"-"+name); err != nil {
+			return false, err
+		}
+		setCondition(hc, "ReconciliationActive", metav1.ConditionTrue, "AsExpected", "Reconciliation active on resource")
+		setCondition(hc, "Available", metav1.ConditionFalse, "WaitingForAvailable", "Waiting for hosted control plane to be healthy")
+		return false, h.updateHostedClusterStatus(ctx, hc)
+	}
+
+	pausedUntil, _, _ := unstructured.NestedString(hc.Object, "spec", "pausedUntil")
+	if pausedUntil != "" {
+		if setCondition(hc, "ReconciliationActive", metav1.ConditionFalse, "ReconciliationPaused", "Reconciliation paused until: "+pausedUntil) {
+			return false, h.updateHostedClusterStatus(ctx, hc)
+		}
+		return false, nil
+	}
+	if setCondition(hc, "ReconciliationActive", metav1.ConditionTrue, "AsExpected", "Reconciliation active on resource") {
+		return false, h.updateHostedClusterStatus(ctx, hc)
+	}
+
+	for _, step := range hostedClusterRollout {
+		if !isConditionTrue(hc, step.condType) {
+			setCondition(hc, step.condType, metav1.ConditionTrue, step.reason, step.message)
+			return false, h.updateHostedClusterStatus(ctx, hc)
+		}
+	}
+
+	if syncHostedClusterVersion(hc) {
+		return false, h.updateHostedClusterStatus(ctx, hc)
+	}
+
+	if err := h.ensureHostedClusterSecrets(ctx, hc); err != nil {
+		return false, err
+	}
+	return false, h.ensureManagedCluster(ctx, hc)
+}
+
+// syncHostedClusterVersion rolls status.version.desired forward to the spec release image and
+// channel, as the control plane operator does once an upgrade has been accepted.
+func syncHostedClusterVersion(hc *unstructured.Unstructured) bool {
+	image, _, _ := unstructured.NestedString(hc.Object, "spec", "release", "image")
+	channel, _, _ := unstructured.NestedString(hc.Object, "spec", "channel")
+	desiredImage, _, _ := unstructured.NestedString(hc.Object, "status", "version", "desired", "image")
+	_, hasChannels, _ := unstructured.NestedStringSlice(hc.Object, "status", "version", "desired", "channels")
+	if desiredImage == image && (channel == "" || hasChannels) {
+		return false
+	}
+
+	desired := map[string]interface{}{"image": image}
+	if channel != "" {
+		desired["channels"] = availableChannels(channel)
+	}
+	_ = unstructured.SetNestedMap(hc.Object, desired, "status", "version", "desired")
+	_ = unstructured.SetNestedSlice(hc.Object, []interface{}{
+		map[string]interface{}{"image": image, "state": "Completed"},
+	}, "status", "version", "history")
+	return true
+}
+
+// availableChannels returns the stable, fast and candidate channels for the minor version of
+// the given channel, e.g. stable-4.15 yields stable-4.15, fast-4.15 and candidate-4.15.
+func availableChannels(channel string) []interface{} {
+	version := channel
+	if i := strings.LastIndex(channel, "-"); i >= 0 {
+		version = channel[i+1:]
+	}
+	return []interface{}{"stable-" + version, "fast-" + version, "candidate-" + version}
+}
+
+func (h *Hub) updateHostedClusterStatus(ctx context.Context, hc *unstructured.Unstructured) error {
+	_, err := h.Dynamic.Resource(utils.HostedClustersGVR).Namespace(hc.GetNamespace()).UpdateStatus(ctx, hc, metav1.UpdateOptions{})
+	return err
+}
+
+// ensureHostedClusterSecrets creates the admin kubeconfig and kubeadmin password secrets the
+// hypershift operator publishes next to an available HostedCluster.
+func (h *Hub) ensureHostedClusterSecrets(ctx context.Context, hc *unstructured.Unstructured) error {
+	for _, suffix := range []string{"admin-kubeconfig", "kubeadmin-password"} {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", hc.GetName(), suffix),
+			Namespace: hc.GetNamespace(),
+		}}
+		_, err := h.Kube.CoreV1().Secrets(hc.GetNamespace()).Create(ctx, secret, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureManagedCluster auto-imports an available HostedCluster the way the hypershift addon does.
+func (h *Hub) ensureManagedCluster(ctx context.Context, hc *unstructured.Unstructured) error {
+	cloud := "Other"
+	if platform, _, _ := unstructured.NestedString(hc.Object, "spec", "platform", "type"); platform == utils.TYPE_AWS {
+		cloud = "Amazon"
+	}
+	mc := newUnstructured(utils.ManagedClustersGVR, "ManagedCluster", "", hc.GetName(), map[string]interface{}{
+		"spec": map[string]interface{}{"hubAcceptsClient": true},
+	})
+	mc.SetLabels(map[string]string{
+		"name":   hc.GetName(),
+		"cloud":  cloud,
+		"vendor": "OpenShift",
+		"cluster.open-cluster-management.io/clusterset": "default",
+	})
+	mc.SetAnnotations(map[string]string{
+		"import.open-cluster-management.io/klusterlet-deploy-mode": "Hosted",
+		"import.open-cluster-management.io/hosting-cluster-name":   utils.LocalClusterName,
+		"open-cluster-management/created-via":                      "hypershift",
+	})
+	_, err := h.Dynamic.Resource(utils.ManagedClustersGVR).Create(ctx, mc, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// cleanupHostedCluster removes everything that belonged to a deleted HostedCluster: its
+// NodePools, secrets, control plane namespace and ManagedCluster.
+func (h *Hub) cleanupHostedCluster(ctx context.Context, namespace, name string) error {
+	nodePools, err := h.Dynamic.Resource(utils.NodePoolsGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, np := range nodePools.Items {
+		if clusterName, _, _ := unstructured.NestedString(np.Object, "spec", "clusterName"); clusterName != name {
+			continue
+		}
+		err := h.Dynamic.Resource(utils.NodePoolsGVR).Namespace(namespace).Delete(ctx, np.GetName(), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	for _, suffix := range []string{"admin-kubeconfig", "kubeadmin-password"} {
+		err := h.Kube.CoreV1().Secrets(namespace).Delete(ctx, fmt.Sprintf("%s-%s", name, suffix), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if err := h.deleteNamespace(ctx, namespace+"-"+name); err != nil {
+		return err
+	}
+	err = h.Dynamic.Resource(utils.ManagedClustersGVR).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (h *Hub) ensureNamespace(ctx context.Context, name string) error {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	_, err := h.Kube.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// deleteNamespace deletes the namespace and finalizes it, as the namespace controller an API
+// server without a controller manager (envtest) lacks would once the namespace is emptied.
+func (h *Hub) deleteNamespace(ctx context.Context, name string) error {
+	err := h.Kube.CoreV1().Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ns, err := h.Kube.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ns.DeletionTimestamp == nil || len(ns.Spec.Finalizers) == 0 {
+		return nil
+	}
+	ns.Spec.Finalizers = nil
+	_, err = h.Kube.CoreV1().Namespaces().Finalize(ctx, ns, metav1.UpdateOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (h *Hub) reconcileNodePool(ctx context.Context, namespace, name string) (bool, error) {
+	np, err := h.Dynamic.Resource(utils.NodePoolsGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	desired, _ := int64Field(np.Object, "spec", "replicas")
+	current, _ := int64Field(np.Object, "status", "replicas")
+	switch {
+	case current < desired:
+		current++
+	case current > desired:
+		current--
+	default:
+		changed := setCondition(np, "AllMachinesReady", metav1.ConditionTrue, "AsExpected", "All is well")
+		changed = setCondition(np, "Ready", metav1.ConditionTrue, "AsExpected", "") || changed
+		image, _, _ := unstructured.NestedString(np.Object, "spec", "release", "image")
+		if version, _, _ := unstructured.NestedString(np.Object, "status", "version"); version != image {
+			_ = unstructured.SetNestedField(np.Object, image, "status", "version")
+			changed = true
+		}
+		if !changed {
+			return false, nil
+		}
+		_, err = h.Dynamic.Resource(utils.NodePoolsGVR).Namespace(namespace).UpdateStatus(ctx, np, metav1.UpdateOptions{})
+		return false, err
+	}
+
+	_ = unstructured.SetNestedField(np.Object, current, "status", "replicas")
+	setCondition(np, "Ready", metav1.ConditionFalse, "WaitingForNodes",
+		fmt.Sprintf("%d of %d nodes are ready", current, desired))
+	_, err = h.Dynamic.Resource(utils.NodePoolsGVR).Namespace(namespace).UpdateStatus(ctx, np, metav1.UpdateOptions{})
+	return false, err
+}
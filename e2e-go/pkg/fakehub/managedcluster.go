@@ -0,0 +1,134 @@
+package fakehub
+
+import (
+	"context"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// managedClusterJoin is the order in which the registration agent reports a hosted cluster
+// joining the hub.
+var managedClusterJoin = []struct {
+	condType string
+	reason   string
+	message  string
+}{
+	{"HubAcceptedManagedCluster", "HubClusterAdminAccepted", "Accepted by hub cluster admin"},
+	{"ManagedClusterJoined", "ManagedClusterJoined", "Managed cluster joined"},
+	{"ManagedClusterConditionAvailable", "ManagedClusterAvailable", "Managed cluster is available"},
+}
+
+func (h *Hub) reconcileManagedCluster(ctx context.Context, _, name string) (bool, error) {
+	mc, err := h.Dynamic.Resource(utils.ManagedClustersGVR).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, h.cleanupManagedCluster(ctx, name)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := h.ensureNamespace(ctx, name); err != nil {
+		return false, err
+	}
+	for _, step := range managedClusterJoin {
+		if !isConditionTrue(mc, step.condType) {
+			setCondition(mc, step.condType, metav1.ConditionTrue, step.reason, step.message)
+			_, err = h.Dynamic.Resource(utils.ManagedClustersGVR).UpdateStatus(ctx, mc, metav1.UpdateOptions{})
+			return false, err
+		}
+	}
+
+	addons := utils.MceManagedClusterAddOns
+	if h.opts.ACM {
+		addons = utils.AcmManagedClusterAddOns
+	}
+	for _, addonName := range addons {
+		addon := newUnstructured(utils.ManagedClusterAddonGVR, "ManagedClusterAddOn", name, addonName, map[string]interface{}{
+			"spec": map[string]interface{}{"installNamespace": "open-cluster-management-agent-addon"},
+		})
+		_, err := h.Dynamic.Resource(utils.ManagedClusterAddonGVR).Namespace(name).Create(ctx, addon, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return false, err
+		}
+		work := newUnstructured(utils.ManifestWorkGVR, "ManifestWork", name, "addon-"+addonName+"-deploy-0", nil)
+		_, err = h.Dynamic.Resource(utils.ManifestWorkGVR).Namespace(name).Create(ctx, work, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return false, err
+		}
+	}
+	return false, h.ensureHostedKlusterlet(ctx, name)
+}
+
+// ensureHostedKlusterlet deploys the hosted mode klusterlet of the cluster on local-cluster the
+// way the import controller does: a ManifestWork in the local-cluster namespace applying the
+// Klusterlet and its agent namespace.
+func (h *Hub) ensureHostedKlusterlet(ctx context.Context, name string) error {
+	work := newUnstructured(utils.ManifestWorkGVR, "ManifestWork", utils.LocalClusterName, name+"-hosted-klusterlet", nil)
+	_, err := h.Dynamic.Resource(utils.ManifestWorkGVR).Namespace(utils.LocalClusterName).Create(ctx, work, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	klusterlet := newUnstructured(utils.KlusterletGVR, "Klusterlet", "", "klusterlet-"+name, map[string]interface{}{
+		"spec": map[string]interface{}{"deployOption": map[string]interface{}{"mode": "Hosted"}, "clusterName": name},
+	})
+	_, err = h.Dynamic.Resource(utils.KlusterletGVR).Create(ctx, klusterlet, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return h.ensureNamespace(ctx, "klusterlet-"+name)
+}
+
+// cleanupManagedCluster removes the add-ons, the ManifestWorks, the hosted mode klusterlet and
+// the cluster namespace of a detached cluster.
+func (h *Hub) cleanupManagedCluster(ctx context.Context, name string) error {
+	for _, gvr := range []schema.GroupVersionResource{utils.ManagedClusterAddonGVR, utils.ManifestWorkGVR} {
+		list, err := h.Dynamic.Resource(gvr).Namespace(name).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			err := h.Dynamic.Resource(gvr).Namespace(name).Delete(ctx, item.GetName(), metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	err := h.Dynamic.Resource(utils.ManifestWorkGVR).Namespace(utils.LocalClusterName).Delete(ctx, name+"-hosted-klusterlet", metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	err = h.Dynamic.Resource(utils.KlusterletGVR).Delete(ctx, "klusterlet-"+name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	for _, ns := range []string{"klusterlet-" + name, name} {
+		if err := h.deleteNamespace(ctx, ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hub) reconcileManagedClusterAddOn(ctx context.Context, namespace, name string) (bool, error) {
+	addon, err := h.Dynamic.Resource(utils.ManagedClusterAddonGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case !isConditionTrue(addon, "ManifestApplied"):
+		setCondition(addon, "ManifestApplied", metav1.ConditionTrue, "AddonManifestApplied", "manifests of addon are applied successfully")
+	case !isConditionTrue(addon, "Available"):
+		setCondition(addon, "Available", metav1.ConditionTrue, "ManagedClusterAddOnLeaseUpdated", name+" add-on is available.")
+	default:
+		return false, nil
+	}
+	_, err = h.Dynamic.Resource(utils.ManagedClusterAddonGVR).Namespace(namespace).UpdateStatus(ctx, addon, metav1.UpdateOptions{})
+	return false, err
+}
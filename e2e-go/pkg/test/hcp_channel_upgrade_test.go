@@ -31,10 +31,12 @@ var _ = ginkgo.Describe("PR 511 / ACM-26476: ClusterCurator HostedCluster channe
 		var err error
 		clusterName, err = utils.GetClusterName("aws")
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		gomega.Expect(clusterName).NotTo(gomega.BeEmpty(), "HCP_CLUSTER_NAME or options.clusters.aws.clusterName must be set")
+		gomega.Expect(clusterName).NotTo(gomega.BeEmpty(), "HCP_CLUSTER_NAME or options.clusters.aws.clusterName must be set, or an AWS cluster created earlier in the run")
 
 		namespace, err = utils.GetNamespace(TYPE_AWS)
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		utils.ReportHostedCluster(namespace, clusterName)
+		utils.RecordConditionTimeline(dynamicClient, namespace, clusterName)
 
 		// Channel from options.yaml (options.clustercurator.channel) or HCP_UPGRADE_CHANNEL env, else default
 		testChannel = utils.GetClusterCuratorChannel()
@@ -57,12 +59,10 @@ var _ = ginkgo.Describe("PR 511 / ACM-26476: ClusterCurator HostedCluster channe
 
 		ginkgo.By("Waiting for ClusterCurator clustercurator-job condition to become True (upgrade completed)")
 		timeout := 15 * time.Minute
-		interval := 15 * time.Second
 		// Controller sets clustercurator-job when the curator job finishes (message e.g. "curator-job-xxx DesiredCuration: upgrade Version (;channel;;;)")
-		gomega.Eventually(func() error {
-			return utils.CheckCuratorCondition(dynamicClient, clusterName, namespace,
-				"clustercurator-job", string(metav1.ConditionTrue), "", "Job_has_finished")
-		}, timeout, interval).ShouldNot(gomega.HaveOccurred())
+		err = utils.WaitForCuratorCondition(dynamicClient, clusterName, namespace,
+			"clustercurator-job", string(metav1.ConditionTrue), "", "Job_has_finished", timeout)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 		ginkgo.By("Verifying HostedCluster spec.channel was updated")
 		channel, err := utils.GetHostedClusterChannel(dynamicClient, clusterName, namespace)
@@ -0,0 +1,116 @@
+package hypershift_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+)
+
+const (
+	// Label for the curator hook failure tests (run with: --label-filter='curator-failure')
+	labelCuratorFailure = "curator-failure"
+)
+
+// The curator install of a paused AWS hosted cluster whose prehook fails, then runs past its
+// jobMonitorTimeout, and is retried with working hooks. The hooks run against the tower stub,
+// whose script (pkg/resources/towerstub/script.yaml) fails or stalls the jobs of the
+// utils.TowerStubFailingTemplate and utils.TowerStubSlowTemplate templates.
+var _ = g.Describe("Hosted Control Plane CLI AWS Curator Failure Tests:", g.Label(TYPE_AWS, labelCuratorFailure), func() {
+
+	g.BeforeEach(func() {
+		if curatorEnabled != "true" {
+			g.Skip("CURATOR_ENABLED is not true, the curator failure tests need the cluster curator")
+		}
+		if utils.GetTowerStubImage() == "" && utils.GetTowerStubHost() == "" {
+			g.Skip("TOWER_STUB_IMAGE or TOWER_STUB_HOST is not set, the curator failure tests need the tower stub to fail the hooks")
+		}
+
+		config.ClusterName, err = utils.GenerateClusterName("acmqe-hc")
+		o.Expect(err).ShouldNot(o.HaveOccurred())
+		utils.ReportHostedCluster(config.Namespace, config.ClusterName)
+		utils.RecordConditionTimeline(dynamicClient, config.Namespace, config.ClusterName)
+
+		config.ClusterArch, err = utils.GetArch()
+		o.Expect(err).ShouldNot(o.HaveOccurred())
+
+		config.AWSStsCreds, err = utils.GetAWSStsCreds()
+		o.Expect(err).ShouldNot(o.HaveOccurred())
+
+		config.AWSRoleArn, err = utils.GetAWSRoleArn()
+		o.Expect(err).ShouldNot(o.HaveOccurred())
+	})
+
+	g.It("Keeps the hosted cluster paused when the install prehook fails or times out, and recovers on retry", func() {
+		startTime := time.Now()
+
+		// paused until the curator install runs its hooks
+		config.FIPS = fipsEnabled == "true"
+		config.Paused = true
+		createCmd := utils.AWSCreateCommand(config)
+		ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+		defer cancel()
+		_, err := createCmd.Run(ctx)
+		// labeled also when hcp failed after applying the HostedCluster, for hc-janitor
+		labelErr := utils.LabelHostedClusterOwnership(dynamicClient, config.Namespace, config.ClusterName)
+		o.Expect(err).ShouldNot(o.HaveOccurred())
+		o.Expect(labelErr).ShouldNot(o.HaveOccurred())
+		// the destroy specs find the cluster in the run state
+		o.Expect(utils.RecordClusterState(dynamicClient, TYPE_AWS, config.Namespace, config.ClusterName, config.ReleaseImage)).Should(o.Succeed())
+
+		o.Expect(utils.CreateOrUpdateCuratorTowerSecret(clientClient, kubeClient, "aap-tower-cred", config.Namespace)).Should(o.BeNil())
+		o.Expect(utils.DeleteClusterCurator(dynamicClient, config.ClusterName, config.Namespace)).Should(o.BeNil())
+
+		g.By(fmt.Sprintf("Failing the install prehook of the cluster %s", config.ClusterName), func() {
+			spec := utils.DefaultClusterCuratorSpec(utils.CurationInstall, "hc-"+TYPE_AWS, "aap-tower-cred")
+			spec.Install.Prehook = []utils.CuratorHook{{Name: utils.TowerStubFailingTemplate}}
+			o.Expect(utils.CreateOrUpdateClusterCuratorSpec(clientClient, config.ClusterName, config.Namespace, spec)).Should(o.Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			o.Expect(utils.WaitForCuratorHookFailure(ctx, dynamicClient, config.ClusterName, config.Namespace,
+				utils.CurationInstall, utils.CuratorPrehook, utils.CuratorHookFailedMessage)).Should(o.Succeed())
+			o.Expect(utils.CheckHostedClusterPaused(dynamicClient, config.ClusterName, config.Namespace)).Should(o.Succeed())
+		})
+
+		g.By(fmt.Sprintf("Retrying with an install prehook that exceeds the jobMonitorTimeout for the cluster %s", config.ClusterName), func() {
+			spec := utils.DefaultClusterCuratorSpec("", "hc-"+TYPE_AWS, "aap-tower-cred")
+			spec.Install.Prehook = []utils.CuratorHook{{Name: utils.TowerStubSlowTemplate}}
+			spec.Install.JobMonitorTimeout = 1
+			o.Expect(utils.CreateOrUpdateClusterCuratorSpec(clientClient, config.ClusterName, config.Namespace, spec)).Should(o.Succeed())
+			o.Expect(utils.SetDesiredCuration(dynamicClient, config.ClusterName, config.Namespace, utils.CurationInstall)).Should(o.Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			o.Expect(utils.WaitForCuratorHookFailure(ctx, dynamicClient, config.ClusterName, config.Namespace,
+				utils.CurationInstall, utils.CuratorPrehook, utils.CuratorHookTimeoutMessage)).Should(o.Succeed())
+			o.Expect(utils.CheckHostedClusterPaused(dynamicClient, config.ClusterName, config.Namespace)).Should(o.Succeed())
+		})
+
+		g.By(fmt.Sprintf("Retrying with the default hooks for the cluster %s", config.ClusterName), func() {
+			spec := utils.DefaultClusterCuratorSpec("", "hc-"+TYPE_AWS, "aap-tower-cred")
+			o.Expect(utils.CreateOrUpdateClusterCuratorSpec(clientClient, config.ClusterName, config.Namespace, spec)).Should(o.Succeed())
+			o.Expect(utils.SetDesiredCuration(dynamicClient, config.ClusterName, config.Namespace, utils.CurationInstall)).Should(o.Succeed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			result, err := utils.WaitForCuratorHook(ctx, dynamicClient, config.ClusterName, config.Namespace, utils.CurationInstall, utils.CuratorPrehook)
+			o.Expect(err).ShouldNot(o.HaveOccurred(), result.String())
+		})
+
+		g.By(fmt.Sprintf("Waiting for hosted cluster plane for cluster %s to be available", config.ClusterName), func() {
+			utils.WaitForHCPAvailable(dynamicClient, config.ClusterName, config.Namespace)
+		})
+
+		g.By(fmt.Sprintf("Waiting for Job_has_finished to True for clustercurator-job for the cluster curator %s", config.ClusterName), func() {
+			o.Expect(utils.WaitForCuratorCondition(dynamicClient, config.ClusterName, config.Namespace, "clustercurator-job", "True", "DesiredCuration: install", "Job_has_finished", eventuallyTimeout)).Should(o.Succeed())
+		})
+
+		fmt.Printf("Test Duration: %s\n", time.Since(startTime).String())
+		fmt.Printf("========================= End Test curator failure of hosted cluster %s ===============================", config.ClusterName)
+	})
+})
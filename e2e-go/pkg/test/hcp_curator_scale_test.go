@@ -0,0 +1,119 @@
+// Package hypershift_test contains e2e tests for the Hypershift addon.
+// This file tests a ClusterCurator scale curation (desiredCuration: scale) with a manual NodePool scale.
+package hypershift_test
+
+import (
+	"context"
+	"fmt"
+
+	ginkgo "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	labelCuratorScale = "curator-scale"
+)
+
+// Curator scale: the ClusterCurator has no NodePool scale parameters for HostedClusters, spec.scale
+// only holds the hooks, so desiredCuration scale runs the scale hooks and the spec scales the
+// NodePools itself with ScaleNodePool. Nothing orders the posthook after the scale. With
+// CURATOR_ENABLED=true the scale stage runs the default prehooks and posthooks. The NodePools are
+// scaled back to their replicas when the spec ends.
+var _ = ginkgo.Describe("ClusterCurator NodePool scale", ginkgo.Label("e2e", labelCuratorScale, TYPE_AWS), func() {
+	var (
+		clusterName string
+		namespace   string
+		replicas    int64
+	)
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		clusterName, err = utils.GetClusterName("aws")
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(clusterName).NotTo(gomega.BeEmpty(), "HCP_CLUSTER_NAME or options.clusters.aws.clusterName must be set, or an AWS cluster created earlier in the run")
+
+		namespace, err = utils.GetNamespace(TYPE_AWS)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		utils.ReportHostedCluster(namespace, clusterName)
+		utils.RecordConditionTimeline(dynamicClient, namespace, clusterName)
+
+		replicas, err = utils.GetClusterCuratorScaleReplicas()
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+
+	ginkgo.It("Scale: set desiredCuration scale, scale the NodePools by hand after the prehook, then verify the NodePool replicas, posthook and curator condition", func() {
+		ginkgo.By("Ensuring at least one NodePool exists for the HostedCluster")
+		nodePools, err := utils.ListNodePoolsForHostedCluster(dynamicClient, namespace, clusterName)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		gomega.Expect(nodePools).NotTo(gomega.BeEmpty(), "HostedCluster %s must have at least one NodePool for curator-scale test", clusterName)
+
+		// HCP_SCALE_REPLICAS or options.clustercurator.scaleReplicas, else one more node per NodePool
+		scale := map[string]int64{}
+		original := map[string]int64{}
+		for _, np := range nodePools {
+			current, _ := utils.GetNodePoolReplicaCounts(np)
+			original[np.GetName()] = current
+			target := replicas
+			if target == 0 {
+				target = current + 1
+			}
+			scale[np.GetName()] = target
+		}
+		// one more node on every run would grow the NodePools of a shared cluster forever
+		ginkgo.DeferCleanup(func() {
+			for name, current := range original {
+				ginkgo.By(fmt.Sprintf("Scaling NodePool %s back to %d replicas", name, current))
+				gomega.Expect(utils.ScaleNodePool(dynamicClient, namespace, name, current)).Should(gomega.Succeed())
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			gomega.Expect(utils.WaitForNodePoolsScaled(ctx, dynamicClient, namespace, clusterName, original)).Should(gomega.Succeed())
+		})
+
+		ginkgo.By("Creating or updating ClusterCurator")
+		spec := utils.ClusterCuratorSpec{Scale: &utils.CuratorStage{JobMonitorTimeout: 5}}
+		if curatorEnabled == "true" {
+			// AAP, or the tower stub when TOWER_STUB_IMAGE or TOWER_STUB_HOST is set
+			gomega.Expect(utils.CreateOrUpdateCuratorTowerSecret(clientClient, kubeClient, "aap-tower-cred", namespace)).Should(gomega.Succeed())
+			spec = utils.DefaultClusterCuratorSpec("", "hc-"+TYPE_AWS, "aap-tower-cred")
+		}
+		gomega.Expect(utils.CreateOrUpdateClusterCuratorSpec(clientClient, clusterName, namespace, spec)).Should(gomega.Succeed())
+
+		ginkgo.By("Setting desiredCuration to scale")
+		gomega.Expect(utils.SetDesiredCuration(dynamicClient, clusterName, namespace, utils.CurationScale)).Should(gomega.Succeed())
+
+		if curatorEnabled == "true" {
+			ginkgo.By("Waiting for the scale prehook AnsibleJob to complete")
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			result, err := utils.WaitForCuratorHook(ctx, dynamicClient, clusterName, namespace, utils.CurationScale, utils.CuratorPrehook)
+			gomega.Expect(err).ShouldNot(gomega.HaveOccurred(), result.String())
+		}
+
+		// not a curator step: the curator has no NodePool scale parameters for HostedClusters
+		for name, target := range scale {
+			ginkgo.By(fmt.Sprintf("Scaling NodePool %s to %d replicas", name, target))
+			gomega.Expect(utils.ScaleNodePool(dynamicClient, namespace, name, target)).Should(gomega.Succeed())
+		}
+
+		ginkgo.By("Waiting for the NodePools spec.replicas and status.replicas to converge")
+		ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+		defer cancel()
+		gomega.Expect(utils.WaitForNodePoolsScaled(ctx, dynamicClient, namespace, clusterName, scale)).Should(gomega.Succeed())
+
+		if curatorEnabled == "true" {
+			ginkgo.By("Waiting for the scale posthook AnsibleJob to complete")
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			result, err := utils.WaitForCuratorHook(ctx, dynamicClient, clusterName, namespace, utils.CurationScale, utils.CuratorPosthook)
+			gomega.Expect(err).ShouldNot(gomega.HaveOccurred(), result.String())
+		}
+
+		ginkgo.By("Waiting for ClusterCurator clustercurator-job condition to become True (scale completed)")
+		err = utils.WaitForCuratorCondition(dynamicClient, clusterName, namespace,
+			"clustercurator-job", string(metav1.ConditionTrue), "DesiredCuration: scale", "Job_has_finished", eventuallyTimeout)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+	})
+})
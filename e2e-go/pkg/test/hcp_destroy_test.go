@@ -0,0 +1,86 @@
+package hypershift_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+)
+
+// destroyHostedCluster destroys the hosted cluster destroyCmd.Namespace/destroyCmd.Name of the
+// platform and fails the spec if anything of it is left behind. With CURATOR_ENABLED=true the
+// destroy runs through its ClusterCurator (desiredCuration: destroy), and the destroy prehook,
+// the hypershift-uninstalling-job and the destroy posthook are verified; else destroyCmd is run.
+func destroyHostedCluster(platform string, destroyCmd utils.HCPDestroyCluster) {
+	startTime := time.Now()
+	clusterName, namespace := destroyCmd.Name, destroyCmd.Namespace
+	timer := utils.StartPhaseTimer(platform, clusterName)
+
+	if curatorEnabled == "true" {
+		fmt.Println("CURATOR ENABLED, INITILIZE DESTROY VIA CURATOR")
+		o.Expect(utils.SetDesiredCuration(dynamicClient, clusterName, namespace, utils.CurationDestroy)).Should(o.BeNil())
+
+		g.By(fmt.Sprintf("Waiting for the destroy prehook AnsibleJob to complete for the cluster %s", clusterName), func() {
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			result, err := utils.WaitForCuratorHook(ctx, dynamicClient, clusterName, namespace, utils.CurationDestroy, utils.CuratorPrehook)
+			o.Expect(err).ShouldNot(o.HaveOccurred(), result.String())
+			fmt.Printf("Prehook ansiblejob %s completed successfully for the cluster %s\n", result.Name, clusterName)
+			fmt.Printf("Time taken for the prehook-ansiblejob to complete: %s\n", time.Since(startTime).String())
+		})
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+		defer cancel()
+		result, err := destroyCmd.Run(ctx)
+		if err != nil {
+			// a stuck destroy leaves the HostedCluster and its finalizers behind
+			err = utils.DiagnoseDestroyFailure(dynamicClient, kubeClient, namespace, clusterName, startTime, err)
+		}
+		o.Expect(err).ShouldNot(o.HaveOccurred())
+		timer.Record(utils.PhaseHCPCLI, time.Now().Add(-result.Duration), result.Duration)
+	}
+
+	// Now we can verify the hosted cluster has sucecssfully been cleaned up
+	g.By(fmt.Sprintf("Waiting for HostedCluster %s to be removed", clusterName), func() {
+		timer.Time(utils.PhaseHCPDestroyed, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			o.Expect(utils.WaitForHostedClusterDeleted(ctx, dynamicClient, kubeClient, namespace, clusterName, startTime)).Should(o.Succeed())
+		})
+	})
+
+	if curatorEnabled == "true" {
+		g.By(fmt.Sprintf("Waiting for Job_has_finished to True for hypershift-uninstalling-job for the cluster curator %s", clusterName), func() {
+			o.Expect(utils.WaitForCuratorCondition(dynamicClient, clusterName, namespace, "hypershift-uninstalling-job", "True", "-uninstall", "Job_has_finished", eventuallyTimeout)).Should(o.Succeed())
+			fmt.Printf("hypershift-uninstalling-job completed successfully for the cluster %s\n", clusterName)
+			fmt.Printf("Time taken for the hypershift-uninstalling-job to complete: %s\n", time.Since(startTime).String())
+		})
+
+		g.By(fmt.Sprintf("Waiting for the destroy posthook AnsibleJob to complete for the cluster %s", clusterName), func() {
+			ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+			defer cancel()
+			result, err := utils.WaitForCuratorHook(ctx, dynamicClient, clusterName, namespace, utils.CurationDestroy, utils.CuratorPosthook)
+			o.Expect(err).ShouldNot(o.HaveOccurred(), result.String())
+			fmt.Printf("Posthook ansiblejob %s completed successfully for the cluster %s\n", result.Name, clusterName)
+		})
+	}
+
+	g.By(fmt.Sprintf("Waiting for ManagedCluster %s to be removed", clusterName), func() {
+		timer.Time(utils.PhaseDetach, func() {
+			utils.WaitForClusterDetached(dynamicClient, clusterName)
+		})
+	})
+	o.Expect(utils.RemoveClusterState(namespace, clusterName)).Should(o.Succeed())
+
+	g.By(fmt.Sprintf("Verifying hosted cluster %s is cleaned up", clusterName), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+		defer cancel()
+		o.Expect(utils.WaitForClusterCleanup(ctx, dynamicClient, kubeClient, namespace, clusterName)).Should(o.Succeed())
+	})
+
+	fmt.Printf("Test Duration: %s\n", time.Since(startTime).String())
+	fmt.Println("========================= End Test Destroy Hosted Cluster ===============================")
+}
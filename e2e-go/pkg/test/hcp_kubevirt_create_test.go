@@ -1,14 +1,12 @@
 package hypershift_test
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
 	"time"
 
 	g "github.com/onsi/ginkgo/v2"
 	o "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gexec"
 	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
 )
 
@@ -18,51 +16,44 @@ var _ = g.Describe("Hosted Control Plane CLI KubeVirt Create Tests:", g.Label(TY
 		// Before each test, generate a unique cluster name to create the hosted cluster with
 		config.ClusterName, err = utils.GenerateClusterName("acmqe-hc")
 		o.Expect(err).ShouldNot(o.HaveOccurred())
+		utils.ReportHostedCluster(config.Namespace, config.ClusterName)
+		utils.RecordConditionTimeline(dynamicClient, config.Namespace, config.ClusterName)
 	})
 
 	g.It("Creates a Kubevirt Hosted Cluster", g.Label("create"), func() {
 		startTime := time.Now()
+		timer := utils.StartPhaseTimer(TYPE_KUBEVIRT, config.ClusterName)
 
-		memory, err := utils.GetKVMem()
+		config.Memory, err = utils.GetKVMem()
 		o.Expect(err).ShouldNot(o.HaveOccurred())
 
-		cores, err := utils.GetKVCPUCores()
+		config.Cores, err = utils.GetKVCPUCores()
 		o.Expect(err).ShouldNot(o.HaveOccurred())
 
 		// TODO get pull secret from hub? default, if none provided
-		commandArgs := []string{
-			"create", "cluster", strings.ToLower(TYPE_KUBEVIRT),
-			"--name", config.ClusterName,
-			"--pull-secret", config.PullSecret,
-		}
-
-		commandArgs = append(commandArgs, "--memory", memory)
-		commandArgs = append(commandArgs, "--cores", cores)
-		commandArgs = append(commandArgs, "--node-pool-replicas", config.NodePoolReplicas)
-		commandArgs = append(commandArgs, "--namespace", config.Namespace)
-		commandArgs = append(commandArgs, "--infra-availability-policy", "SingleReplica")
-		commandArgs = append(commandArgs, "--control-plane-availability-policy", "SingleReplica")
-
-		// default not provide release image if empty
-		commandArgs = append(commandArgs, "--release-image", config.ReleaseImage)
-
 		// TODO check if fips enabled requested
 		// TODO label cluster with fips=true for easy searching
 		// TODO check nodes if fips is good
-		if fipsEnabled == "true" {
-			commandArgs = append(commandArgs, "--fips")
-		}
-
-		commandArgs = append(commandArgs, "--generate-ssh")
-
-		if curatorEnabled == "true" {
+		config.FIPS = fipsEnabled == "true"
+		config.Paused = curatorEnabled == "true"
+		if config.Paused {
 			fmt.Println("CURATOR ENABLED, SETTING PAUSEDUNTIL TO TRUE")
-			commandArgs = append(commandArgs, "--pausedUntil", "true")
 		}
-
-		cmd := exec.Command(utils.HypershiftCLIName, commandArgs...)
-		session, err := gexec.Start(cmd, g.GinkgoWriter, g.GinkgoWriter)
+		// default not provide release image if empty
+		createCmd := utils.KubeVirtCreateCommand(config)
+
+		ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+		defer cancel()
+		result, err := createCmd.Run(ctx)
+		// owner, run ID and creation time let hc-janitor find the cluster if the run is aborted,
+		// also when hcp failed after applying the HostedCluster
+		labelErr := utils.LabelHostedClusterOwnership(dynamicClient, config.Namespace, config.ClusterName)
 		o.Expect(err).ShouldNot(o.HaveOccurred())
+		o.Expect(labelErr).ShouldNot(o.HaveOccurred())
+		timer.Record(utils.PhaseHCPCLI, time.Now().Add(-result.Duration), result.Duration)
+		// later stages of the run find the cluster in the run state
+		o.Expect(utils.RecordClusterState(dynamicClient, TYPE_KUBEVIRT, config.Namespace, config.ClusterName, config.ReleaseImage)).Should(o.Succeed())
+		timer.Start(utils.PhaseHCPAvailable)
 
 		if curatorEnabled == "true" {
 			// TODO: FAIL test if operator is not in good state or not installed -> suite level?
@@ -70,7 +61,8 @@ var _ = g.Describe("Hosted Control Plane CLI KubeVirt Create Tests:", g.Label(TY
 			// TODO: awx: remove & upload expected templates to tower
 			// Create/Update the aap tower secret -> suite level?
 			fmt.Println("Creating Ansible Tower secret...")
-			o.Expect(utils.CreateOrUpdateAnsibleTowerSecret(clientClient, "aap-tower-cred", config.Namespace, "", "")).Should(o.BeNil())
+			// AAP, or the tower stub when TOWER_STUB_IMAGE or TOWER_STUB_HOST is set
+			o.Expect(utils.CreateOrUpdateCuratorTowerSecret(clientClient, kubeClient, "aap-tower-cred", config.Namespace)).Should(o.BeNil())
 
 			// destroy any existing clustercurator first if it exists in the same ns with same name and then re-create it.
 			o.Expect(utils.DeleteClusterCurator(dynamicClient, config.ClusterName, config.Namespace)).Should(o.BeNil())
@@ -78,81 +70,53 @@ var _ = g.Describe("Hosted Control Plane CLI KubeVirt Create Tests:", g.Label(TY
 				clientClient, config.ClusterName, config.Namespace, "install", "hc-"+TYPE_KUBEVIRT, "aap-tower-cred")).Should(o.BeNil())
 		}
 
-		defer gexec.KillAndWait()
-
-		o.Eventually(session, eventuallyTimeout, eventuallyInterval).Should(gexec.Exit(0))
-		utils.PrintOutput(session) // prints command, args and output
-
 		if curatorEnabled == "true" {
 			// TODO - Check all curator pods are not in error in the HC namespace
-			g.By(fmt.Sprintf("Waiting AnsibleJob for prehook-ansiblejob to complete for the cluster %s", config.ClusterName), func() {
-				o.Eventually(func() bool {
-					ansibleJob, err := utils.GetCurrentAnsibleJob(dynamicClient, config.ClusterName, config.Namespace)
-					if ansibleJob == nil || err != nil {
-						return false
-					}
-					isFinished := ansibleJob.Object["status"].(map[string]interface{})["isFinished"]
-					hookVar := ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})["hook"]
-
-					fmt.Printf("AnsibleJob isFinished field for the cluster %s: %#v\n", config.ClusterName, isFinished)
-					return isFinished != nil && isFinished.(bool) == true &&
-						hookVar != nil && hookVar.(string) == "pre"
-				}, eventuallyTimeout, eventuallyInterval).Should(o.BeTrue())
-				fmt.Printf("Prehook ansiblejob completed successfully for the cluster %s\n", config.ClusterName)
-				fmt.Printf("Time taken for the prehook-ansiblejob to complete: %s\n", time.Since(startTime).String())
-			})
-
-			g.By(fmt.Sprintf("Waiting ClusterCurator for prehook-ansiblejob to complete with status True and reason job_has_finished for the cluster %s", config.ClusterName), func() {
-				o.Eventually(func() error {
-					return utils.CheckCuratorCondition(dynamicClient, config.ClusterName, config.Namespace, "prehook-ansiblejob", "True", "Completed executing init container", "Job_has_finished")
-				}, eventuallyTimeout, eventuallyInterval).Should(o.BeNil())
-				fmt.Printf("Prehook ansiblejob completed successfully for the cluster %s\n", config.ClusterName)
+			g.By(fmt.Sprintf("Waiting for the install prehook AnsibleJob to complete for the cluster %s", config.ClusterName), func() {
+				ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+				defer cancel()
+				result, err := utils.WaitForCuratorHook(ctx, dynamicClient, config.ClusterName, config.Namespace, "install", utils.CuratorPrehook)
+				o.Expect(err).ShouldNot(o.HaveOccurred(), result.String())
+				fmt.Printf("Prehook ansiblejob %s completed successfully for the cluster %s\n", result.Name, config.ClusterName)
 				fmt.Printf("Time taken for the prehook-ansiblejob to complete: %s\n", time.Since(startTime).String())
 			})
 		}
 
 		g.By(fmt.Sprintf("Waiting for hosted cluster plane for cluster %s to be available", config.ClusterName), func() {
 			utils.WaitForHCPAvailable(dynamicClient, config.ClusterName, config.Namespace)
-			fmt.Printf("Time taken for the hosted control plane to be available: %s\n", time.Since(startTime).String())
+			timer.Stop(utils.PhaseHCPAvailable)
 		})
 
 		// TODO: wait until HC nodes are up?
 
 		if curatorEnabled == "true" {
 			g.By(fmt.Sprintf("Waiting for Job_has_finished to True for hypershift-provisioning-job for the cluster curator  %s", config.ClusterName), func() {
-				o.Eventually(func() error {
-					return utils.CheckCuratorCondition(dynamicClient, config.ClusterName, config.Namespace, "hypershift-provisioning-job", "True", "-provision", "Job_has_finished")
-				}, eventuallyTimeout, eventuallyInterval).Should(o.BeNil())
+				o.Expect(utils.WaitForCuratorCondition(dynamicClient, config.ClusterName, config.Namespace, "hypershift-provisioning-job", "True", "-provision", "Job_has_finished", eventuallyTimeout)).Should(o.Succeed())
 				fmt.Printf("hypershift-provisioning-job completed successfully for the cluster %s\n", config.ClusterName)
 				fmt.Printf("Time taken for the hypershift-provisioning-job to complete: %s\n", time.Since(startTime).String())
 			})
 
-			g.By(fmt.Sprintf("Waiting AnsibleJob for prehook-ansiblejob to complete for the cluster %s", config.ClusterName), func() {
-				o.Eventually(func() bool {
-					ansibleJob, err := utils.GetCurrentAnsibleJob(dynamicClient, config.ClusterName, config.Namespace)
-					if ansibleJob == nil || err != nil {
-						return false
-					}
-					isFinished := ansibleJob.Object["status"].(map[string]interface{})["isFinished"]
-					hookVar := ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})["hook"]
-
-					fmt.Printf("AnsibleJob isFinished field for the cluster %s: %#v\n", config.ClusterName, isFinished)
-					return isFinished != nil && isFinished.(bool) == true &&
-						hookVar != nil && hookVar.(string) == "post"
-				}, eventuallyTimeout, eventuallyInterval).Should(o.BeTrue())
-				fmt.Printf("Posthook ansiblejob completed successfully for the cluster %s\n", config.ClusterName)
+			g.By(fmt.Sprintf("Waiting for the install posthook AnsibleJob to complete for the cluster %s", config.ClusterName), func() {
+				ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+				defer cancel()
+				result, err := utils.WaitForCuratorHook(ctx, dynamicClient, config.ClusterName, config.Namespace, "install", utils.CuratorPosthook)
+				o.Expect(err).ShouldNot(o.HaveOccurred(), result.String())
+				fmt.Printf("Posthook ansiblejob %s completed successfully for the cluster %s\n", result.Name, config.ClusterName)
 			})
 		}
 
 		// Checks to see if ManagedCluster is created and the HC is auto-imported...
 		g.By(fmt.Sprintf("Waiting for managed cluster %s to be Available", config.ClusterName), func() {
-			utils.WaitForClusterImported(dynamicClient, config.ClusterName)
-			fmt.Printf("Time taken for the cluster to be imported: %s\n", time.Since(startTime).String())
+			timer.Time(utils.PhaseImport, func() {
+				utils.WaitForClusterImported(dynamicClient, config.ClusterName)
+			})
 		})
 
 		// Checks to see if add-ons are installed and available for the HC managed cluster...
 		g.By(fmt.Sprintf("Waiting for managed cluster %s addons are Enabled and Available", config.ClusterName), func() {
-			utils.WaitForClusterAddonsAvailable(dynamicClient, config.ClusterName)
+			timer.Time(utils.PhaseAddonsAvailable, func() {
+				o.Expect(utils.WaitForClusterAddonsAvailable(dynamicClient, config.ClusterName)).Should(o.Succeed())
+			})
 			fmt.Printf("Time taken for the cluster be imported and addons ready: %s\n", time.Since(startTime).String())
 		})
 
@@ -1,139 +1,67 @@
 package hypershift_test
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
-	"strings"
 	"time"
 
 	g "github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
 	o "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gexec"
 	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
 )
 
 var _ = g.Describe("Hosted Control Plane CLI KubeVirt Destroy Tests:", g.Label(TYPE_KUBEVIRT), func() {
 
-	g.It("Destroy all KubeVirt hosted clusters on the hub", g.Label("destroy"), func() {
+	g.It("Destroy all KubeVirt hosted clusters on the hub", g.Label("destroy"), func(ctx g.SpecContext) {
 		startTime := time.Now()
 
-		// get list of kubevirt hosted clusters
-		hostedClusterList, err := utils.GetHostedClustersList(dynamicClient, TYPE_KUBEVIRT, "")
+		// HCP_DESTROY_SELECTOR, else options.destroySelector, else the KubeVirt hosted clusters of the owner
+		selector := utils.GetDestroySelector()
+		// each cluster gets its own timeout once it is destroyed, the batch is only bound by the spec
+		summary, err := utils.DestroyHostedClusters(ctx, dynamicClient, kubeClient, selector, TYPE_KUBEVIRT, utils.DestroyHostedClustersOptions{
+			Creds:   utils.KubeVirtBulkDestroyCreds(),
+			Timeout: eventuallyTimeout,
+			Since:   startTime,
+		})
 		o.Expect(err).ShouldNot(o.HaveOccurred())
 
-		// if hostedClusterList is empty, skip the test
-		if len(hostedClusterList) == 0 {
-			g.Skip("No KubeVirt hosted clusters found on the hub")
-		}
-
-		// Run destroy command on all kubevirt hosted clusters without waiting to verify at first
-		for _, hostedCluster := range hostedClusterList {
-			fmt.Printf("KubeVirt Hosted Cluster found: %s\n", hostedCluster.GetName())
-
-			commandArgs := []string{
-				"destroy", "cluster", strings.ToLower(TYPE_KUBEVIRT),
-				"--name", hostedCluster.GetName(),
-				"--namespace", hostedCluster.GetNamespace(),
-				"--destroy-cloud-resources",
-			}
-
-			cmd := exec.Command(utils.HypershiftCLIName, commandArgs...)
-			session, err := gexec.Start(cmd, g.GinkgoWriter, g.GinkgoWriter)
-			defer gexec.KillAndWait()
-			o.Expect(err).ShouldNot(o.HaveOccurred())
-			o.Eventually(session, eventuallyTimeout, eventuallyInterval).Should(gexec.Exit(0))
-			utils.PrintOutput(session) // prints command, args and output
+		// if no hosted cluster was selected, skip the test
+		if len(summary.Results) == 0 {
+			g.Skip(fmt.Sprintf("No KubeVirt hosted clusters found on the hub with selector %q", selector))
 		}
 
-		// Now we can verify each hosted cluster has sucecssfully been cleaned up
-		for _, hostedCluster := range hostedClusterList {
-			g.By(fmt.Sprintf("Waiting for hosted cluster %s to be removed", hostedCluster.GetName()), func() {
-				utils.WaitForHostedClusterDestroyed(dynamicClient, hostedCluster.GetName())
+		// Verify each destroyed hosted cluster has sucecssfully been cleaned up
+		for _, result := range summary.Destroyed() {
+			g.By(fmt.Sprintf("Waiting for managed cluster %s to be removed", result.Name), func() {
+				utils.WaitForClusterDetached(dynamicClient, result.Name)
 			})
+			o.Expect(utils.RemoveClusterState(result.Namespace, result.Name)).Should(o.Succeed())
 
-			g.By(fmt.Sprintf("Waiting for managed cluster %s to be removed", hostedCluster.GetName()), func() {
-				utils.WaitForClusterDetached(dynamicClient, hostedCluster.GetName())
+			g.By(fmt.Sprintf("Verifying hosted cluster %s is cleaned up", result.Name), func() {
+				ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+				defer cancel()
+				o.Expect(utils.WaitForClusterCleanup(ctx, dynamicClient, kubeClient, result.Namespace, result.Name)).Should(o.Succeed())
 			})
 		}
+		o.Expect(summary.Err()).ShouldNot(o.HaveOccurred())
 
 		fmt.Printf("Test Duration: %s\n", time.Since(startTime).String())
 		fmt.Println("========================= End Test Destroy Hosted Clusters ===============================")
 	})
 
 	g.It("Destroy a KubeVirt hosted cluster on the hub", g.Label("destroy-one"), func() {
-		startTime := time.Now()
+		// HCP_CLUSTER_NAME, else the KubeVirt cluster created earlier in the run
+		config.ClusterName, err = utils.GetClusterName(TYPE_KUBEVIRT)
+		gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
 
-		config.ClusterName, err = utils.GetClusterName("")
+		config.Namespace, err = utils.GetNamespace(TYPE_KUBEVIRT)
 		gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
 
 		if config.ClusterName == "" {
-			g.Skip("HCP_CLUSTER_NAME is not defined. Please supply the name of the cluster to destroy before running.")
-		}
-
-		if curatorEnabled == "true" {
-			fmt.Println("CURATOR ENABLED, INITILIZE DESTROY VIA CURATOR")
-			o.Expect(utils.SetDesiredCuration(dynamicClient, config.ClusterName, config.Namespace, "destroy")).Should(o.BeNil())
-
-			g.By(fmt.Sprintf("Waiting for prehook-ansiblejob to complete with status True and reason job_has_finished for the cluster %s", config.ClusterName), func() {
-				o.Eventually(func() error {
-					return utils.CheckCuratorCondition(dynamicClient, config.ClusterName, config.Namespace, "prehook-ansiblejob", "True", "Completed executing init container", "Job_has_finished")
-				}, eventuallyTimeout, eventuallyInterval).Should(o.BeNil())
-				fmt.Printf("Prehook ansiblejob completed successfully for the cluster %s\n", config.ClusterName)
-				fmt.Printf("Time taken for the prehook-ansiblejob to complete: %s\n", time.Since(startTime).String())
-			})
-		} else {
-			commandArgs := []string{
-				"destroy", "cluster", strings.ToLower(TYPE_KUBEVIRT),
-				"--name", config.ClusterName,
-				"--namespace", config.Namespace,
-				"--destroy-cloud-resources",
-			}
-
-			cmd := exec.Command(utils.HypershiftCLIName, commandArgs...)
-			session, err := gexec.Start(cmd, g.GinkgoWriter, g.GinkgoWriter)
-			defer gexec.KillAndWait()
-			o.Expect(err).ShouldNot(o.HaveOccurred())
-			o.Eventually(session, eventuallyTimeout, eventuallyInterval).Should(gexec.Exit(0))
-			utils.PrintOutput(session) // prints command, args and output
+			g.Skip("HCP_CLUSTER_NAME is not defined and no KubeVirt cluster is in the run state. Please supply the name of the cluster to destroy before running.")
 		}
 
-		// Now we can verify the hosted cluster has sucecssfully been cleaned up
-		g.By(fmt.Sprintf("Waiting for HostedCluster %s to be removed", config.ClusterName), func() {
-			utils.WaitForHostedClusterDestroyed(dynamicClient, config.ClusterName)
-		})
-
-		if curatorEnabled == "true" {
-			g.By(fmt.Sprintf("Waiting for Job_has_finished to True for hypershift-uninstalling-job for the cluster curator  %s", config.ClusterName), func() {
-				o.Eventually(func() error {
-					return utils.CheckCuratorCondition(dynamicClient, config.ClusterName, config.Namespace, "hypershift-uninstalling-job", "True", "-uninstall", "Job_has_finished")
-				}, eventuallyTimeout, eventuallyInterval).Should(o.BeNil())
-				fmt.Printf("hypershift-uninstalling-job completed successfully for the cluster %s\n", config.ClusterName)
-				fmt.Printf("Time taken for the hypershift-uninstalling-job to complete: %s\n", time.Since(startTime).String())
-			})
-
-			g.By(fmt.Sprintf("Waiting AnsibleJob for posthook-ansiblejob to complete for the cluster %s", config.ClusterName), func() {
-				o.Eventually(func() bool {
-					ansibleJob, err := utils.GetCurrentAnsibleJob(dynamicClient, config.ClusterName, config.Namespace)
-					if ansibleJob == nil || err != nil {
-						return false
-					}
-					isFinished := ansibleJob.Object["status"].(map[string]interface{})["isFinished"]
-					hookVar := ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})["hook"]
-
-					fmt.Printf("AnsibleJob isFinished field for the cluster %s: %#v\n", config.ClusterName, isFinished)
-					return isFinished != nil && isFinished.(bool) == true &&
-						hookVar != nil && hookVar.(string) == "post"
-				}, eventuallyTimeout, eventuallyInterval).Should(o.BeTrue())
-				fmt.Printf("Posthook ansiblejob completed successfully for the cluster %s\n", config.ClusterName)
-			})
-		}
-
-		g.By(fmt.Sprintf("Waiting for ManagedCluster %s to be removed", config.ClusterName), func() {
-			utils.WaitForClusterDetached(dynamicClient, config.ClusterName)
-		})
-
-		fmt.Printf("Test Duration: %s\n", time.Since(startTime).String())
-		fmt.Println("========================= End Test Destroy Hosted Cluster ===============================")
+		destroyHostedCluster(TYPE_KUBEVIRT, utils.KubeVirtDestroyCommand(config))
 	})
 })
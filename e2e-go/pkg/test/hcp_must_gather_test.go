@@ -1,31 +1,42 @@
 package hypershift_test
 
 import (
-	"os/exec"
+	"context"
+	"fmt"
+	"path/filepath"
 
 	ginkgo "github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
-	"github.com/onsi/gomega/gexec"
 	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
 )
 
 var _ = ginkgo.Describe("Hypershift Add-on Must-Gather Tests:", ginkgo.Label("@must-gather"), func() {
 
 	ginkgo.It("Triggers must-gather on a particular hosted cluster", func() {
-		// TODO: get a hosted cluster and its namespace if HCP is provided
-		// TOOD: pick random hosted cluster if not provided or not found
-		// TODO: run must-gather on the hub using hosted cluster
-		// TODO: param needed: must-gather image -> if not provided, test will skip.
+		image := utils.GetMustGatherImage()
+		if image == "" {
+			ginkgo.Skip("must-gather test requires an image (set MUST_GATHER_IMAGE or options.mustgather.image)")
+		}
 
-		// assumes running from /e2e-go/pkg/test
-		pathToScript := "./../../../scripts/must-gather/run_must_gather_hcp.sh"
+		ginkgo.By("Selecting the hosted cluster, a random one if none is given")
+		hc, err := utils.GetMustGatherHostedCluster()
+		gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
+		hc, err = utils.SelectHostedCluster(dynamicClient, hc)
+		gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
+		utils.ReportHostedCluster(hc.Namespace, hc.Name)
+		fmt.Printf("Running must-gather on the hosted cluster %s/%s\n", hc.Namespace, hc.Name)
 
-		cmd := exec.Command("/bin/sh", pathToScript)
+		ginkgo.By("Running oc adm must-gather")
+		destDir := filepath.Join(utils.GetArtifactsDir(), "must-gather", hc.Name)
+		ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+		defer cancel()
+		gomega.Expect(utils.RunMustGather(ctx, image, hc, destDir)).Should(gomega.Succeed())
 
-		session, err := gexec.Start(cmd, ginkgo.GinkgoWriter, ginkgo.GinkgoWriter)
-		defer gexec.KillAndWait()
-		utils.PrintOutput(session) // prints command, args and output
+		ginkgo.By("Checking the hypershift dump contents")
+		dump, err := utils.FindHypershiftDump(destDir, hc)
+		gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
+		archive, err := utils.ParseMustGatherArchive(dump)
 		gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
-		gomega.Eventually(session, eventuallyTimeout, eventuallyInterval).Should(gexec.Exit(0))
+		gomega.Expect(utils.ValidateHypershiftDump(archive, hc)).Should(gomega.Succeed())
 	})
 })
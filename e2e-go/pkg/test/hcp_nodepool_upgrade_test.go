@@ -31,10 +31,12 @@ var _ = ginkgo.Describe("Nodepool-only upgrade", ginkgo.Label("e2e", labelNodepo
 		var err error
 		clusterName, err = utils.GetClusterName("aws")
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
-		gomega.Expect(clusterName).NotTo(gomega.BeEmpty(), "HCP_CLUSTER_NAME or options.clusters.aws.clusterName must be set")
+		gomega.Expect(clusterName).NotTo(gomega.BeEmpty(), "HCP_CLUSTER_NAME or options.clusters.aws.clusterName must be set, or an AWS cluster created earlier in the run")
 
 		namespace, err = utils.GetNamespace(TYPE_AWS)
 		gomega.Expect(err).NotTo(gomega.HaveOccurred())
+		utils.ReportHostedCluster(namespace, clusterName)
+		utils.RecordConditionTimeline(dynamicClient, namespace, clusterName)
 
 		desiredUpdate = utils.GetClusterCuratorDesiredUpdate()
 		upgradeType = utils.GetClusterCuratorUpgradeType()
@@ -71,11 +73,9 @@ var _ = ginkgo.Describe("Nodepool-only upgrade", ginkgo.Label("e2e", labelNodepo
 
 		ginkgo.By("Waiting for ClusterCurator clustercurator-job condition to become True (upgrade completed)")
 		timeout := 30 * time.Minute // nodepool upgrade typically requires ~30 minutes
-		interval := 15 * time.Second
-		gomega.Eventually(func() error {
-			return utils.CheckCuratorCondition(dynamicClient, clusterName, namespace,
-				"clustercurator-job", string(metav1.ConditionTrue), "", "Job_has_finished")
-		}, timeout, interval).ShouldNot(gomega.HaveOccurred())
+		err = utils.WaitForCuratorCondition(dynamicClient, clusterName, namespace,
+			"clustercurator-job", string(metav1.ConditionTrue), "", "Job_has_finished", timeout)
+		gomega.Expect(err).NotTo(gomega.HaveOccurred())
 
 		ginkgo.By("Verifying all NodePools spec.release reflects desired version")
 		nodePools, err = utils.ListNodePoolsForHostedCluster(dynamicClient, namespace, clusterName)
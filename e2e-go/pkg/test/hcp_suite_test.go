@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,23 +26,6 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-type Config struct {
-	ClusterName      string
-	InstanceType     string
-	BaseDomain       string
-	Region           string
-	NodePoolReplicas string
-	ReleaseImage     string
-	Namespace        string
-	PullSecret       string
-	AWSCreds         string
-	ExternalDNS      string
-	SecretCredsName  string
-	ClusterArch      string
-	AWSStsCreds      string
-	AWSRoleArn       string
-}
-
 const (
 	eventuallyTimeout      = 30 * time.Minute
 	eventuallyTimeoutShort = 10 * time.Minute
@@ -61,16 +45,27 @@ var (
 	defaultManagedCluster     string
 	defaultInstallNamespace   string
 	mceNamespace              string
-	config                    Config
+	config                    utils.ClusterConfig
 	err                       error
 	hcpCliConsoleDownloadSpec map[string]interface{}
 	curatorEnabled            string
 	fipsEnabled               string
+	runMetadata               utils.RunMetadata
 )
 
 func TestE2e(t *testing.T) {
 	gomega.RegisterFailHandler(ginkgo.Fail)
-	ginkgo.RunSpecs(t, "Hypershift E2e Suite")
+
+	// RERUN_FAILED_FROM runs only the specs that failed in previous JUnit reports
+	suiteConfig, reporterConfig := ginkgo.GinkgoConfiguration()
+	failedSpecs, err := utils.FocusFailedSpecs(&suiteConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failedSpecs != nil && len(failedSpecs) == 0 {
+		t.Skipf("no failed spec in %s", os.Getenv(utils.RerunFailedFromEnv))
+	}
+	ginkgo.RunSpecs(t, "Hypershift E2e Suite", suiteConfig, reporterConfig)
 }
 
 // This suite is sensitive to the following environment variables:
@@ -219,14 +214,64 @@ var _ = ginkgo.SynchronizedBeforeSuite(func() {
 
 	fipsEnabled, err = utils.GetFIPSEnabled()
 	gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	// run metadata added to the JUnit report as properties
+	runMetadata = utils.GetRunMetadata(dynamicClient, cfg.Host, config.ReleaseImage)
 }, func() {})
 
+// Skip the specs quarantined in resources/quarantine.yaml (or QUARANTINE_FILE) on the MCE version
+// of the hub, with the reason and tracking issue in the JUnit skipped message.
+var _ = ginkgo.BeforeEach(func() {
+	utils.SkipIfQuarantined(runMetadata.MCEVersion)
+})
+
+// On failure, dump the HostedClusters the spec worked on, their control plane namespaces and the
+// hypershift operator and addon agent logs into results/<spec>/. GenerateJUnitReport links the
+// directory from the spec system-out.
+var _ = ginkgo.ReportAfterEach(func(report ginkgo.SpecReport) {
+	if !report.Failed() {
+		return
+	}
+	if dynamicClient != nil && kubeClient != nil {
+		clusters := utils.HostedClustersFromReport(report)
+		if len(clusters) == 0 && config.ClusterName != "" {
+			clusters = append(clusters, utils.HostedClusterRef{Namespace: config.Namespace, Name: config.ClusterName})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		dir, err := utils.CollectFailureArtifacts(ctx, dynamicClient, kubeClient, report, clusters)
+		if err != nil {
+			fmt.Printf("Failed to collect some failure artifacts: %v\n", err)
+		}
+		fmt.Printf("Failure artifacts for %q are in %s\n", report.FullText(), dir)
+	}
+
+	// classified after the collection so the operator and addon agent logs are considered
+	fmt.Printf("Failure of %q classified as %s\n", report.FullText(), utils.ClassifySpecFailure(report))
+})
+
 var _ = ginkgo.ReportAfterSuite("HyperShift E2E Report", func(report ginkgo.Report) {
 	junit_report_file := os.Getenv("JUNIT_REPORT_FILE")
 	if junit_report_file != "" {
-		err := utils.GenerateJUnitReport(report, junit_report_file)
+		err := utils.GenerateJUnitReport(report, junit_report_file, runMetadata.WithReport(report).Properties()...)
 		if err != nil {
 			fmt.Printf("Failed to generate the report due to: %v", err)
 		}
 	}
+
+	if polarionReportFile := os.Getenv(utils.PolarionReportFileEnv); polarionReportFile != "" {
+		if _, err := utils.GeneratePolarionReport(report, polarionReportFile, utils.GetPolarionOptions()); err != nil {
+			fmt.Printf("Failed to generate the Polarion report due to: %v\n", err)
+		}
+	}
+
+	if phases := utils.PhaseResultsFromReport(report); len(phases) > 0 {
+		files, err := utils.WritePhaseSummary(phases, utils.GetArtifactsDir())
+		if err != nil {
+			fmt.Printf("Failed to write the phase durations: %v\n", err)
+		} else {
+			fmt.Printf("Phase durations written to %s\n", strings.Join(files, ", "))
+		}
+	}
 })
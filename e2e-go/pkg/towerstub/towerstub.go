@@ -0,0 +1,461 @@
+// Package towerstub provides a stand-in for the Ansible Tower/AAP controller API that the
+// curator hooks run against, so curator hook tests do not need a shared AAP.
+//
+// The server implements the subset of the /api/v2 API the AnsibleJob runner of the ansible
+// resource operator calls: looking up job and workflow templates by name, launching them and
+// polling the launched job until it finishes. Any template name is accepted. Each launched job
+// finishes with the outcome scripted for its template, after the scripted duration:
+//
+//	stub := towerstub.New(towerstub.Options{Token: "stub-token"})
+//	stub.SetOutcome("Auto_CLC_Sample_Template", towerstub.Outcome{Status: towerstub.StatusFailed})
+//	http.ListenAndServe(":8080", stub)
+//
+// Outcomes can also be changed at runtime with PUT /stub/outcomes/<template>, and the launches
+// are listed by GET /stub/launches.
+package towerstub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Final statuses of a Tower job.
+const (
+	StatusSuccessful = "successful"
+	StatusFailed     = "failed"
+	StatusError      = "error"
+	StatusCanceled   = "canceled"
+
+	statusRunning = "running"
+
+	// DefaultToken is the token the stub accepts when Options.Token is empty.
+	DefaultToken = "tower-stub-token"
+
+	apiPrefix = "/api/v2/"
+)
+
+// Outcome is how the jobs of a template end.
+type Outcome struct {
+	// Status is successful (default), failed, error or canceled
+	Status string `json:"status,omitempty"`
+	// Duration is how long the job runs, e.g. 10m for a slow job
+	Duration Duration `json:"duration,omitempty"`
+}
+
+// Duration is a time.Duration read from and written as a string like 30s.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Validate checks the status of the outcome.
+func (o Outcome) Validate() error {
+	switch o.Status {
+	case "", StatusSuccessful, StatusFailed, StatusError, StatusCanceled:
+	default:
+		return fmt.Errorf("ERROR unknown job status %q, expected %s, %s, %s or %s", o.Status, StatusSuccessful, StatusFailed, StatusError, StatusCanceled)
+	}
+	if o.Duration < 0 {
+		return fmt.Errorf("ERROR negative job duration %s", time.Duration(o.Duration))
+	}
+	return nil
+}
+
+func (o Outcome) status() string {
+	if o.Status == "" {
+		return StatusSuccessful
+	}
+	return o.Status
+}
+
+// Script is the outcome of the jobs of each template, and of the templates not listed.
+type Script struct {
+	Default   Outcome            `json:"default,omitempty"`
+	Templates map[string]Outcome `json:"templates,omitempty"`
+}
+
+// Validate checks every outcome of the script.
+func (s Script) Validate() error {
+	if err := s.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %v", err)
+	}
+	for name, outcome := range s.Templates {
+		if err := outcome.Validate(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Options configures the stub.
+type Options struct {
+	// Token is the bearer token the requests must carry. Defaults to DefaultToken.
+	Token string
+	// Script is the initial outcome of the jobs.
+	Script Script
+	// Now returns the current time, defaults to time.Now.
+	Now func() time.Time
+}
+
+// Launch is a job launched from a template.
+type Launch struct {
+	ID        int                    `json:"id"`
+	Type      string                 `json:"type"` // job or workflow_job
+	Template  string                 `json:"template"`
+	ExtraVars map[string]interface{} `json:"extra_vars,omitempty"`
+	Outcome   Outcome                `json:"outcome"`
+	Started   time.Time              `json:"started"`
+}
+
+type template struct {
+	id   int
+	name string
+	// kind is job_template or workflow_job_template
+	kind string
+}
+
+// Server is the Tower API stand-in.
+type Server struct {
+	opts Options
+
+	mu        sync.Mutex
+	templates map[string]*template
+	outcomes  map[string]Outcome
+	launches  []*Launch
+	nextID    int
+}
+
+// New returns a stub serving the script of opts.
+func New(opts Options) *Server {
+	if opts.Token == "" {
+		opts.Token = DefaultToken
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	s := &Server{opts: opts, templates: map[string]*template{}, outcomes: map[string]Outcome{}}
+	for name, outcome := range opts.Script.Templates {
+		s.outcomes[name] = outcome
+	}
+	return s
+}
+
+// SetOutcome changes the outcome of the jobs launched from now on from the template.
+func (s *Server) SetOutcome(templateName string, outcome Outcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outcomes[templateName] = outcome
+}
+
+// Launches returns the jobs launched so far, oldest first.
+func (s *Server) Launches() []Launch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	launches := []Launch{}
+	for _, launch := range s.launches {
+		launches = append(launches, *launch)
+	}
+	return launches
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-API-Product-Name", "AWX")
+	w.Header().Set("X-API-Product-Version", "tower-stub")
+	path := strings.TrimSuffix(r.URL.Path, "/") + "/"
+
+	switch {
+	case path == apiPrefix+"ping/" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"version": "tower-stub", "active_node": "tower-stub", "ha": false})
+		return
+	case strings.HasPrefix(path, "/stub/"):
+		s.serveControl(w, r, strings.TrimPrefix(path, "/stub/"))
+		return
+	case !strings.HasPrefix(path, apiPrefix):
+		writeError(w, http.StatusNotFound, "The requested resource could not be found.")
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+s.opts.Token {
+		writeError(w, http.StatusUnauthorized, "Authentication credentials were not provided.")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, apiPrefix), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "me" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, listResponse([]interface{}{map[string]interface{}{"id": 1, "username": "tower-stub"}}))
+	case len(parts) == 1 && parts[0] == "config" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"version": "tower-stub"})
+	case len(parts) == 1 && isTemplateKind(parts[0]) && r.Method == http.MethodGet:
+		s.listTemplates(w, parts[0], r.URL.Query())
+	case len(parts) == 2 && isTemplateKind(parts[0]) && r.Method == http.MethodGet:
+		s.getTemplate(w, parts[0], parts[1])
+	case len(parts) == 3 && isTemplateKind(parts[0]) && parts[2] == "launch" && r.Method == http.MethodPost:
+		s.launch(w, r, parts[0], parts[1])
+	case len(parts) == 2 && (parts[0] == "jobs" || parts[0] == "workflow_jobs") && r.Method == http.MethodGet:
+		s.getJob(w, parts[0], parts[1])
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "stdout" && r.Method == http.MethodGet:
+		s.getStdout(w, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "The requested resource could not be found.")
+	}
+}
+
+func isTemplateKind(collection string) bool {
+	return collection == "job_templates" || collection == "workflow_job_templates"
+}
+
+// templateFor returns the template of the collection with the name, registering it on first use
+// so any template name can be launched.
+func (s *Server) templateFor(collection, name string) *template {
+	key := collection + "/" + name
+	if t, ok := s.templates[key]; ok {
+		return t
+	}
+	s.nextID++
+	t := &template{id: s.nextID, name: name, kind: strings.TrimSuffix(collection, "s")}
+	s.templates[key] = t
+	return t
+}
+
+func (s *Server) templateByID(collection, id string) *template {
+	for key, t := range s.templates {
+		if strings.HasPrefix(key, collection+"/") && strconv.Itoa(t.id) == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func (t *template) object(collection string) map[string]interface{} {
+	url := fmt.Sprintf("%s%s/%d/", apiPrefix, collection, t.id)
+	return map[string]interface{}{
+		"id":                      t.id,
+		"type":                    t.kind,
+		"name":                    t.name,
+		"url":                     url,
+		"ask_variables_on_launch": true,
+		"related":                 map[string]interface{}{"launch": url + "launch/"},
+	}
+}
+
+func (s *Server) listTemplates(w http.ResponseWriter, collection string, query url.Values) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := []interface{}{}
+	if name := query.Get("name"); name != "" {
+		results = append(results, s.templateFor(collection, name).object(collection))
+	} else {
+		keys := []string{}
+		for key := range s.templates {
+			if strings.HasPrefix(key, collection+"/") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			results = append(results, s.templates[key].object(collection))
+		}
+	}
+	writeJSON(w, http.StatusOK, listResponse(results))
+}
+
+func (s *Server) getTemplate(w http.ResponseWriter, collection, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.templateByID(collection, id)
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, t.object(collection))
+}
+
+func (s *Server) launch(w http.ResponseWriter, r *http.Request, collection, id string) {
+	body := struct {
+		ExtraVars interface{} `json:"extra_vars"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid launch request: %v", err))
+			return
+		}
+	}
+	extraVars, err := parseExtraVars(body.ExtraVars)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.templateByID(collection, id)
+	if t == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	outcome, ok := s.outcomes[t.name]
+	if !ok {
+		outcome = s.opts.Script.Default
+	}
+	s.nextID++
+	launch := &Launch{ID: s.nextID, Type: "job", Template: t.name, ExtraVars: extraVars, Outcome: outcome, Started: s.opts.Now()}
+	if collection == "workflow_job_templates" {
+		launch.Type = "workflow_job"
+	}
+	s.launches = append(s.launches, launch)
+
+	job := s.jobObject(launch)
+	job[launch.Type] = launch.ID
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// parseExtraVars accepts the extra_vars of a launch as an object or as a JSON string.
+func parseExtraVars(extraVars interface{}) (map[string]interface{}, error) {
+	switch v := extraVars.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		vars := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(v), &vars); err != nil {
+			return nil, fmt.Errorf("invalid extra_vars: %v", err)
+		}
+		return vars, nil
+	}
+	return nil, fmt.Errorf("invalid extra_vars of type %T", extraVars)
+}
+
+func (s *Server) findLaunch(collection, id string) *Launch {
+	for _, launch := range s.launches {
+		if launch.Type+"s" == collection && strconv.Itoa(launch.ID) == id {
+			return launch
+		}
+	}
+	return nil
+}
+
+// jobObject returns the Tower job of the launch as of now: running for the scripted duration,
+// then finished with the scripted status.
+func (s *Server) jobObject(launch *Launch) map[string]interface{} {
+	elapsed := s.opts.Now().Sub(launch.Started)
+	duration := time.Duration(launch.Outcome.Duration)
+	extraVars, _ := json.Marshal(launch.ExtraVars)
+	job := map[string]interface{}{
+		"id":         launch.ID,
+		"type":       launch.Type,
+		"url":        fmt.Sprintf("%s%ss/%d/", apiPrefix, launch.Type, launch.ID),
+		"name":       launch.Template,
+		"extra_vars": string(extraVars),
+		"started":    launch.Started.UTC().Format(time.RFC3339),
+		"finished":   nil,
+		"failed":     false,
+		"elapsed":    elapsed.Seconds(),
+	}
+	if elapsed < duration {
+		job["status"] = statusRunning
+	} else {
+		job["status"] = launch.Outcome.status()
+		job["failed"] = launch.Outcome.status() != StatusSuccessful
+		job["finished"] = launch.Started.Add(duration).UTC().Format(time.RFC3339)
+		job["elapsed"] = duration.Seconds()
+	}
+	return job
+}
+
+func (s *Server) getJob(w http.ResponseWriter, collection, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	launch := s.findLaunch(collection, id)
+	if launch == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.jobObject(launch))
+}
+
+func (s *Server) getStdout(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	launch := s.findLaunch("jobs", id)
+	if launch == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "tower-stub: job %d of template %q: %s\n", launch.ID, launch.Template, s.jobObject(launch)["status"])
+}
+
+// serveControl serves the stub endpoints: GET launches, and PUT/DELETE outcomes/<template>
+// (outcomes/default for the templates not listed).
+func (s *Server) serveControl(w http.ResponseWriter, r *http.Request, path string) {
+	path = strings.Trim(path, "/")
+	switch {
+	case path == "launches" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Launches())
+	case strings.HasPrefix(path, "outcomes/") && (r.Method == http.MethodPut || r.Method == http.MethodDelete):
+		name := strings.TrimPrefix(path, "outcomes/")
+		outcome := Outcome{}
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&outcome); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid outcome: %v", err))
+				return
+			}
+			if err := outcome.Validate(); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		s.mu.Lock()
+		switch {
+		case name == "default":
+			s.opts.Script.Default = outcome
+		case r.Method == http.MethodDelete:
+			delete(s.outcomes, name)
+		default:
+			s.outcomes[name] = outcome
+		}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "The requested resource could not be found.")
+	}
+}
+
+func listResponse(results []interface{}) map[string]interface{} {
+	return map[string]interface{}{"count": len(results), "next": nil, "previous": nil, "results": results}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]interface{}{"detail": detail})
+}
@@ -0,0 +1,146 @@
+package towerstub
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type client struct {
+	t     *testing.T
+	url   string
+	token string
+}
+
+func (c client) do(method, path string, body interface{}) (int, map[string]interface{}) {
+	c.t.Helper()
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest(method, c.url+path, bytes.NewReader(data))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	obj := map[string]interface{}{}
+	_ = json.NewDecoder(resp.Body).Decode(&obj)
+	return resp.StatusCode, obj
+}
+
+// launch looks the template up by name and launches it, like the AnsibleJob runner.
+func (c client) launch(collection, name string, extraVars interface{}) map[string]interface{} {
+	c.t.Helper()
+	code, list := c.do(http.MethodGet, "/api/v2/"+collection+"/?name="+url.QueryEscape(name), nil)
+	if code != http.StatusOK || list["count"] != 1.0 {
+		c.t.Fatalf("GET %s?name=%s = %d %v", collection, name, code, list)
+	}
+	template := list["results"].([]interface{})[0].(map[string]interface{})
+	code, job := c.do(http.MethodPost, template["related"].(map[string]interface{})["launch"].(string), map[string]interface{}{"extra_vars": extraVars})
+	if code != http.StatusCreated {
+		c.t.Fatalf("launch %s = %d %v", name, code, job)
+	}
+	return job
+}
+
+func TestServer(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	stub := New(Options{
+		Script: Script{Templates: map[string]Outcome{"slow": {Duration: Duration(10 * time.Minute)}}},
+		Now:    func() time.Time { return now },
+	})
+	stub.SetOutcome("Auto_CLC_Sample_Template", Outcome{Status: StatusFailed})
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	c := client{t: t, url: server.URL, token: DefaultToken}
+
+	if code, _ := (client{t: t, url: server.URL, token: "wrong"}).do(http.MethodGet, "/api/v2/me/", nil); code != http.StatusUnauthorized {
+		t.Errorf("wrong token: got %d, want 401", code)
+	}
+	if code, _ := c.do(http.MethodGet, "/api/v2/ping/", nil); code != http.StatusOK {
+		t.Errorf("ping: got %d", code)
+	}
+
+	tests := []struct {
+		name       string
+		collection string
+		template   string
+		extraVars  interface{}
+		wantType   string
+		wantStatus string
+		wantFailed bool
+	}{
+		{
+			name:       "default outcome",
+			collection: "workflow_job_templates",
+			template:   "Demo Workflow Template",
+			extraVars:  map[string]interface{}{"hook": "pre", "stage": "install"},
+			wantType:   "workflow_job",
+			wantStatus: StatusSuccessful,
+		},
+		{
+			name:       "scripted failure",
+			collection: "job_templates",
+			template:   "Auto_CLC_Sample_Template",
+			extraVars:  `{"hook": "post"}`,
+			wantType:   "job",
+			wantStatus: StatusFailed,
+			wantFailed: true,
+		},
+		{
+			name:       "slow job",
+			collection: "job_templates",
+			template:   "slow",
+			wantType:   "job",
+			wantStatus: "running",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := client{t: t, url: server.URL, token: DefaultToken}
+			job := c.launch(tt.collection, tt.template, tt.extraVars)
+			if job["type"] != tt.wantType || job[tt.wantType] != job["id"] {
+				t.Errorf("launch: got %v, want a %s", job, tt.wantType)
+			}
+			code, polled := c.do(http.MethodGet, job["url"].(string), nil)
+			if code != http.StatusOK || polled["status"] != tt.wantStatus || polled["failed"] != tt.wantFailed {
+				t.Errorf("poll: got %d %v, want status %s failed %v", code, polled, tt.wantStatus, tt.wantFailed)
+			}
+		})
+	}
+
+	// the slow job finishes after its duration
+	now = now.Add(10 * time.Minute)
+	_, polled := c.do(http.MethodGet, "/api/v2/jobs/6/", nil)
+	if polled["status"] != StatusSuccessful || polled["elapsed"] != 600.0 {
+		t.Errorf("slow job after 10m: got %v", polled)
+	}
+
+	launches := stub.Launches()
+	if len(launches) != 3 || launches[0].ExtraVars["stage"] != "install" || launches[1].ExtraVars["hook"] != "post" {
+		t.Errorf("launches: got %+v", launches)
+	}
+
+	// outcomes can be changed at runtime
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/stub/outcomes/default", strings.NewReader(`{"status": "error", "duration": "1s"}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("PUT outcome: %v %v", resp, err)
+	}
+	job := c.launch("job_templates", "another", nil)
+	now = now.Add(time.Second)
+	if _, polled := c.do(http.MethodGet, job["url"].(string), nil); polled["status"] != StatusError {
+		t.Errorf("after PUT default outcome: got %v", polled)
+	}
+	req, _ = http.NewRequest(http.MethodPut, server.URL+"/stub/outcomes/another", strings.NewReader(`{"status": "flaky"}`))
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PUT invalid outcome: %v %v", resp, err)
+	}
+}
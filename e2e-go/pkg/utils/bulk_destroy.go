@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	DestroySelectorEnv = "HCP_DESTROY_SELECTOR"
+	// DestroyAllSelector selects every hosted cluster of the platform, whoever created it
+	DestroyAllSelector        = "*"
+	defaultDestroyConcurrency = 3
+)
+
+// DestroyHostedClustersOptions configures DestroyHostedClusters.
+type DestroyHostedClustersOptions struct {
+	// Concurrency is the number of clusters destroyed at once, default 3
+	Concurrency int
+	// Creds holds the hcp destroy credentials of AWS clusters and DestroyCloudResources
+	Creds HCPDestroyCluster
+	// Timeout bounds the hcp destroy of each cluster and, separately, the wait for its
+	// HostedCluster to be gone. It starts once the cluster gets a slot; zero is no timeout
+	Timeout time.Duration
+	// Since is when the destroy started, the operator logs of the diagnostics start there
+	Since time.Time
+	// Run runs the hcp destroy command, default HCPDestroyCluster.Run
+	Run func(ctx context.Context, cmd HCPDestroyCluster) error
+}
+
+// HostedClusterDestroyResult is the outcome of destroying one hosted cluster.
+type HostedClusterDestroyResult struct {
+	Namespace string
+	Name      string
+	Platform  string
+	Duration  time.Duration
+	Err       error
+}
+
+// DestroySummary lists the outcome of DestroyHostedClusters for every selected hosted cluster, in
+// the order they were listed.
+type DestroySummary struct {
+	Selector string
+	Results  []HostedClusterDestroyResult
+}
+
+// Destroyed returns the results of the hosted clusters that were removed.
+func (s DestroySummary) Destroyed() []HostedClusterDestroyResult {
+	destroyed := []HostedClusterDestroyResult{}
+	for _, result := range s.Results {
+		if result.Err == nil {
+			destroyed = append(destroyed, result)
+		}
+	}
+	return destroyed
+}
+
+// Err aggregates the errors of the hosted clusters that were not removed.
+func (s DestroySummary) Err() error {
+	errs := []error{}
+	for _, result := range s.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %v", result.Namespace, result.Name, result.Err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (s DestroySummary) String() string {
+	lines := []string{fmt.Sprintf("Destroyed %d of %d hosted cluster(s) selected by %q:", len(s.Destroyed()), len(s.Results), s.Selector)}
+	for _, result := range s.Results {
+		outcome := "destroyed"
+		if result.Err != nil {
+			outcome = "FAILED"
+		}
+		lines = append(lines, fmt.Sprintf("  %s/%s (%s): %s in %s", result.Namespace, result.Name, result.Platform, outcome, result.Duration.Round(time.Second)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetDestroySelector returns the label selector of the hosted clusters the destroy specs destroy,
+// in the following priority:
+// 1. HCP_DESTROY_SELECTOR env
+// 2. options.destroySelector
+// 3. The owner label of the clusters the suite creates, only the clusters of GetOwner
+//
+// DestroyAllSelector ("*") must be given explicitly to destroy every hosted cluster of the
+// platform, and is returned as the empty selector.
+func GetDestroySelector() string {
+	selector := os.Getenv(DestroySelectorEnv)
+	if selector == "" {
+		selector = TestOptions.Options.DestroySelector
+	}
+	if selector == "" {
+		selector = OwnerLabel + "=" + LabelValue(GetOwner())
+	}
+	if strings.TrimSpace(selector) == DestroyAllSelector {
+		return ""
+	}
+	return selector
+}
+
+// hcpDestroyCommand returns the hcp destroy invocation of a hosted cluster. The AWS credentials of
+// creds are only kept for an AWS cluster.
+func hcpDestroyCommand(platform, namespace, name string, creds HCPDestroyCluster) HCPDestroyCluster {
+	cmd := HCPDestroyCluster{
+		Platform:              platform,
+		Name:                  name,
+		Namespace:             namespace,
+		DestroyCloudResources: creds.DestroyCloudResources,
+	}
+	if strings.EqualFold(platform, TYPE_AWS) {
+		cmd.STSCreds = creds.STSCreds
+		cmd.RoleArn = creds.RoleArn
+		cmd.SecretCreds = creds.SecretCreds
+	}
+	return cmd
+}
+
+// DestroyHostedClusters destroys the hosted clusters of the platform, or of every platform when
+// platform is empty, matching the label selector. Up to opts.Concurrency clusters are destroyed
+// at once with `hcp destroy cluster <platform>`, and each is waited for until its HostedCluster
+// is gone. A cluster that fails to destroy is diagnosed with DiagnoseDestroyFailure and does not
+// stop the others. The error is only set when the hosted clusters cannot be listed.
+func DestroyHostedClusters(ctx context.Context, hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface,
+	selector, platform string, opts DestroyHostedClustersOptions) (DestroySummary, error) {
+	summary := DestroySummary{Selector: selector, Results: []HostedClusterDestroyResult{}}
+	hostedClusters, err := GetHostedClustersList(hubClientDynamic, platform, selector)
+	if err != nil {
+		return summary, fmt.Errorf("ERROR failed to list the %s hosted clusters with %q: %v", platform, selector, err)
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultDestroyConcurrency
+	}
+	if opts.Since.IsZero() {
+		opts.Since = time.Now()
+	}
+	if opts.Run == nil {
+		opts.Run = func(ctx context.Context, cmd HCPDestroyCluster) error {
+			_, err := cmd.Run(ctx)
+			return err
+		}
+	}
+
+	summary.Results = make([]HostedClusterDestroyResult, len(hostedClusters))
+	slots := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, hc := range hostedClusters {
+		result := &summary.Results[i]
+		result.Namespace, result.Name = hc.GetNamespace(), hc.GetName()
+		result.Platform = platform
+		if result.Platform == "" {
+			result.Platform, _, _ = unstructured.NestedString(hc.Object, "spec", "platform", "type")
+		}
+		fmt.Printf("Cluster %s: destroying %s hosted cluster in %s\n", result.Name, result.Platform, result.Namespace)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			startTime := time.Now()
+			cmd := hcpDestroyCommand(result.Platform, result.Namespace, result.Name, opts.Creds)
+			err := cmd.Validate()
+			if err == nil {
+				runCtx, cancel := withOptionalTimeout(ctx, opts.Timeout)
+				err = opts.Run(runCtx, cmd)
+				cancel()
+			}
+			if err != nil {
+				result.Err = DiagnoseDestroyFailure(hubClientDynamic, kubeClient, result.Namespace, result.Name, opts.Since, err)
+			} else {
+				waitCtx, cancel := withOptionalTimeout(ctx, opts.Timeout)
+				result.Err = WaitForHostedClusterDeleted(waitCtx, hubClientDynamic, kubeClient, result.Namespace, result.Name, opts.Since)
+				cancel()
+			}
+			result.Duration = time.Since(startTime)
+		}()
+	}
+	wg.Wait()
+	fmt.Println(summary)
+	return summary, nil
+}
+
+// withOptionalTimeout bounds ctx by timeout when it is set.
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
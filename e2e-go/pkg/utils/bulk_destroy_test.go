@@ -0,0 +1,140 @@
+package utils_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDestroyHostedClusters(t *testing.T) {
+	g := gomega.NewWithT(t)
+	t.Setenv(utils.ArtifactsDirEnv, t.TempDir())
+	ctx := context.TODO()
+
+	hub := fakehub.New(fakehub.Options{})
+	for i, run := range []string{"run-1", "run-1", "run-1", "run-1", "run-2"} {
+		hc := fakehub.NewHostedCluster("clusters", fmt.Sprintf("acmqe-hc-%d", i), utils.TYPE_KUBEVIRT, "quay.io/openshift-release-dev/ocp-release:4.16.0-multi", "")
+		hc.SetLabels(map[string]string{utils.RunIDLabel: run})
+		_, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(ctx, hc, metav1.CreateOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+
+	// acmqe-hc-2 fails to destroy, the others are deleted by the fake hcp destroy
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	opts := utils.DestroyHostedClustersOptions{
+		Concurrency: 2,
+		Creds:       utils.HCPDestroyCluster{STSCreds: "sts.json", DestroyCloudResources: true},
+		Run: func(ctx context.Context, cmd utils.HCPDestroyCluster) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}()
+			time.Sleep(10 * time.Millisecond)
+
+			args, err := cmd.Args()
+			if err != nil {
+				return err
+			}
+			if cmd.Name == "acmqe-hc-2" {
+				return fmt.Errorf("hcp %v exited with 1", args)
+			}
+			return hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace(cmd.Namespace).Delete(ctx, cmd.Name, metav1.DeleteOptions{})
+		},
+	}
+	summary, err := utils.DestroyHostedClusters(ctx, hub.Dynamic, hub.Kube, utils.RunIDLabel+"=run-1", utils.TYPE_KUBEVIRT, opts)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(maxRunning).To(gomega.Equal(2))
+
+	g.Expect(summary.Results).To(gomega.HaveLen(4))
+	destroyed := []string{}
+	for _, result := range summary.Destroyed() {
+		destroyed = append(destroyed, result.Name)
+	}
+	g.Expect(destroyed).To(gomega.ConsistOf("acmqe-hc-0", "acmqe-hc-1", "acmqe-hc-3"))
+	g.Expect(summary.Err()).To(gomega.MatchError(gomega.ContainSubstring("clusters/acmqe-hc-2: hcp [destroy cluster kubevirt --name acmqe-hc-2 --namespace clusters --destroy-cloud-resources] exited with 1")))
+	g.Expect(summary.Err().Error()).To(gomega.ContainSubstring("HostedCluster clusters/acmqe-hc-2 was not destroyed"))
+	g.Expect(summary.String()).To(gomega.ContainSubstring(`Destroyed 3 of 4 hosted cluster(s) selected by "hypershift-e2e.open-cluster-management.io/run-id=run-1":`))
+	g.Expect(summary.String()).To(gomega.ContainSubstring("  clusters/acmqe-hc-2 (KubeVirt): FAILED in "))
+
+	// the cluster of the other run is left alone
+	remaining, err := utils.GetHostedClustersList(hub.Dynamic, "", "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	names := []string{}
+	for _, hc := range remaining {
+		names = append(names, hc.GetName())
+	}
+	g.Expect(names).To(gomega.ConsistOf("acmqe-hc-2", "acmqe-hc-4"))
+
+	t.Setenv(utils.DestroySelectorEnv, "purpose=e2e")
+	g.Expect(utils.GetDestroySelector()).To(gomega.Equal("purpose=e2e"))
+}
+
+func TestGetDestroySelector(t *testing.T) {
+	g := gomega.NewWithT(t)
+	saved := utils.TestOptions
+	t.Cleanup(func() { utils.TestOptions = saved })
+	utils.TestOptions.Options.DestroySelector = ""
+	t.Setenv(utils.DestroySelectorEnv, "")
+
+	// only the clusters of the owner unless every cluster is asked for
+	g.Expect(utils.GetDestroySelector()).To(gomega.Equal(utils.OwnerLabel + "=" + utils.LabelValue(utils.GetOwner())))
+	utils.TestOptions.Options.DestroySelector = utils.RunIDLabel + "=1234"
+	g.Expect(utils.GetDestroySelector()).To(gomega.Equal(utils.RunIDLabel + "=1234"))
+	t.Setenv(utils.DestroySelectorEnv, utils.DestroyAllSelector)
+	g.Expect(utils.GetDestroySelector()).To(gomega.BeEmpty())
+}
+
+func TestDestroyHostedClustersTimeout(t *testing.T) {
+	g := gomega.NewWithT(t)
+	t.Setenv(utils.ArtifactsDirEnv, t.TempDir())
+	ctx := context.TODO()
+
+	hub := fakehub.New(fakehub.Options{})
+	for i := 0; i < 3; i++ {
+		hc := fakehub.NewHostedCluster("clusters", fmt.Sprintf("acmqe-hc-%d", i), utils.TYPE_KUBEVIRT, "quay.io/openshift-release-dev/ocp-release:4.16.0-multi", "")
+		_, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(ctx, hc, metav1.CreateOptions{})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+	}
+
+	// one at a time, each destroy takes most of the timeout: the clusters waiting for a slot
+	// must not spend theirs, only acmqe-hc-1 that hangs times out
+	opts := utils.DestroyHostedClustersOptions{
+		Concurrency: 1,
+		Timeout:     200 * time.Millisecond,
+		Run: func(ctx context.Context, cmd utils.HCPDestroyCluster) error {
+			if cmd.Name == "acmqe-hc-1" {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			select {
+			case <-time.After(150 * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace(cmd.Namespace).Delete(ctx, cmd.Name, metav1.DeleteOptions{})
+		},
+	}
+	summary, err := utils.DestroyHostedClusters(ctx, hub.Dynamic, hub.Kube, "", utils.TYPE_KUBEVIRT, opts)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	destroyed := []string{}
+	for _, result := range summary.Destroyed() {
+		destroyed = append(destroyed, result.Name)
+	}
+	g.Expect(destroyed).To(gomega.ConsistOf("acmqe-hc-0", "acmqe-hc-2"))
+	g.Expect(summary.Err()).To(gomega.MatchError(gomega.ContainSubstring("clusters/acmqe-hc-1: " + context.DeadlineExceeded.Error())))
+}
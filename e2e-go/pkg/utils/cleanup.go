@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+var ManifestWorkGVR = schema.GroupVersionResource{
+	Group:    "work.open-cluster-management.io",
+	Version:  "v1",
+	Resource: "manifestworks",
+}
+
+var KlusterletGVR = schema.GroupVersionResource{
+	Group:    "operator.open-cluster-management.io",
+	Version:  "v1",
+	Resource: "klusterlets",
+}
+
+// Components owning the resources of a hosted cluster, so a leftover can be reported against
+// the right one.
+const (
+	ComponentHypershiftOperator = "hypershift operator"
+	ComponentHCPCLI             = "hcp CLI"
+	ComponentRegistration       = "registration"
+	ComponentAddonManager       = "addon manager"
+	ComponentWork               = "work"
+	ComponentImportController   = "managedcluster-import-controller"
+	ComponentKlusterletOperator = "klusterlet operator"
+
+	cleanupPollInterval = 10 * time.Second
+)
+
+// ClusterLeftover is a resource of a destroyed hosted cluster that is still on the hub.
+type ClusterLeftover struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Component string
+	// Terminating is set when the resource is being deleted, e.g. waiting for its finalizers
+	Terminating bool
+}
+
+func (l ClusterLeftover) String() string {
+	name := l.Name
+	if l.Namespace != "" {
+		name = l.Namespace + "/" + l.Name
+	}
+	state := ""
+	if l.Terminating {
+		state = ", terminating"
+	}
+	return fmt.Sprintf("%s %s (%s%s)", l.Kind, name, l.Component, state)
+}
+
+// ClusterCleanupError lists the resources of a destroyed hosted cluster that were not removed.
+type ClusterCleanupError struct {
+	Namespace string
+	Name      string
+	Leftovers []ClusterLeftover
+	Err       error
+}
+
+func (e *ClusterCleanupError) Error() string {
+	lines := []string{fmt.Sprintf("ERROR hosted cluster %s/%s was not cleaned up after it was destroyed (%v), leftovers:", e.Namespace, e.Name, e.Err)}
+	for _, leftover := range e.Leftovers {
+		lines = append(lines, "  - "+leftover.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ClusterCleanupError) Unwrap() error {
+	return e.Err
+}
+
+// CheckClusterCleanup returns what is left on the hub of the destroyed hosted cluster namespace/name:
+//   - the control plane namespace <namespace>-<name>
+//   - the admin kubeconfig, kubeadmin password and SSH key secrets in namespace
+//   - the ManagedCluster namespace and the ManagedClusterAddOns and ManifestWorks in it
+//   - the hosted mode klusterlet: its ManifestWork in the local-cluster namespace, its Klusterlet
+//     and its agent namespace, as local-cluster hosts the klusterlets of the hosted clusters
+func CheckClusterCleanup(ctx context.Context, hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface, namespace, name string) ([]ClusterLeftover, error) {
+	leftovers := []ClusterLeftover{}
+
+	namespaces := []struct{ name, component string }{
+		{namespace + "-" + name, ComponentHypershiftOperator},
+		{name, ComponentRegistration},
+		{"klusterlet-" + name, ComponentKlusterletOperator},
+	}
+	for _, ns := range namespaces {
+		obj, err := kubeClient.CoreV1().Namespaces().Get(ctx, ns.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ERROR failed to get the namespace %s: %v", ns.name, err)
+		}
+		leftovers = append(leftovers, ClusterLeftover{Kind: "Namespace", Name: ns.name, Component: ns.component, Terminating: obj.DeletionTimestamp != nil})
+	}
+
+	secrets := []struct{ name, component string }{
+		{name + "-admin-kubeconfig", ComponentHypershiftOperator},
+		{name + "-kubeadmin-password", ComponentHypershiftOperator},
+		{name + "-ssh-key", ComponentHCPCLI},
+	}
+	for _, secret := range secrets {
+		obj, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, secret.name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ERROR failed to get the secret %s/%s: %v", namespace, secret.name, err)
+		}
+		leftovers = append(leftovers, ClusterLeftover{Kind: "Secret", Namespace: namespace, Name: secret.name, Component: secret.component, Terminating: obj.DeletionTimestamp != nil})
+	}
+
+	resources := []struct {
+		gvr       schema.GroupVersionResource
+		kind      string
+		namespace string
+		name      string
+		component string
+	}{
+		{gvr: ManagedClusterAddonGVR, kind: "ManagedClusterAddOn", namespace: name, component: ComponentAddonManager},
+		{gvr: ManifestWorkGVR, kind: "ManifestWork", namespace: name, component: ComponentWork},
+		{gvr: ManifestWorkGVR, kind: "ManifestWork", namespace: LocalClusterName, name: name + "-hosted-klusterlet", component: ComponentImportController},
+		{gvr: KlusterletGVR, kind: "Klusterlet", name: "klusterlet-" + name, component: ComponentKlusterletOperator},
+	}
+	for _, r := range resources {
+		list, err := hubClientDynamic.Resource(r.gvr).Namespace(r.namespace).List(ctx, metav1.ListOptions{})
+		if errors.IsNotFound(err) {
+			// the API is not served on this hub
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ERROR failed to list the %s in %q: %v", r.gvr.Resource, r.namespace, err)
+		}
+		for _, item := range list.Items {
+			if r.name != "" && item.GetName() != r.name {
+				continue
+			}
+			leftovers = append(leftovers, ClusterLeftover{Kind: r.kind, Namespace: r.namespace, Name: item.GetName(), Component: r.component, Terminating: item.GetDeletionTimestamp() != nil})
+		}
+	}
+	return leftovers, nil
+}
+
+// WaitForClusterCleanup polls CheckClusterCleanup until nothing of the destroyed hosted cluster
+// is left or ctx is done. On timeout the returned *ClusterCleanupError lists the leftovers.
+func WaitForClusterCleanup(ctx context.Context, hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface, namespace, name string) error {
+	var leftovers []ClusterLeftover
+	err := wait.PollUntilContextCancel(ctx, cleanupPollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := CheckClusterCleanup(ctx, hubClientDynamic, kubeClient, namespace, name)
+		if err != nil {
+			fmt.Printf("Cluster %s: %v\n", name, err)
+			return false, nil
+		}
+		if len(current) != len(leftovers) {
+			fmt.Printf("Cluster %s: %d resource(s) left to clean up\n", name, len(current))
+		}
+		leftovers = current
+		return len(leftovers) == 0, nil
+	})
+	if err != nil {
+		return &ClusterCleanupError{Namespace: namespace, Name: name, Leftovers: leftovers, Err: err}
+	}
+	fmt.Printf("Cluster %s: successfully cleaned up!\n\n", name)
+	return nil
+}
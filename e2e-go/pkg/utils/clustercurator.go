@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/stolostron/applier/pkg/applier"           // old (V1.0.1) version
 	"github.com/stolostron/applier/pkg/templateprocessor" // old (V1.0.1) version
@@ -13,8 +14,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -33,6 +36,27 @@ var AnsibleJobGVR = schema.GroupVersionResource{
 	Resource: "ansiblejobs",
 }
 
+// Hooks of a ClusterCurator curation stage (install, upgrade, scale or destroy). The curator
+// reports each on its <hook>-ansiblejob condition.
+const (
+	CuratorPrehook  = "prehook"
+	CuratorPosthook = "posthook"
+
+	// AnsibleJobSuccessful is the status.ansibleJobResult.status of an AnsibleJob that succeeded
+	AnsibleJobSuccessful = "successful"
+)
+
+// How the curator reports a failed curation: the condition of the failed step, e.g.
+// prehook-ansiblejob, and the clustercurator-job condition have the reason Job_failed.
+const (
+	CuratorJobFailed = "Job_failed"
+
+	// CuratorHookFailedMessage is in the message of a hook whose AnsibleJob did not succeed
+	CuratorHookFailedMessage = "exited with an error"
+	// CuratorHookTimeoutMessage is the message of a hook whose AnsibleJob ran past the jobMonitorTimeout of the stage
+	CuratorHookTimeoutMessage = "Timed out waiting for job"
+)
+
 func CreateOrUpdateAnsibleTowerSecret(clientClient client.Client, ansSecretName, ansSecretNs, ansHost, ansToken string) error {
 	var err error
 	if ansSecretName == "" || ansSecretNs == "" {
@@ -79,62 +103,144 @@ func CreateOrUpdateAnsibleTowerSecret(clientClient client.Client, ansSecretName,
 	return nil
 }
 
-func CreateOrUpdateClusterCurator(clientClient client.Client, hcName, hcNamespace, desiredCuration, hcPlatform, ansTowerSecret string) error {
-	if hcName == "" || hcNamespace == "" || desiredCuration == "" || hcPlatform == "" || ansTowerSecret == "" {
-		return fmt.Errorf("ERROR: cluster curator name, namespace, desiredcuration, platform, and tower secret must be provided")
+// Curation stages of a ClusterCurator, the values of spec.desiredCuration.
+const (
+	CurationInstall = "install"
+	CurationUpgrade = "upgrade"
+	CurationScale   = "scale"
+	CurationDestroy = "destroy"
+)
+
+// CuratorHook is an Ansible Tower job or workflow template run by a curation stage.
+type CuratorHook struct {
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type,omitempty"` // Job (default) or Workflow
+	ExtraVars map[string]interface{} `json:"extra_vars,omitempty"`
+}
+
+// CuratorStage is the spec of a curation stage of a ClusterCurator.
+type CuratorStage struct {
+	TowerAuthSecret   string        `json:"towerAuthSecret,omitempty"`
+	Prehook           []CuratorHook `json:"prehook,omitempty"`
+	Posthook          []CuratorHook `json:"posthook,omitempty"`
+	JobMonitorTimeout int           `json:"jobMonitorTimeout,omitempty"` // minutes
+
+	// upgrade only
+	MonitorTimeout int    `json:"monitorTimeout,omitempty"` // minutes
+	Channel        string `json:"channel,omitempty"`
+	DesiredUpdate  string `json:"desiredUpdate,omitempty"`
+	UpgradeType    string `json:"upgradeType,omitempty"` // ControlPlane, NodePools, or empty for both
+}
+
+// ClusterCuratorSpec is the spec of a ClusterCurator. A nil stage is left out.
+type ClusterCuratorSpec struct {
+	// DesiredCuration starts the curation when set; the CRD does not allow "", so it is omitted when empty
+	DesiredCuration string        `json:"desiredCuration,omitempty"`
+	Install         *CuratorStage `json:"install,omitempty"`
+	Upgrade         *CuratorStage `json:"upgrade,omitempty"`
+	Scale           *CuratorStage `json:"scale,omitempty"`
+	Destroy         *CuratorStage `json:"destroy,omitempty"`
+}
+
+// DefaultCuratorHooks returns the hooks the curator specs run on every stage: the
+// "Demo Workflow Template" workflow and the "Auto_CLC_Sample_Template" job, with the platform,
+// the hook (pre or post) and the stage as extra_vars.
+func DefaultCuratorHooks(platform, stage, hook string) []CuratorHook {
+	extraVars := func() map[string]interface{} {
+		return map[string]interface{}{"platform": platform, "hook": strings.TrimSuffix(hook, "hook"), "stage": stage}
+	}
+	return []CuratorHook{
+		{Name: "Demo Workflow Template", Type: "Workflow", ExtraVars: extraVars()},
+		{Name: "Auto_CLC_Sample_Template", Type: "Job", ExtraVars: extraVars()},
 	}
+}
 
-	createYamlReader := templateprocessor.NewYamlFileReader(filepath.Join(CLUSTER_CURATOR_TEST_FIXTURE_DIR, "cluster_curator.yaml"))
-	values := struct {
-		ClusterName        string
-		ClusterNamespace   string
-		DesiredCuration    string
-		ClusterPlatform    string
-		AnsibleTowerSecret string
-	}{
-		ClusterName:        hcName,
-		ClusterNamespace:   hcNamespace,
-		DesiredCuration:    desiredCuration,
-		ClusterPlatform:    hcPlatform,
-		AnsibleTowerSecret: ansTowerSecret,
+// DefaultClusterCuratorSpec returns the ClusterCurator spec of the curator specs: the default
+// hooks before and after the install, upgrade, scale and destroy stages, run with the tower secret.
+func DefaultClusterCuratorSpec(desiredCuration, platform, towerSecret string) ClusterCuratorSpec {
+	stage := func(name string) *CuratorStage {
+		return &CuratorStage{
+			TowerAuthSecret: towerSecret,
+			Prehook:         DefaultCuratorHooks(platform, name, CuratorPrehook),
+			Posthook:        DefaultCuratorHooks(platform, name, CuratorPosthook),
+		}
 	}
+	spec := ClusterCuratorSpec{
+		DesiredCuration: desiredCuration,
+		Install:         stage(CurationInstall),
+		Upgrade:         stage(CurationUpgrade),
+		Scale:           stage(CurationScale),
+		Destroy:         stage(CurationDestroy),
+	}
+	spec.Install.JobMonitorTimeout = 5
+	spec.Scale.JobMonitorTimeout = 5
+	spec.Upgrade.MonitorTimeout = 120
+	spec.Destroy.JobMonitorTimeout = 5
+	return spec
+}
 
-	testApplier, err := applier.NewApplier(createYamlReader, &templateprocessor.Options{}, clientClient, nil, nil, nil)
+// NewClusterCurator returns the ClusterCurator CR namespace/name with the spec.
+func NewClusterCurator(name, namespace string, spec ClusterCuratorSpec) (*unstructured.Unstructured, error) {
+	specObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
 	if err != nil {
-		return fmt.Errorf("ERROR Applier failed to be created: %v", err)
+		return nil, fmt.Errorf("ERROR failed to convert the cluster curator spec: %v", err)
 	}
+	curator := &unstructured.Unstructured{Object: map[string]interface{}{"spec": specObj}}
+	curator.SetAPIVersion(ClusterCuratorGVR.GroupVersion().String())
+	curator.SetKind("ClusterCurator")
+	curator.SetName(name)
+	curator.SetNamespace(namespace)
+	return curator, nil
+}
 
-	err = testApplier.CreateOrUpdateInPath("", nil, true, values)
+// ApplyClusterCurator creates the ClusterCurator, or replaces the spec of the existing one.
+func ApplyClusterCurator(clientClient client.Client, curator *unstructured.Unstructured) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(curator.GroupVersionKind())
+	err := clientClient.Get(context.TODO(), client.ObjectKeyFromObject(curator), existing)
+	if errors.IsNotFound(err) {
+		if err := clientClient.Create(context.TODO(), curator); err != nil {
+			return fmt.Errorf("ERROR failed to create the cluster curator CR: %v", err)
+		}
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("ERROR Applier failed to create or update cluster curator CR: %v", err)
+		return fmt.Errorf("ERROR failed to get the cluster curator CR: %v", err)
+	}
+	curator.SetResourceVersion(existing.GetResourceVersion())
+	if err := clientClient.Update(context.TODO(), curator); err != nil {
+		return fmt.Errorf("ERROR failed to update the cluster curator CR: %v", err)
 	}
-
 	return nil
 }
 
-// CreateOrUpdateClusterCuratorForChannelUpgrade creates a minimal ClusterCurator for channel-upgrade only (PR 511).
-// No Ansible Tower secret or hooks; use with SetClusterCuratorUpgradeChannel and SetDesiredCuration("upgrade").
-func CreateOrUpdateClusterCuratorForChannelUpgrade(clientClient client.Client, hcName, hcNamespace string) error {
+// CreateOrUpdateClusterCuratorSpec creates or updates the ClusterCurator hcNamespace/hcName with the spec.
+func CreateOrUpdateClusterCuratorSpec(clientClient client.Client, hcName, hcNamespace string, spec ClusterCuratorSpec) error {
 	if hcName == "" || hcNamespace == "" {
 		return fmt.Errorf("ERROR: cluster curator name and namespace must be provided")
 	}
-	createYamlReader := templateprocessor.NewYamlFileReader(filepath.Join(CLUSTER_CURATOR_TEST_FIXTURE_DIR, "cluster_curator_channel_upgrade.yaml"))
-	values := struct {
-		ClusterName      string
-		ClusterNamespace string
-	}{
-		ClusterName:      hcName,
-		ClusterNamespace: hcNamespace,
-	}
-	testApplier, err := applier.NewApplier(createYamlReader, &templateprocessor.Options{}, clientClient, nil, nil, nil)
+	curator, err := NewClusterCurator(hcName, hcNamespace, spec)
 	if err != nil {
-		return fmt.Errorf("ERROR Applier failed to be created: %v", err)
+		return err
 	}
-	err = testApplier.CreateOrUpdateInPath("", nil, true, values)
-	if err != nil {
-		return fmt.Errorf("ERROR Applier failed to create or update cluster curator CR: %v", err)
+	return ApplyClusterCurator(clientClient, curator)
+}
+
+// CreateOrUpdateClusterCurator creates or updates the ClusterCurator of DefaultClusterCuratorSpec.
+func CreateOrUpdateClusterCurator(clientClient client.Client, hcName, hcNamespace, desiredCuration, hcPlatform, ansTowerSecret string) error {
+	if hcName == "" || hcNamespace == "" || desiredCuration == "" || hcPlatform == "" || ansTowerSecret == "" {
+		return fmt.Errorf("ERROR: cluster curator name, namespace, desiredcuration, platform, and tower secret must be provided")
 	}
-	return nil
+	return CreateOrUpdateClusterCuratorSpec(clientClient, hcName, hcNamespace, DefaultClusterCuratorSpec(desiredCuration, hcPlatform, ansTowerSecret))
+}
+
+// CreateOrUpdateClusterCuratorForChannelUpgrade creates a minimal ClusterCurator for channel-upgrade only (PR 511).
+// No Ansible Tower secret or hooks; use with SetClusterCuratorUpgradeChannel and SetDesiredCuration("upgrade").
+// desiredCuration is omitted (CRD allows only enum or null, not ""); the test patches it to "upgrade" after setting channel.
+func CreateOrUpdateClusterCuratorForChannelUpgrade(clientClient client.Client, hcName, hcNamespace string) error {
+	return CreateOrUpdateClusterCuratorSpec(clientClient, hcName, hcNamespace, ClusterCuratorSpec{
+		Upgrade: &CuratorStage{MonitorTimeout: 120},
+	})
 }
 
 func SetDesiredCuration(hubClientDynamic dynamic.Interface, curatorName, namespace, desiredCuration string) error {
@@ -214,25 +320,16 @@ func CheckCuratorCondition(hubClientDynamic dynamic.Interface, curatorName, name
 	if err != nil {
 		return fmt.Errorf("ERROR failed to get the cluster curator CR: %v", err)
 	}
+	return CheckCondition(clusterCurator, CuratorCondition(conType, expectedStatus, expectedMsg, expectedReason))
+}
 
-	var condition map[string]interface{}
-	condition, err = libgounstructuredv1.GetConditionByType(clusterCurator, conType)
-	if err != nil {
-		return fmt.Errorf("ERROR failed to get the cluster curator condition by type %s: %v", conType, err)
-	}
-	fmt.Printf("ClusterCurator %s: Condition %#v\n", curatorName, condition)
-
-	// Ensure the rest of the conditions have status set to expectedStatus
-	if v, ok := condition["status"]; ok && v == expectedStatus &&
-		(condition["reason"] == expectedReason) &&
-		(strings.Contains(condition["message"].(string), expectedMsg)) {
-		return nil
-	} else {
-		fmt.Printf("ClusterCurator %s: Expected \"%s\" but got \"%v\"\n", curatorName, expectedStatus, v)
-		return generateErrorMsg(UnknownError, UnknownErrorLink,
-			"ClusterCurator "+conType+" condition not reached",
-			"ClusterCurator "+conType+" condition not reached")
-	}
+// WaitForCuratorCondition watches the ClusterCurator until the condition matches or the timeout expires.
+func WaitForCuratorCondition(hubClientDynamic dynamic.Interface, curatorName, namespace, conType, expectedStatus, expectedMsg, expectedReason string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := WaitForCondition(ctx, hubClientDynamic, ClusterCuratorGVR, namespace, curatorName,
+		CuratorCondition(conType, expectedStatus, expectedMsg, expectedReason))
+	return err
 }
 
 func GetCurrentAnsibleJob(hubClientDynamic dynamic.Interface, curatorName, namespace string) (*unstructured.Unstructured, error) {
@@ -258,11 +355,154 @@ func GetCurrentAnsibleJob(hubClientDynamic dynamic.Interface, curatorName, names
 	fmt.Printf("ClusterCurator %s: Condition %#v\n", curatorName, "current-ansiblejob")
 
 	// get resource AnsibleJobGVR with the name condition["message"]
+	jobName, _ := condition["message"].(string)
+	if jobName == "" {
+		return nil, fmt.Errorf("ERROR the cluster curator condition %s has no AnsibleJob name", "current-ansiblejob")
+	}
 	var ansibleJob *unstructured.Unstructured
-	ansibleJob, err = GetResource(hubClientDynamic, AnsibleJobGVR, namespace, condition["message"].(string))
+	ansibleJob, err = GetResource(hubClientDynamic, AnsibleJobGVR, namespace, jobName)
 	if err != nil {
 		return nil, fmt.Errorf("ERROR failed to get the ansiblejob: %v", err)
 	}
 
 	return ansibleJob, nil
 }
+
+// AnsibleJobResult is what an AnsibleJob run by a ClusterCurator hook reports.
+type AnsibleJobResult struct {
+	Name     string
+	Template string
+	// Stage and Hook are spec.extra_vars.stage and spec.extra_vars.hook (pre or post), when set
+	Stage    string
+	Hook     string
+	Finished bool
+	// Status is status.ansibleJobResult.status: running, successful, failed, error or canceled
+	Status      string
+	StartedAt   string
+	FinishedAt  string
+	ElapsedTime string
+	URL         string
+}
+
+func (r AnsibleJobResult) String() string {
+	return fmt.Sprintf("AnsibleJob %s (template %q, stage %q, hook %q): status %q, started %s, finished %s, elapsed %s, url %s",
+		r.Name, r.Template, r.Stage, r.Hook, r.Status, r.StartedAt, r.FinishedAt, r.ElapsedTime, r.URL)
+}
+
+// Succeeded reports whether the job finished successfully.
+func (r AnsibleJobResult) Succeeded() bool {
+	return r.Finished && r.Status == AnsibleJobSuccessful
+}
+
+// GetAnsibleJobResult reads the result of an AnsibleJob. Missing or mistyped fields are left empty.
+func GetAnsibleJobResult(ansibleJob *unstructured.Unstructured) AnsibleJobResult {
+	str := func(fields ...string) string {
+		value, _, _ := unstructured.NestedFieldNoCopy(ansibleJob.Object, fields...)
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+	result := AnsibleJobResult{
+		Name:        ansibleJob.GetName(),
+		Template:    str("spec", "job_template_name"),
+		Stage:       str("spec", "extra_vars", "stage"),
+		Hook:        str("spec", "extra_vars", "hook"),
+		Status:      str("status", "ansibleJobResult", "status"),
+		StartedAt:   str("status", "ansibleJobResult", "started"),
+		FinishedAt:  str("status", "ansibleJobResult", "finished"),
+		ElapsedTime: str("status", "ansibleJobResult", "elapsed"),
+		URL:         str("status", "ansibleJobResult", "url"),
+	}
+	if result.Template == "" {
+		result.Template = str("spec", "workflow_template_name")
+	}
+	result.Finished, _, _ = unstructured.NestedBool(ansibleJob.Object, "status", "isFinished")
+	return result
+}
+
+// runsCuratorHook reports whether the AnsibleJob was launched for the hook of the curation stage,
+// from its extra_vars when they are set, else from its prehookjob-/posthookjob- name.
+func (r AnsibleJobResult) runsCuratorHook(stage, hook string) bool {
+	if r.Hook != "" && r.Hook+"hook" != hook {
+		return false
+	}
+	if r.Hook == "" && !strings.HasPrefix(r.Name, hook+"job-") {
+		return false
+	}
+	return r.Stage == "" || r.Stage == stage
+}
+
+// WaitForCuratorHook waits until the prehook or posthook of the curation stage of the
+// ClusterCurator is done: the current AnsibleJob of the curator runs that hook, it is finished
+// and successful, and the <hook>-ansiblejob condition of the curator is True. It returns the
+// result of the last AnsibleJob of the hook, also when the job failed or ctx is done.
+func WaitForCuratorHook(ctx context.Context, hubClientDynamic dynamic.Interface, curatorName, namespace, stage, hook string) (AnsibleJobResult, error) {
+	if hook != CuratorPrehook && hook != CuratorPosthook {
+		return AnsibleJobResult{}, fmt.Errorf("ERROR unknown cluster curator hook %q, expected %s or %s", hook, CuratorPrehook, CuratorPosthook)
+	}
+	conType := hook + "-ansiblejob"
+	var result AnsibleJobResult
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, eventuallyInterval, true, func(ctx context.Context) (bool, error) {
+		ansibleJob, err := GetCurrentAnsibleJob(hubClientDynamic, curatorName, namespace)
+		if err == nil && result.Name != "" && !GetAnsibleJobResult(ansibleJob).runsCuratorHook(stage, hook) {
+			// the curator moved on to its next job, check the job of the hook seen before
+			ansibleJob, err = GetResource(hubClientDynamic, AnsibleJobGVR, namespace, result.Name)
+		}
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		current := GetAnsibleJobResult(ansibleJob)
+		if !current.runsCuratorHook(stage, hook) {
+			lastErr = fmt.Errorf("the current AnsibleJob %s does not run the %s %s", current.Name, stage, hook)
+			return false, nil
+		}
+		result = current
+		fmt.Printf("ClusterCurator %s: %s\n", curatorName, result)
+		if !result.Finished {
+			lastErr = fmt.Errorf("AnsibleJob %s has not finished", result.Name)
+			return false, nil
+		}
+		if !result.Succeeded() {
+			return false, fmt.Errorf("ERROR the %s %s of ClusterCurator %s/%s failed: %s", stage, hook, namespace, curatorName, result)
+		}
+		if err := CheckCuratorCondition(hubClientDynamic, curatorName, namespace, conType, "True", "Completed executing init container", "Job_has_finished"); err != nil {
+			lastErr = err
+			return false, nil
+		}
+		return true, nil
+	})
+	if wait.Interrupted(err) {
+		return result, fmt.Errorf("ERROR timed out waiting for the %s %s of ClusterCurator %s/%s: %v", stage, hook, namespace, curatorName, lastErr)
+	}
+	if err != nil {
+		return result, err
+	}
+	fmt.Printf("ClusterCurator %s: %s %s completed successfully\n", curatorName, stage, hook)
+	return result, nil
+}
+
+// WaitForCuratorHookFailure waits until the prehook or posthook of the curation failed: the
+// <hook>-ansiblejob condition of the ClusterCurator has the reason Job_failed and expectedMsg in
+// its message, e.g. CuratorHookFailedMessage or CuratorHookTimeoutMessage, and then the
+// clustercurator-job condition reports the curation as Job_failed. The hook condition is reset
+// when a curation is retried, so a retry is only seen failing once its hook did.
+func WaitForCuratorHookFailure(ctx context.Context, hubClientDynamic dynamic.Interface, curatorName, namespace, curation, hook, expectedMsg string) error {
+	if hook != CuratorPrehook && hook != CuratorPosthook {
+		return fmt.Errorf("ERROR unknown cluster curator hook %q, expected %s or %s", hook, CuratorPrehook, CuratorPosthook)
+	}
+	_, err := WaitForCondition(ctx, hubClientDynamic, ClusterCuratorGVR, namespace, curatorName,
+		CuratorCondition(hook+"-ansiblejob", "", expectedMsg, CuratorJobFailed))
+	if err != nil {
+		return fmt.Errorf("ERROR the %s %s of ClusterCurator %s/%s did not fail: %v", curation, hook, namespace, curatorName, err)
+	}
+	_, err = WaitForCondition(ctx, hubClientDynamic, ClusterCuratorGVR, namespace, curatorName,
+		CuratorCondition("clustercurator-job", "", "DesiredCuration: "+curation, CuratorJobFailed))
+	if err != nil {
+		return fmt.Errorf("ERROR the %s curation of ClusterCurator %s/%s was not reported failed: %v", curation, namespace, curatorName, err)
+	}
+	fmt.Printf("ClusterCurator %s: %s %s failed as expected\n", curatorName, curation, hook)
+	return nil
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of testdata")
+
+func TestNewClusterCuratorGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		spec   ClusterCuratorSpec
+	}{
+		{
+			name:   "default hooks",
+			golden: "default.yaml",
+			spec:   DefaultClusterCuratorSpec(CurationInstall, "hc-AWS", "aap-tower-cred"),
+		},
+		{
+			name:   "channel upgrade",
+			golden: "channel_upgrade.yaml",
+			spec:   ClusterCuratorSpec{Upgrade: &CuratorStage{MonitorTimeout: 120}},
+		},
+		{
+			name:   "custom stages",
+			golden: "custom.yaml",
+			spec: ClusterCuratorSpec{
+				DesiredCuration: CurationUpgrade,
+				Install: &CuratorStage{
+					TowerAuthSecret:   "tower-stub",
+					Prehook:           []CuratorHook{{Name: "validate-network", ExtraVars: map[string]interface{}{"retries": 3, "strict": true}}},
+					JobMonitorTimeout: 10,
+				},
+				Upgrade: &CuratorStage{
+					TowerAuthSecret: "tower-stub",
+					Posthook:        []CuratorHook{{Name: "smoke-test", Type: "Workflow"}},
+					MonitorTimeout:  60,
+					Channel:         "fast-4.16",
+					DesiredUpdate:   "4.16.1",
+					UpgradeType:     "ControlPlane",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curator, err := NewClusterCurator("acmqe-hc-1", "clusters", tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := yaml.Marshal(curator.Object)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "clustercurator", tt.golden)
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("ClusterCurator differs from %s (go test -run TestNewClusterCuratorGolden -update):\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package utils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newCuratorWithHookJob(g *gomega.WithT, hub *fakehub.Hub, hookCondition map[string]interface{}, job map[string]interface{}) {
+	ctx := context.TODO()
+	curator := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cluster.open-cluster-management.io/v1beta1",
+		"kind":       "ClusterCurator",
+		"metadata":   map[string]interface{}{"name": "acmqe-hc-1", "namespace": "clusters"},
+		"status": map[string]interface{}{"conditions": []interface{}{
+			hookCondition,
+			map[string]interface{}{"type": "current-ansiblejob", "status": "False", "reason": "Job_has_finished", "message": "prehookjob-abcde"},
+		}},
+	}}
+	_, err := hub.Dynamic.Resource(utils.ClusterCuratorGVR).Namespace("clusters").Create(ctx, curator, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	ansibleJob := &unstructured.Unstructured{Object: job}
+	ansibleJob.SetAPIVersion("tower.ansible.com/v1alpha1")
+	ansibleJob.SetKind("AnsibleJob")
+	ansibleJob.SetName("prehookjob-abcde")
+	ansibleJob.SetNamespace("clusters")
+	_, err = hub.Dynamic.Resource(utils.AnsibleJobGVR).Namespace("clusters").Create(ctx, ansibleJob, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+}
+
+func TestWaitForCuratorHook(t *testing.T) {
+	completed := map[string]interface{}{"type": "prehook-ansiblejob", "status": "True", "reason": "Job_has_finished", "message": "Completed executing init container"}
+	running := map[string]interface{}{"type": "prehook-ansiblejob", "status": "False", "reason": "Job_has_started", "message": "Executing init container"}
+	spec := map[string]interface{}{
+		"job_template_name": "Auto_CLC_Sample_Template",
+		"extra_vars":        map[string]interface{}{"hook": "pre", "stage": "install"},
+	}
+	jobStatus := func(isFinished bool, status string) map[string]interface{} {
+		return map[string]interface{}{
+			"isFinished":       isFinished,
+			"ansibleJobResult": map[string]interface{}{"status": status, "url": "https://tower.example.com/#/jobs/42"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		condition map[string]interface{}
+		job       map[string]interface{}
+		stage     string
+		hook      string
+		wantErr   string
+		want      string
+	}{
+		{
+			name:      "successful prehook",
+			condition: completed,
+			job:       map[string]interface{}{"spec": spec, "status": jobStatus(true, "successful")},
+			stage:     "install",
+			hook:      utils.CuratorPrehook,
+			want:      "successful",
+		},
+		{
+			name:      "failed job",
+			condition: completed,
+			job:       map[string]interface{}{"spec": spec, "status": jobStatus(true, "failed")},
+			stage:     "install",
+			hook:      utils.CuratorPrehook,
+			wantErr:   `ERROR the install prehook of ClusterCurator clusters/acmqe-hc-1 failed: AnsibleJob prehookjob-abcde (template "Auto_CLC_Sample_Template", stage "install", hook "pre"): status "failed"`,
+			want:      "failed",
+		},
+		{
+			name:      "job without status",
+			condition: completed,
+			job:       map[string]interface{}{"spec": spec},
+			stage:     "install",
+			hook:      utils.CuratorPrehook,
+			wantErr:   "AnsibleJob prehookjob-abcde has not finished",
+		},
+		{
+			name:      "condition disagrees with the job",
+			condition: running,
+			job:       map[string]interface{}{"spec": spec, "status": jobStatus(true, "successful")},
+			stage:     "install",
+			hook:      utils.CuratorPrehook,
+			wantErr:   "prehook-ansiblejob",
+			want:      "successful",
+		},
+		{
+			name:      "job of another stage",
+			condition: completed,
+			job:       map[string]interface{}{"spec": spec, "status": jobStatus(true, "successful")},
+			stage:     "upgrade",
+			hook:      utils.CuratorPrehook,
+			wantErr:   "the current AnsibleJob prehookjob-abcde does not run the upgrade prehook",
+		},
+		{
+			name:      "job without extra_vars is matched by name",
+			condition: completed,
+			job:       map[string]interface{}{"spec": map[string]interface{}{"workflow_template_name": "Demo Workflow Template"}, "status": jobStatus(true, "successful")},
+			stage:     "install",
+			hook:      utils.CuratorPrehook,
+			want:      "successful",
+		},
+		{
+			name:    "unknown hook",
+			hook:    "midhook",
+			wantErr: `unknown cluster curator hook "midhook"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			hub := fakehub.New(fakehub.Options{})
+			if tt.job != nil {
+				newCuratorWithHookJob(g, hub, tt.condition, tt.job)
+			}
+			ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+			defer cancel()
+			result, err := utils.WaitForCuratorHook(ctx, hub.Dynamic, "acmqe-hc-1", "clusters", tt.stage, tt.hook)
+			if tt.wantErr != "" {
+				g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(tt.wantErr)))
+			} else {
+				g.Expect(err).NotTo(gomega.HaveOccurred())
+			}
+			g.Expect(result.Status).To(gomega.Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// HostedClusterReportEntry is the name of the report entry that ties a spec to the
+	// HostedCluster it works on. The value is "<namespace>/<name>".
+	HostedClusterReportEntry = "hosted-cluster"
+	ArtifactsDirEnv          = "ARTIFACTS_DIR"
+	defaultArtifactsDir      = "results"
+
+	HypershiftAddonAgentNamespace = "open-cluster-management-agent-addon"
+	HypershiftAddonAgentLabel     = "app=hypershift-addon-agent"
+	HypershiftOperatorLabel       = "app=operator"
+)
+
+var artifactsDirNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// HostedClusterRef identifies a HostedCluster by namespace and name.
+type HostedClusterRef struct {
+	Namespace string
+	Name      string
+}
+
+// ReportHostedCluster records on the current spec the HostedCluster it works on, so the failure
+// artifacts collected by CollectFailureArtifacts cover it. Call it from a BeforeEach or an It.
+func ReportHostedCluster(namespace, name string) {
+	ginkgo.AddReportEntry(HostedClusterReportEntry, namespace+"/"+name, ginkgo.ReportEntryVisibilityNever)
+}
+
+// HostedClustersFromReport returns the HostedClusters recorded on the spec with ReportHostedCluster.
+func HostedClustersFromReport(spec types.SpecReport) []HostedClusterRef {
+	refs := []HostedClusterRef{}
+	seen := map[string]bool{}
+	for _, entry := range spec.ReportEntries {
+		value := entry.StringRepresentation()
+		if entry.Name != HostedClusterReportEntry || seen[value] {
+			continue
+		}
+		seen[value] = true
+		namespace, name, found := strings.Cut(value, "/")
+		if !found || name == "" {
+			continue
+		}
+		refs = append(refs, HostedClusterRef{Namespace: namespace, Name: name})
+	}
+	return refs
+}
+
+// GetArtifactsDir returns the directory failure artifacts are written to: ARTIFACTS_DIR if set,
+// otherwise the directory of JUNIT_REPORT_FILE, otherwise results.
+func GetArtifactsDir() string {
+	if dir := os.Getenv(ArtifactsDirEnv); dir != "" {
+		return dir
+	}
+	if junitReportFile := os.Getenv("JUNIT_REPORT_FILE"); junitReportFile != "" {
+		return filepath.Dir(junitReportFile)
+	}
+	return defaultArtifactsDir
+}
+
+// SpecArtifactsDir returns the directory holding the failure artifacts of the spec.
+func SpecArtifactsDir(spec types.SpecReport) string {
+	name := strings.Trim(artifactsDirNameRegexp.ReplaceAllString(spec.FullText(), "-"), "-")
+	if len(name) > 120 {
+		name = name[:120]
+	}
+	if name == "" {
+		name = "spec"
+	}
+	return filepath.Join(GetArtifactsDir(), name)
+}
+
+// CollectFailureArtifacts dumps the state needed to debug a failed spec into SpecArtifactsDir:
+// for every given HostedCluster its YAML, NodePools, ManagedCluster, ManagedClusterAddOns and
+// namespace events, the pods and events of its control plane namespace, and the hypershift
+// operator and hypershift-addon-agent logs written while the spec ran. Collection continues
+// past individual failures; they are returned together once everything possible was written.
+func CollectFailureArtifacts(ctx context.Context, hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface,
+	spec types.SpecReport, clusters []HostedClusterRef) (string, error) {
+	dir := SpecArtifactsDir(spec)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return dir, fmt.Errorf("ERROR failed to create the artifacts directory %s: %v", dir, err)
+	}
+	fmt.Printf("Collecting failure artifacts for %q into %s\n", spec.FullText(), dir)
+
+	errs := []error{}
+	for _, cluster := range clusters {
+		errs = append(errs, collectHostedClusterArtifacts(ctx, hubClientDynamic, kubeClient, filepath.Join(dir, cluster.Name), cluster)...)
+	}
+
+	endTime := spec.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	errs = append(errs,
+		collectPodLogs(ctx, kubeClient, filepath.Join(dir, "logs", "hypershift-operator"),
+			HypershiftOperatorNamespace, HypershiftOperatorLabel, spec.StartTime, endTime, ""),
+		collectPodLogs(ctx, kubeClient, filepath.Join(dir, "logs", "hypershift-addon-agent"),
+			HypershiftAddonAgentNamespace, HypershiftAddonAgentLabel, spec.StartTime, endTime, ""),
+	)
+	return dir, utilerrors.NewAggregate(errs)
+}
+
+func collectHostedClusterArtifacts(ctx context.Context, hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface,
+	dir string, cluster HostedClusterRef) []error {
+	errs := []error{}
+	collect := func(file string, get func() (interface{}, error)) {
+		obj, err := get()
+		if errors.IsNotFound(err) {
+			return
+		}
+		if err == nil {
+			err = writeYAML(filepath.Join(dir, file), obj)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ERROR failed to collect %s for %s/%s: %v", file, cluster.Namespace, cluster.Name, err))
+		}
+	}
+	controlPlaneNamespace := cluster.Namespace + "-" + cluster.Name
+
+	collect("hostedcluster.yaml", func() (interface{}, error) {
+		return hubClientDynamic.Resource(HostedClustersGVR).Namespace(cluster.Namespace).Get(ctx, cluster.Name, metav1.GetOptions{})
+	})
+	collect("nodepools.yaml", func() (interface{}, error) {
+		nodePools, err := ListNodePoolsForHostedCluster(hubClientDynamic, cluster.Namespace, cluster.Name)
+		return unstructuredItems(nodePools), err
+	})
+	collect("managedcluster.yaml", func() (interface{}, error) {
+		return hubClientDynamic.Resource(ManagedClustersGVR).Get(ctx, cluster.Name, metav1.GetOptions{})
+	})
+	collect("managedclusteraddons.yaml", func() (interface{}, error) {
+		return hubClientDynamic.Resource(ManagedClusterAddonGVR).Namespace(cluster.Name).List(ctx, metav1.ListOptions{})
+	})
+	collect("events.yaml", func() (interface{}, error) {
+		return kubeClient.CoreV1().Events(cluster.Namespace).List(ctx, metav1.ListOptions{})
+	})
+	collect(filepath.Join("control-plane", "pods.yaml"), func() (interface{}, error) {
+		return kubeClient.CoreV1().Pods(controlPlaneNamespace).List(ctx, metav1.ListOptions{})
+	})
+	collect(filepath.Join("control-plane", "events.yaml"), func() (interface{}, error) {
+		return kubeClient.CoreV1().Events(controlPlaneNamespace).List(ctx, metav1.ListOptions{})
+	})
+	return errs
+}
+
+// collectPodLogs writes the logs of every container of the matching pods, limited to the
+// lines logged between since and until, and to the lines containing mention when it is set.
+func collectPodLogs(ctx context.Context, kubeClient kubernetes.Interface, dir, namespace, labelSelector string, since, until time.Time, mention string) error {
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return fmt.Errorf("ERROR failed to list pods %s in %s: %v", labelSelector, namespace, err)
+	}
+
+	errs := []error{}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			opts := &corev1.PodLogOptions{Container: container.Name, Timestamps: true}
+			if !since.IsZero() {
+				opts.SinceTime = &metav1.Time{Time: since}
+			}
+			logs, err := kubeClient.CoreV1().Pods(namespace).GetLogs(pod.Name, opts).DoRaw(ctx)
+			if err == nil {
+				file := filepath.Join(dir, fmt.Sprintf("%s_%s.log", pod.Name, container.Name))
+				err = writeFile(file, filterLogLines(string(logs), until, mention))
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("ERROR failed to collect the logs of %s/%s container %s: %v", namespace, pod.Name, container.Name, err))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// filterLogLines drops the timestamped lines logged after until and, when mention is set, the
+// lines not containing it. Lines without a timestamp are kept.
+func filterLogLines(logs string, until time.Time, mention string) []byte {
+	var b strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if mention != "" && !strings.Contains(line, mention) {
+			continue
+		}
+		timestamp, _, _ := strings.Cut(line, " ")
+		if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil && t.After(until) {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+func unstructuredItems(objs []*unstructured.Unstructured) []interface{} {
+	items := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, obj.Object)
+	}
+	return items
+}
+
+func writeYAML(file string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return writeFile(file, data)
+}
+
+func writeFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o644)
+}
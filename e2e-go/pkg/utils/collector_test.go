@@ -0,0 +1,101 @@
+package utils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCollectFailureArtifacts(t *testing.T) {
+	g := gomega.NewWithT(t)
+	artifactsDir := t.TempDir()
+	t.Setenv(utils.ArtifactsDirEnv, artifactsDir)
+
+	hub := fakehub.New(fakehub.Options{StepDelay: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		hub.Wait()
+	})
+	g.Expect(hub.Start(ctx)).To(gomega.Succeed())
+
+	hc := fakehub.NewHostedCluster("clusters", "acmqe-hc-1", utils.TYPE_AWS, "quay.io/openshift-release-dev/ocp-release:4.15.5-multi", "")
+	_, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(ctx, hc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	np := fakehub.NewNodePool("clusters", "acmqe-hc-1", "acmqe-hc-1", utils.TYPE_AWS, "quay.io/openshift-release-dev/ocp-release:4.15.5-multi", 2, "")
+	_, err = hub.Dynamic.Resource(utils.NodePoolsGVR).Namespace("clusters").Create(ctx, np, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Eventually(func() error {
+		return utils.CheckClusterImported(hub.Dynamic, "acmqe-hc-1")
+	}, 10*time.Second, 20*time.Millisecond).Should(gomega.BeNil())
+
+	operatorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-abc", Namespace: utils.HypershiftOperatorNamespace, Labels: map[string]string{"app": "operator"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "operator"}}},
+	}
+	_, err = hub.Kube.CoreV1().Pods(utils.HypershiftOperatorNamespace).Create(ctx, operatorPod, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	spec := types.SpecReport{
+		ContainerHierarchyTexts: []string{"Hosted Control Plane CLI AWS Create Tests:"},
+		LeafNodeText:            "Creates a FIPS AWS Hosted Cluster using STS Creds",
+		State:                   types.SpecStateFailed,
+		StartTime:               time.Now().Add(-time.Minute),
+		EndTime:                 time.Now(),
+		ReportEntries: types.ReportEntries{
+			{Name: utils.HostedClusterReportEntry, Value: types.WrapEntryValue("clusters/acmqe-hc-1")},
+			{Name: utils.HostedClusterReportEntry, Value: types.WrapEntryValue("clusters/acmqe-hc-1")},
+		},
+	}
+	clusters := utils.HostedClustersFromReport(spec)
+	g.Expect(clusters).To(gomega.Equal([]utils.HostedClusterRef{{Namespace: "clusters", Name: "acmqe-hc-1"}}))
+
+	dir, err := utils.CollectFailureArtifacts(ctx, hub.Dynamic, hub.Kube, spec, clusters)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(dir).To(gomega.Equal(filepath.Join(artifactsDir, "Hosted-Control-Plane-CLI-AWS-Create-Tests-Creates-a-FIPS-AWS-Hosted-Cluster-using-STS-Creds")))
+
+	for _, file := range []string{
+		"acmqe-hc-1/hostedcluster.yaml",
+		"acmqe-hc-1/nodepools.yaml",
+		"acmqe-hc-1/managedcluster.yaml",
+		"acmqe-hc-1/managedclusteraddons.yaml",
+		"acmqe-hc-1/events.yaml",
+		"acmqe-hc-1/control-plane/pods.yaml",
+		"acmqe-hc-1/control-plane/events.yaml",
+		"logs/hypershift-operator/operator-abc_operator.log",
+	} {
+		g.Expect(filepath.Join(dir, file)).To(gomega.BeAnExistingFile())
+	}
+	hostedCluster, err := os.ReadFile(filepath.Join(dir, "acmqe-hc-1", "hostedcluster.yaml"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(hostedCluster)).To(gomega.ContainSubstring("The hosted control plane is available"))
+
+	junitFile := filepath.Join(t.TempDir(), "result.xml")
+	g.Expect(utils.GenerateJUnitReport(types.Report{SpecReports: types.SpecReports{spec}}, junitFile)).To(gomega.Succeed())
+	junit, err := os.ReadFile(junitFile)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(junit)).To(gomega.ContainSubstring("Failure artifacts: " + dir))
+}
+
+func TestSpecArtifactsDirDefaultsToJUnitReportDir(t *testing.T) {
+	t.Setenv(utils.ArtifactsDirEnv, "")
+	t.Setenv("JUNIT_REPORT_FILE", "./results/result.xml")
+	spec := types.SpecReport{LeafNodeText: "Destroy all hosted clusters: AWS / KubeVirt"}
+	want := filepath.Join("results", "Destroy-all-hosted-clusters-AWS-KubeVirt")
+	if got := utils.SpecArtifactsDir(spec); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if got := utils.SpecArtifactsDir(types.SpecReport{LeafNodeText: strings.Repeat("a", 200)}); len(filepath.Base(got)) != 120 {
+		t.Errorf("expected the directory name to be capped at 120 characters, got %d", len(filepath.Base(got)))
+	}
+}
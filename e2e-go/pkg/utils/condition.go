@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// ConditionMatcher selects a status condition by type and describes the state to wait for.
+// Empty Status, Reason and MessageContains match anything.
+type ConditionMatcher struct {
+	Type            string
+	Status          string
+	Reason          string
+	MessageContains string
+}
+
+var (
+	// HCPAvailableCondition matches a HostedCluster whose hosted control plane is available.
+	HCPAvailableCondition = ConditionMatcher{
+		Type:            "Available",
+		Status:          string(metav1.ConditionTrue),
+		Reason:          "AsExpected",
+		MessageContains: "The hosted control plane is available",
+	}
+	// ClusterImportedCondition matches an imported ManagedCluster.
+	ClusterImportedCondition = ConditionMatcher{
+		Type:   "ManagedClusterConditionAvailable",
+		Status: string(metav1.ConditionTrue),
+	}
+	// AddOnAvailableCondition matches an available ManagedClusterAddOn.
+	AddOnAvailableCondition = ConditionMatcher{
+		Type:   "Available",
+		Status: string(metav1.ConditionTrue),
+	}
+)
+
+// CuratorCondition matches a ClusterCurator condition the way CheckCuratorCondition does.
+func CuratorCondition(conType, expectedStatus, expectedMsg, expectedReason string) ConditionMatcher {
+	return ConditionMatcher{Type: conType, Status: expectedStatus, Reason: expectedReason, MessageContains: expectedMsg}
+}
+
+// Matches reports whether the condition is in the expected state. A nil condition never matches.
+func (m ConditionMatcher) Matches(condition map[string]interface{}) bool {
+	if condition == nil {
+		return false
+	}
+	status, _ := condition["status"].(string)
+	reason, _ := condition["reason"].(string)
+	message, _ := condition["message"].(string)
+	return (m.Status == "" || status == m.Status) &&
+		(m.Reason == "" || reason == m.Reason) &&
+		(m.MessageContains == "" || strings.Contains(message, m.MessageContains))
+}
+
+func (m ConditionMatcher) String() string {
+	expected := []string{"type=" + m.Type}
+	if m.Status != "" {
+		expected = append(expected, "status="+m.Status)
+	}
+	if m.Reason != "" {
+		expected = append(expected, "reason="+m.Reason)
+	}
+	if m.MessageContains != "" {
+		expected = append(expected, fmt.Sprintf("message contains %q", m.MessageContains))
+	}
+	return strings.Join(expected, " ")
+}
+
+// ConditionTransition is one observed state of the watched condition.
+type ConditionTransition struct {
+	Time    time.Time
+	Present bool
+	Status  string
+	Reason  string
+	Message string
+}
+
+func (t ConditionTransition) String() string {
+	if !t.Present {
+		return fmt.Sprintf("%s not present", t.Time.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s status=%s reason=%s message=%q", t.Time.Format(time.RFC3339), t.Status, t.Reason, t.Message)
+}
+
+func newConditionTransition(condition map[string]interface{}) ConditionTransition {
+	transition := ConditionTransition{Time: time.Now(), Present: condition != nil}
+	if condition != nil {
+		transition.Status, _ = condition["status"].(string)
+		transition.Reason, _ = condition["reason"].(string)
+		transition.Message, _ = condition["message"].(string)
+	}
+	return transition
+}
+
+func (t ConditionTransition) sameState(other ConditionTransition) bool {
+	return t.Present == other.Present && t.Status == other.Status && t.Reason == other.Reason && t.Message == other.Message
+}
+
+// ConditionWaitError is returned by WaitForCondition when the condition did not match in time.
+// It carries every transition observed while waiting and the classification of the failure.
+type ConditionWaitError struct {
+	Resource       string
+	Namespace      string
+	Name           string
+	Matcher        ConditionMatcher
+	Found          bool
+	Transitions    []ConditionTransition
+	Err            error
+	Classification FailureClassification
+}
+
+func (e *ConditionWaitError) Error() string {
+	msg := e.message()
+	if e.Classification.Tag == "" {
+		return msg
+	}
+	reason := ""
+	if len(e.Transitions) > 0 {
+		reason = e.Transitions[len(e.Transitions)-1].Reason
+	}
+	return generateErrorMsg(e.Classification.Tag, e.Classification.PossibleSolution(), reason, msg).Error()
+}
+
+func (e *ConditionWaitError) message() string {
+	object := e.Name
+	if e.Namespace != "" {
+		object = e.Namespace + "/" + e.Name
+	}
+	msg := fmt.Sprintf("%s %s: condition %s was not reached: %v", e.Resource, object, e.Matcher, e.Err)
+	switch {
+	case !e.Found:
+		return msg + "; the object was never observed"
+	case len(e.Transitions) == 0:
+		return msg + "; no condition was observed"
+	}
+	last := e.Transitions[len(e.Transitions)-1]
+	msg += "; last observed " + e.Matcher.Type + ": " + last.String()
+	if len(e.Transitions) > 1 {
+		transitions := make([]string, 0, len(e.Transitions))
+		for _, t := range e.Transitions {
+			transitions = append(transitions, t.String())
+		}
+		msg += "; transitions:\n  " + strings.Join(transitions, "\n  ")
+	}
+	return msg
+}
+
+func (e *ConditionWaitError) Unwrap() error {
+	return e.Err
+}
+
+// CheckCondition returns nil when the object's condition matches and a descriptive error otherwise.
+func CheckCondition(obj *unstructured.Unstructured, matcher ConditionMatcher) error {
+	condition := findCondition(obj, matcher.Type)
+	if matcher.Matches(condition) {
+		return nil
+	}
+	reason, msg := "", fmt.Sprintf("%s %s: condition %s not found", obj.GetKind(), obj.GetName(), matcher.Type)
+	if condition != nil {
+		reason, _ = condition["reason"].(string)
+		msg = fmt.Sprintf("%s %s: expected condition %s but got %s", obj.GetKind(), obj.GetName(), matcher, newConditionTransition(condition))
+	}
+	classification := ClassifyConditionFailure(reason, msg)
+	return generateErrorMsg(classification.Tag, classification.PossibleSolution(), reason, msg)
+}
+
+func findCondition(obj *unstructured.Unstructured, conType string) map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		if condition, ok := c.(map[string]interface{}); ok && condition["type"] == conType {
+			return condition
+		}
+	}
+	return nil
+}
+
+// WaitForCondition watches the named object until its condition matches or ctx is done. Every
+// change of the condition is printed and returned. On timeout the returned *ConditionWaitError
+// describes the last observed condition. namespace is empty for cluster scoped resources.
+func WaitForCondition(ctx context.Context, hubClientDynamic dynamic.Interface, gvr schema.GroupVersionResource,
+	namespace, name string, matcher ConditionMatcher) ([]ConditionTransition, error) {
+	object := name
+	if namespace != "" {
+		object = namespace + "/" + name
+	}
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+			return hubClientDynamic.Resource(gvr).Namespace(namespace).List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+			return hubClientDynamic.Resource(gvr).Namespace(namespace).Watch(ctx, options)
+		},
+	}
+
+	found := false
+	transitions := []ConditionTransition{}
+	record := func(transition ConditionTransition) {
+		if len(transitions) > 0 && transitions[len(transitions)-1].sameState(transition) {
+			return
+		}
+		transitions = append(transitions, transition)
+		fmt.Printf("%s %s: %s %s\n", gvr.Resource, object, matcher.Type, transition)
+	}
+
+	_, err := watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, nil, func(event watch.Event) (bool, error) {
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok || obj.GetName() != name || obj.GetNamespace() != namespace {
+			return false, nil
+		}
+		found = true
+		if event.Type == watch.Deleted {
+			record(ConditionTransition{Time: time.Now()})
+			return false, nil
+		}
+		condition := findCondition(obj, matcher.Type)
+		if condition != nil || len(transitions) > 0 {
+			record(newConditionTransition(condition))
+		}
+		return matcher.Matches(condition), nil
+	})
+	if err != nil {
+		waitErr := &ConditionWaitError{
+			Resource:    gvr.Resource,
+			Namespace:   namespace,
+			Name:        name,
+			Matcher:     matcher,
+			Found:       found,
+			Transitions: transitions,
+			Err:         err,
+		}
+		reason := ""
+		if len(transitions) > 0 {
+			reason = transitions[len(transitions)-1].Reason
+		}
+		waitErr.Classification = ClassifyConditionFailure(reason, waitErr.message())
+		return transitions, waitErr
+	}
+	return transitions, nil
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newConditionTestClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		HostedClustersGVR: "HostedClusterList",
+	}, objects...)
+}
+
+func newConditionTestHostedCluster(name string, conditions ...interface{}) *unstructured.Unstructured {
+	hc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "hypershift.openshift.io/v1beta1",
+		"kind":       "HostedCluster",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "clusters"},
+	}}
+	if len(conditions) > 0 {
+		_ = unstructured.SetNestedSlice(hc.Object, conditions, "status", "conditions")
+	}
+	return hc
+}
+
+func availableCondition(status, reason, message string) interface{} {
+	return map[string]interface{}{"type": "Available", "status": status, "reason": reason, "message": message}
+}
+
+func TestConditionMatcherMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		matcher   ConditionMatcher
+		condition map[string]interface{}
+		want      bool
+	}{
+		{
+			name:      "hosted control plane available",
+			matcher:   HCPAvailableCondition,
+			condition: availableCondition("True", "AsExpected", "The hosted control plane is available").(map[string]interface{}),
+			want:      true,
+		},
+		{
+			name:      "wrong status",
+			matcher:   HCPAvailableCondition,
+			condition: availableCondition("False", "AsExpected", "The hosted control plane is available").(map[string]interface{}),
+		},
+		{
+			name:      "wrong reason",
+			matcher:   HCPAvailableCondition,
+			condition: availableCondition("True", "WaitingForAvailable", "The hosted control plane is available").(map[string]interface{}),
+		},
+		{
+			name:      "message substring",
+			matcher:   CuratorCondition("hypershift-provisioning-job", "True", "-provision", "Job_has_finished"),
+			condition: map[string]interface{}{"type": "hypershift-provisioning-job", "status": "True", "reason": "Job_has_finished", "message": "hypershift-job-abcde-provision"},
+			want:      true,
+		},
+		{
+			name:      "empty fields match anything",
+			matcher:   ConditionMatcher{Type: "Available"},
+			condition: availableCondition("Unknown", "", "").(map[string]interface{}),
+			want:      true,
+		},
+		{
+			name:    "missing condition",
+			matcher: ConditionMatcher{Type: "Available"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.Matches(tt.condition); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckCondition(t *testing.T) {
+	hc := newConditionTestHostedCluster("acmqe-hc-1", availableCondition("False", "WaitingForAvailable", "Waiting for the kube-apiserver"))
+	err := CheckCondition(hc, HCPAvailableCondition)
+	if err == nil || !strings.Contains(err.Error(), "reason=WaitingForAvailable") {
+		t.Errorf("expected the observed reason in the error, got %v", err)
+	}
+	if err := CheckCondition(newConditionTestHostedCluster("acmqe-hc-1"), HCPAvailableCondition); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a missing condition error, got %v", err)
+	}
+}
+
+func TestWaitForConditionRecordsTransitions(t *testing.T) {
+	client := newConditionTestClient(newConditionTestHostedCluster("acmqe-hc-1"))
+	updates := []interface{}{
+		availableCondition("False", "WaitingForAvailable", "Waiting for the kube-apiserver"),
+		availableCondition("False", "WaitingForAvailable", "Waiting for the kube-apiserver"),
+		availableCondition("True", "AsExpected", "The hosted control plane is available"),
+	}
+	go func() {
+		for _, condition := range updates {
+			time.Sleep(20 * time.Millisecond)
+			hc := newConditionTestHostedCluster("acmqe-hc-1", condition)
+			if _, err := client.Resource(HostedClustersGVR).Namespace("clusters").Update(context.Background(), hc, metav1.UpdateOptions{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	transitions, err := WaitForCondition(ctx, client, HostedClustersGVR, "clusters", "acmqe-hc-1", HCPAvailableCondition)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transitions) != 2 {
+		t.Fatalf("expected 2 transitions, got %v", transitions)
+	}
+	if transitions[0].Reason != "WaitingForAvailable" || transitions[1].Reason != "AsExpected" {
+		t.Errorf("unexpected transitions: %v", transitions)
+	}
+}
+
+func TestWaitForConditionTimeout(t *testing.T) {
+	client := newConditionTestClient(newConditionTestHostedCluster("acmqe-hc-1",
+		availableCondition("False", "WaitingForAvailable", "Waiting for the kube-apiserver")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	transitions, err := WaitForCondition(ctx, client, HostedClustersGVR, "clusters", "acmqe-hc-1", HCPAvailableCondition)
+	var waitErr *ConditionWaitError
+	if !errors.As(err, &waitErr) {
+		t.Fatalf("expected a ConditionWaitError, got %v", err)
+	}
+	if len(transitions) != 1 || !waitErr.Found {
+		t.Errorf("expected one transition on an observed object, got %v", transitions)
+	}
+	for _, want := range []string{"clusters/acmqe-hc-1", "status=False", "reason=WaitingForAvailable", "Waiting for the kube-apiserver"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got %v", want, err)
+		}
+	}
+}
+
+func TestWaitForConditionObjectNotFound(t *testing.T) {
+	client := newConditionTestClient(newConditionTestHostedCluster("acmqe-hc-2"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := WaitForCondition(ctx, client, HostedClustersGVR, "clusters", "acmqe-hc-1", HCPAvailableCondition)
+	if err == nil || !strings.Contains(err.Error(), "never observed") {
+		t.Errorf("expected a never observed error, got %v", err)
+	}
+}
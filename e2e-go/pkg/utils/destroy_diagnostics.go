@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DestroyDiagnosticsDirName is the directory of GetArtifactsDir holding the diagnostics of the
+	// hosted clusters that failed to destroy, one directory per cluster.
+	DestroyDiagnosticsDirName = "destroy-diagnostics"
+
+	destroyDiagnosticsTimeout = 2 * time.Minute
+)
+
+// finalizerControllers maps the finalizers, or their prefix when ending with a slash, found on
+// the resources of a hosted cluster to the controller that removes them.
+var finalizerControllers = []struct {
+	finalizer  string
+	controller string
+}{
+	{"hypershift.io/aws-oidc-discovery", "hypershift operator (AWS OIDC documents in the S3 bucket)"},
+	{"hypershift.openshift.io/finalizer", "hypershift operator"},
+	{"hypershift.openshift.io/", "hypershift operator"},
+	{"hypershift.io/", "hypershift operator"},
+	{"cluster.x-k8s.io/", "cluster-api controllers in the control plane namespace"},
+	{"hypershift.open-cluster-management.io/", "hypershift addon"},
+	{"managedcluster-import-controller.open-cluster-management.io/", "managedcluster-import-controller"},
+	{"cluster.open-cluster-management.io/", "registration"},
+	{"addon.open-cluster-management.io/", "addon manager"},
+	{"open-cluster-management.io/", "cluster manager"},
+	{"foregroundDeletion", "kube garbage collector"},
+	{"kubernetes", "kube namespace controller"},
+}
+
+// FinalizerController returns the controller that removes the finalizer, or "unknown controller".
+func FinalizerController(finalizer string) string {
+	for _, fc := range finalizerControllers {
+		if finalizer == fc.finalizer || (strings.HasSuffix(fc.finalizer, "/") && strings.HasPrefix(finalizer, fc.finalizer)) {
+			return fc.controller
+		}
+	}
+	return "unknown controller"
+}
+
+// PendingFinalizer is a finalizer holding back the deletion of a resource of a hosted cluster.
+type PendingFinalizer struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	Finalizer  string
+	Controller string
+	// Deleting is set when the resource has a deletion timestamp
+	Deleting bool
+}
+
+func (f PendingFinalizer) String() string {
+	name := f.Name
+	if f.Namespace != "" {
+		name = f.Namespace + "/" + f.Name
+	}
+	state := "not deleted"
+	if f.Deleting {
+		state = "deleting"
+	}
+	return fmt.Sprintf("%s %s (%s): %s, removed by the %s", f.Kind, name, state, f.Finalizer, f.Controller)
+}
+
+// DestroyDiagnosis is what DiagnoseDestroyFailure found about a hosted cluster that failed to destroy.
+type DestroyDiagnosis struct {
+	Dir        string
+	Finalizers []PendingFinalizer
+}
+
+func (d DestroyDiagnosis) String() string {
+	lines := []string{}
+	for _, f := range d.Finalizers {
+		lines = append(lines, "  - "+f.String())
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "  no finalizers left")
+	}
+	return fmt.Sprintf("pending finalizers:\n%s\ndiagnostics: %s", strings.Join(lines, "\n"), d.Dir)
+}
+
+func pendingFinalizers(kind string, obj *unstructured.Unstructured) []PendingFinalizer {
+	finalizers := []PendingFinalizer{}
+	for _, finalizer := range obj.GetFinalizers() {
+		finalizers = append(finalizers, PendingFinalizer{
+			Kind:       kind,
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+			Finalizer:  finalizer,
+			Controller: FinalizerController(finalizer),
+			Deleting:   obj.GetDeletionTimestamp() != nil,
+		})
+	}
+	return finalizers
+}
+
+// CollectDestroyDiagnostics writes to <artifacts dir>/destroy-diagnostics/<name>/ the YAML of the
+// HostedCluster, its NodePools and its ManagedCluster that are left, their pending finalizers
+// with the controller owning each in finalizers.txt, and the hypershift operator log lines
+// logged since that mention the cluster. Collection continues past individual failures.
+func CollectDestroyDiagnostics(ctx context.Context, hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface,
+	namespace, name string, since time.Time) (DestroyDiagnosis, error) {
+	diagnosis := DestroyDiagnosis{Dir: filepath.Join(GetArtifactsDir(), DestroyDiagnosticsDirName, name), Finalizers: []PendingFinalizer{}}
+	errs := []error{}
+
+	hc, err := hubClientDynamic.Resource(HostedClustersGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		diagnosis.Finalizers = append(diagnosis.Finalizers, pendingFinalizers("HostedCluster", hc)...)
+		err = writeYAML(filepath.Join(diagnosis.Dir, "hostedcluster.yaml"), hc.Object)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		errs = append(errs, fmt.Errorf("ERROR failed to collect the HostedCluster %s/%s: %v", namespace, name, err))
+	}
+
+	nodePools, err := ListNodePoolsForHostedCluster(hubClientDynamic, namespace, name)
+	if err == nil && len(nodePools) > 0 {
+		for _, np := range nodePools {
+			diagnosis.Finalizers = append(diagnosis.Finalizers, pendingFinalizers("NodePool", np)...)
+		}
+		err = writeYAML(filepath.Join(diagnosis.Dir, "nodepools.yaml"), unstructuredItems(nodePools))
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("ERROR failed to collect the NodePools of %s/%s: %v", namespace, name, err))
+	}
+
+	mc, err := hubClientDynamic.Resource(ManagedClustersGVR).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		diagnosis.Finalizers = append(diagnosis.Finalizers, pendingFinalizers("ManagedCluster", mc)...)
+		err = writeYAML(filepath.Join(diagnosis.Dir, "managedcluster.yaml"), mc.Object)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		errs = append(errs, fmt.Errorf("ERROR failed to collect the ManagedCluster %s: %v", name, err))
+	}
+
+	lines := []string{}
+	for _, f := range diagnosis.Finalizers {
+		lines = append(lines, f.String()+"\n")
+	}
+	if err := writeFile(filepath.Join(diagnosis.Dir, "finalizers.txt"), []byte(strings.Join(lines, ""))); err != nil {
+		errs = append(errs, fmt.Errorf("ERROR failed to write the finalizers of %s/%s: %v", namespace, name, err))
+	}
+
+	errs = append(errs, collectPodLogs(ctx, kubeClient, filepath.Join(diagnosis.Dir, "logs", "hypershift-operator"),
+		HypershiftOperatorNamespace, HypershiftOperatorLabel, since, time.Now(), name))
+	return diagnosis, utilerrors.NewAggregate(errs)
+}
+
+// DiagnoseDestroyFailure collects the destroy diagnostics of a hosted cluster whose destroy failed
+// with cause, and returns cause with the pending finalizers and the diagnostics directory.
+// Nothing is collected when the HostedCluster is already gone.
+func DiagnoseDestroyFailure(hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface,
+	namespace, name string, since time.Time, cause error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), destroyDiagnosticsTimeout)
+	defer cancel()
+	if exists, err := HasResource(hubClientDynamic, HostedClustersGVR, namespace, name); err == nil && !exists {
+		return cause
+	}
+	fmt.Printf("Cluster %s: collecting destroy diagnostics\n", name)
+	diagnosis, err := CollectDestroyDiagnostics(ctx, hubClientDynamic, kubeClient, namespace, name, since)
+	if err != nil {
+		fmt.Printf("Cluster %s: %v\n", name, err)
+	}
+	return fmt.Errorf("%v\nHostedCluster %s/%s was not destroyed, %s", cause, namespace, name, diagnosis)
+}
+
+// WaitForHostedClusterDeleted waits until the HostedCluster namespace/name is gone or ctx is
+// done. On timeout the destroy diagnostics are collected and returned in the error.
+func WaitForHostedClusterDeleted(ctx context.Context, hubClientDynamic dynamic.Interface, kubeClient kubernetes.Interface,
+	namespace, name string, since time.Time) error {
+	err := wait.PollUntilContextCancel(ctx, eventuallyInterval, true, func(ctx context.Context) (bool, error) {
+		exists, err := HasResource(hubClientDynamic, HostedClustersGVR, namespace, name)
+		if err != nil {
+			fmt.Printf("Cluster %s: %v\n", name, err)
+			return false, nil
+		}
+		return !exists, nil
+	})
+	if err != nil {
+		return DiagnoseDestroyFailure(hubClientDynamic, kubeClient, namespace, name, since,
+			fmt.Errorf("ERROR HostedCluster %s/%s was not deleted: %v", namespace, name, err))
+	}
+	fmt.Printf("Hosted Cluster %s: successfully destroyed!\n\n", name)
+	return nil
+}
@@ -0,0 +1,67 @@
+package utils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWaitForHostedClusterDeletedDiagnosesStuckDestroy(t *testing.T) {
+	g := gomega.NewWithT(t)
+	artifactsDir := t.TempDir()
+	t.Setenv(utils.ArtifactsDirEnv, artifactsDir)
+	ctx := context.TODO()
+
+	hub := fakehub.New(fakehub.Options{})
+	hc := fakehub.NewHostedCluster("clusters", "acmqe-hc-1", utils.TYPE_AWS, "quay.io/openshift-release-dev/ocp-release:4.15.5-multi", "")
+	hc.SetFinalizers([]string{"hypershift.openshift.io/finalizer", "hypershift.io/aws-oidc-discovery"})
+	now := metav1.Now()
+	hc.SetDeletionTimestamp(&now)
+	_, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(ctx, hc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	np := fakehub.NewNodePool("clusters", "acmqe-hc-1-us-east-1a", "acmqe-hc-1", utils.TYPE_AWS, "quay.io/openshift-release-dev/ocp-release:4.15.5-multi", 2, "")
+	np.SetFinalizers([]string{"example.com/custom"})
+	_, err = hub.Dynamic.Resource(utils.NodePoolsGVR).Namespace("clusters").Create(ctx, np, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	operatorPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "operator-abc", Namespace: utils.HypershiftOperatorNamespace, Labels: map[string]string{"app": "operator"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "operator"}}},
+	}
+	_, err = hub.Kube.CoreV1().Pods(utils.HypershiftOperatorNamespace).Create(ctx, operatorPod, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	waitErr := utils.WaitForHostedClusterDeleted(waitCtx, hub.Dynamic, hub.Kube, "clusters", "acmqe-hc-1", time.Now().Add(-time.Hour))
+	g.Expect(waitErr).To(gomega.HaveOccurred())
+	dir := filepath.Join(artifactsDir, utils.DestroyDiagnosticsDirName, "acmqe-hc-1")
+	g.Expect(waitErr.Error()).To(gomega.ContainSubstring("ERROR HostedCluster clusters/acmqe-hc-1 was not deleted"))
+	g.Expect(waitErr.Error()).To(gomega.ContainSubstring("  - HostedCluster clusters/acmqe-hc-1 (deleting): hypershift.io/aws-oidc-discovery, removed by the hypershift operator (AWS OIDC documents in the S3 bucket)"))
+	g.Expect(waitErr.Error()).To(gomega.ContainSubstring("  - NodePool clusters/acmqe-hc-1-us-east-1a (not deleted): example.com/custom, removed by the unknown controller"))
+	g.Expect(waitErr.Error()).To(gomega.ContainSubstring("diagnostics: " + dir))
+
+	for _, file := range []string{"hostedcluster.yaml", "nodepools.yaml", "finalizers.txt", filepath.Join("logs", "hypershift-operator", "operator-abc_operator.log")} {
+		_, err := os.Stat(filepath.Join(dir, file))
+		g.Expect(err).NotTo(gomega.HaveOccurred(), file)
+	}
+	g.Expect(filepath.Join(dir, "managedcluster.yaml")).NotTo(gomega.BeAnExistingFile())
+	finalizers, err := os.ReadFile(filepath.Join(dir, "finalizers.txt"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(finalizers)).To(gomega.ContainSubstring("hypershift.openshift.io/finalizer, removed by the hypershift operator\n"))
+
+	catalog, err := utils.LoadFailureCatalog("../resources/failure_catalog.yaml")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(catalog.Classify(utils.FailureEvidence{Message: waitErr.Error()}).Tag).To(gomega.Equal("[destroy stuck]"))
+
+	// a HostedCluster that is gone is not diagnosed
+	g.Expect(hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Delete(ctx, "acmqe-hc-1", metav1.DeleteOptions{})).To(gomega.Succeed())
+	g.Expect(utils.WaitForHostedClusterDeleted(ctx, hub.Dynamic, hub.Kube, "clusters", "acmqe-hc-1", time.Now())).To(gomega.Succeed())
+}
@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	FailureCatalogFileEnv         = "FAILURE_CATALOG_FILE"
+	defaultFailureCatalogFilePath = "../resources/failure_catalog.yaml"
+	// Only the end of large log files is searched; the failure is usually logged last.
+	maxClassifiedLogBytes = 1 << 20
+)
+
+// Condition reasons quoted in failure messages, e.g. "reason=WaitingForAvailable" from
+// CheckCondition and WaitForCondition or "Reason: ..." from generateErrorMsg.
+var failureReasonRegexp = regexp.MustCompile(`(?:reason=|Reason: )([A-Za-z][A-Za-z0-9_]*)`)
+
+// FailureSignature describes a known failure. It matches when any of its condition reasons,
+// message patterns or log patterns matches.
+type FailureSignature struct {
+	Tag      string   `json:"tag"`
+	Solution string   `json:"solution,omitempty"`
+	Link     string   `json:"link,omitempty"`
+	Reasons  []string `json:"reasons,omitempty"`  // condition reasons, matched exactly
+	Messages []string `json:"messages,omitempty"` // regexes on the failure message, which includes the hcp CLI stderr
+	Logs     []string `json:"logs,omitempty"`     // regexes on the collected hypershift operator and addon agent logs
+
+	messages []*regexp.Regexp
+	logs     []*regexp.Regexp
+}
+
+// FailureCatalog is the list of known failures. The first matching signature wins.
+type FailureCatalog struct {
+	Failures []FailureSignature `json:"failures"`
+}
+
+// FailureEvidence is what a failure is classified on.
+type FailureEvidence struct {
+	Message string
+	Reasons []string
+	Logs    map[string]string // file name to content
+}
+
+// FailureClassification is the tag of a failure with its remediation.
+type FailureClassification struct {
+	Tag      string
+	Solution string
+	Link     string
+	Evidence string // what matched, empty for unknown failures
+}
+
+// Known reports whether the failure matched a signature of the catalog.
+func (c FailureClassification) Known() bool {
+	return c.Tag != UnknownError
+}
+
+// PossibleSolution returns the remediation and its link.
+func (c FailureClassification) PossibleSolution() string {
+	switch {
+	case c.Solution == "":
+		return c.Link
+	case c.Link == "":
+		return c.Solution
+	}
+	return c.Solution + " (" + c.Link + ")"
+}
+
+func (c FailureClassification) String() string {
+	if !c.Known() {
+		return c.Tag
+	}
+	return fmt.Sprintf("%s matched by %s, possible solution: %s", c.Tag, c.Evidence, c.PossibleSolution())
+}
+
+func unknownFailure() FailureClassification {
+	return FailureClassification{Tag: UnknownError, Link: UnknownErrorLink}
+}
+
+// LoadFailureCatalog reads the catalog from a YAML file.
+func LoadFailureCatalog(path string) (*FailureCatalog, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to read the failure catalog %s: %v", path, err)
+	}
+	catalog, err := ParseFailureCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR invalid failure catalog %s: %v", path, err)
+	}
+	return catalog, nil
+}
+
+// ParseFailureCatalog parses and validates a YAML catalog.
+func ParseFailureCatalog(data []byte) (*FailureCatalog, error) {
+	catalog := &FailureCatalog{}
+	if err := yaml.UnmarshalStrict(data, catalog); err != nil {
+		return nil, err
+	}
+	for i := range catalog.Failures {
+		signature := &catalog.Failures[i]
+		if signature.Tag == "" {
+			return nil, fmt.Errorf("failure %d has no tag", i)
+		}
+		if len(signature.Reasons)+len(signature.Messages)+len(signature.Logs) == 0 {
+			return nil, fmt.Errorf("failure %s has no reasons, messages or logs to match", signature.Tag)
+		}
+		for _, pattern := range signature.Messages {
+			r, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failure %s: invalid message pattern: %v", signature.Tag, err)
+			}
+			signature.messages = append(signature.messages, r)
+		}
+		for _, pattern := range signature.Logs {
+			r, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failure %s: invalid log pattern: %v", signature.Tag, err)
+			}
+			signature.logs = append(signature.logs, r)
+		}
+	}
+	return catalog, nil
+}
+
+var (
+	failureCatalog     *FailureCatalog
+	failureCatalogOnce sync.Once
+)
+
+// GetFailureCatalog returns the catalog, loaded once.
+// Priority: FAILURE_CATALOG_FILE env, then options.failureCatalog, else resources/failure_catalog.yaml.
+// A catalog that cannot be loaded is reported and treated as empty, so every failure is unknown.
+func GetFailureCatalog() *FailureCatalog {
+	failureCatalogOnce.Do(func() {
+		path := os.Getenv(FailureCatalogFileEnv)
+		if path == "" {
+			path = TestOptions.Options.FailureCatalog
+		}
+		if path == "" {
+			path = defaultFailureCatalogFilePath
+		}
+		catalog, err := LoadFailureCatalog(path)
+		if err != nil {
+			fmt.Printf("Failures will not be classified: %v\n", err)
+			catalog = &FailureCatalog{}
+		}
+		failureCatalog = catalog
+	})
+	return failureCatalog
+}
+
+// Classify returns the first signature matching the evidence, else the unknown error.
+func (c *FailureCatalog) Classify(evidence FailureEvidence) FailureClassification {
+	for _, signature := range c.Failures {
+		if matched := signature.match(evidence); matched != "" {
+			return FailureClassification{Tag: signature.Tag, Solution: signature.Solution, Link: signature.Link, Evidence: matched}
+		}
+	}
+	return unknownFailure()
+}
+
+func (s FailureSignature) match(evidence FailureEvidence) string {
+	for _, reason := range evidence.Reasons {
+		for _, r := range s.Reasons {
+			if reason == r {
+				return "reason " + reason
+			}
+		}
+	}
+	for _, r := range s.messages {
+		if r.MatchString(evidence.Message) {
+			return fmt.Sprintf("message %q", r.FindString(evidence.Message))
+		}
+	}
+	files := make([]string, 0, len(evidence.Logs))
+	for file := range evidence.Logs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		for _, r := range s.logs {
+			if content := evidence.Logs[file]; r.MatchString(content) {
+				return fmt.Sprintf("log %s %q", file, r.FindString(content))
+			}
+		}
+	}
+	return ""
+}
+
+// ClassifyConditionFailure classifies a condition that is not in the expected state.
+func ClassifyConditionFailure(reason, message string) FailureClassification {
+	evidence := FailureEvidence{Message: message}
+	if reason != "" {
+		evidence.Reasons = []string{reason}
+	}
+	return GetFailureCatalog().Classify(evidence)
+}
+
+// ClassifySpecFailure classifies the failure of a spec on its failure message, the condition
+// reasons quoted in it and the logs collected into its artifacts directory.
+func ClassifySpecFailure(spec types.SpecReport) FailureClassification {
+	message := spec.Failure.Message
+	if spec.Failure.ForwardedPanic != "" {
+		message += "\n" + spec.Failure.ForwardedPanic
+	}
+	evidence := FailureEvidence{Message: message, Logs: readFailureLogs(filepath.Join(SpecArtifactsDir(spec), "logs"))}
+	for _, match := range failureReasonRegexp.FindAllStringSubmatch(message, -1) {
+		evidence.Reasons = append(evidence.Reasons, match[1])
+	}
+	return GetFailureCatalog().Classify(evidence)
+}
+
+func readFailureLogs(dir string) map[string]string {
+	logs := map[string]string{}
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".log") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		if len(data) > maxClassifiedLogBytes {
+			data = data[len(data)-maxClassifiedLogBytes:]
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			path = rel
+		}
+		logs[path] = string(data)
+		return nil
+	})
+	return logs
+}
+
+func generateErrorMsg(tag, solution, reason, errmsg string) error {
+	return fmt.Errorf("tag: %v, "+
+		"Possible Solution: %v, "+
+		"Reason: %v, "+
+		"Error message: %v,",
+		tag, solution, reason, errmsg)
+}
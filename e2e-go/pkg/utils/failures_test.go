@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseFailureCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog string
+		wantErr string
+	}{
+		{
+			name:    "no tag",
+			catalog: "failures:\n- reasons: [ReconciliationPaused]\n",
+			wantErr: "has no tag",
+		},
+		{
+			name:    "nothing to match",
+			catalog: "failures:\n- tag: '[paused]'\n",
+			wantErr: "has no reasons, messages or logs",
+		},
+		{
+			name:    "invalid log pattern",
+			catalog: "failures:\n- tag: '[etcd]'\n  logs: ['etcd(']\n",
+			wantErr: "invalid log pattern",
+		},
+		{
+			name:    "unknown field",
+			catalog: "failures:\n- tag: '[etcd]'\n  reason: [EtcdWaitingForQuorum]\n",
+			wantErr: "unknown field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFailureCatalog([]byte(tt.catalog)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFailureCatalogClassify(t *testing.T) {
+	catalog, err := LoadFailureCatalog(defaultFailureCatalogFilePath)
+	if err != nil {
+		t.Fatalf("unexpected error loading the seed catalog: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		evidence FailureEvidence
+		wantTag  string
+	}{
+		{
+			name:     "condition reason",
+			evidence: FailureEvidence{Reasons: []string{"ReconciliationPaused"}},
+			wantTag:  "[reconciliation paused]",
+		},
+		{
+			name:     "hcp CLI stderr",
+			evidence: FailureEvidence{Message: "ERROR hcp create cluster aws failed with exit code 1 after 2m: exit status 1\nstderr:\nerror: VcpuLimitExceeded: You have requested more vCPU capacity"},
+			wantTag:  "[aws quota]",
+		},
+		{
+			name:     "aws throttling is not a quota",
+			evidence: FailureEvidence{Message: "ERROR hcp create cluster aws failed with exit code 1 after 2m: exit status 1\nstderr:\nerror: RequestLimitExceeded: Request limit exceeded."},
+			wantTag:  "[aws throttling]",
+		},
+		{
+			name:     "hcp CLI failure without a known cause",
+			evidence: FailureEvidence{Message: "ERROR hcp destroy cluster kubevirt failed with exit code 1 after 5s: exit status 1"},
+			wantTag:  "[hcp cli]",
+		},
+		{
+			name: "operator log",
+			evidence: FailureEvidence{
+				Message: "hostedclusters clusters/acmqe-hc-1: condition type=Available status=True was not reached: timed out",
+				Logs:    map[string]string{"hypershift-operator/operator-1_operator.log": "2024-04-01T10:00:00Z error NoSuchBucket: The specified bucket does not exist"},
+			},
+			wantTag: "[oidc s3 bucket]",
+		},
+		{
+			name: "destroy leftovers",
+			evidence: FailureEvidence{Message: (&ClusterCleanupError{Namespace: "clusters", Name: "acmqe-hc-1", Err: context.DeadlineExceeded, Leftovers: []ClusterLeftover{
+				{Kind: "ManagedClusterAddOn", Namespace: "acmqe-hc-1", Name: "work-manager", Component: ComponentAddonManager, Terminating: true},
+			}}).Error()},
+			wantTag: "[cleanup leftovers]",
+		},
+		{
+			name:     "unknown",
+			evidence: FailureEvidence{Message: "Expected <int>: 1 to equal <int>: 2", Reasons: []string{"AsExpected"}},
+			wantTag:  UnknownError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := catalog.Classify(tt.evidence)
+			if got.Tag != tt.wantTag {
+				t.Fatalf("expected %s, got %s", tt.wantTag, got)
+			}
+			if got.Known() && (got.Evidence == "" || got.Link == "") {
+				t.Errorf("expected the evidence and the link of a known failure, got %+v", got)
+			}
+			if !got.Known() && got.Link != UnknownErrorLink {
+				t.Errorf("expected the unknown error link, got %s", got.Link)
+			}
+		})
+	}
+}
+
+func TestCheckConditionClassification(t *testing.T) {
+	hc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind":     "HostedCluster",
+		"metadata": map[string]interface{}{"name": "acmqe-hc-1", "namespace": "clusters"},
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Available", "status": "False", "reason": "EtcdWaitingForQuorum", "message": "Waiting for etcd to reach quorum"},
+		}},
+	}}
+	err := CheckCondition(hc, HCPAvailableCondition)
+	if err == nil || !strings.HasPrefix(err.Error(), "tag: [etcd], Possible Solution: ") || !strings.Contains(err.Error(), "Reason: EtcdWaitingForQuorum") {
+		t.Errorf("expected an etcd failure, got %v", err)
+	}
+}
+
+func TestClassifySpecFailure(t *testing.T) {
+	t.Setenv(ArtifactsDirEnv, t.TempDir())
+	spec := types.SpecReport{
+		LeafNodeText: "Creates a FIPS AWS Hosted Cluster using STS Creds",
+		State:        types.SpecStateFailed,
+		Failure:      types.Failure{Message: "hostedclusters clusters/acmqe-hc-1: condition type=Available was not reached: timed out; last observed Available: status=False reason=WaitingForAvailable"},
+	}
+	if got := ClassifySpecFailure(spec); got.Known() {
+		t.Errorf("expected an unknown failure without logs, got %s", got)
+	}
+
+	logs := filepath.Join(SpecArtifactsDir(spec), "logs", "hypershift-operator")
+	if err := os.MkdirAll(logs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(logs, "operator-1_operator.log"), []byte("error: InvalidClientTokenId: The security token included in the request is invalid\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := ClassifySpecFailure(spec)
+	if got.Tag != "[aws credentials]" || !strings.Contains(got.Evidence, "hypershift-operator/operator-1_operator.log") {
+		t.Fatalf("expected an AWS credentials failure from the operator log, got %s", got)
+	}
+
+	test := newJUnitTestCase(types.Report{SuiteDescription: "Hypershift E2e Suite"}, spec)
+	if test.Failure == nil || !strings.HasPrefix(test.Failure.Message, "[aws credentials] hostedclusters") {
+		t.Errorf("expected the tag in the JUnit failure message, got %+v", test.Failure)
+	}
+	if !strings.HasPrefix(test.Failure.Description, "Failure tag: [aws credentials], Possible Solution: ") {
+		t.Errorf("expected the possible solution in the JUnit failure description, got %q", test.Failure.Description)
+	}
+}
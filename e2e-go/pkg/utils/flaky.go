@@ -0,0 +1,272 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Outcomes of a spec in a JUnit report.
+const (
+	SpecOutcomePassed  = "passed"
+	SpecOutcomeFailed  = "failed"
+	SpecOutcomeSkipped = "skipped"
+)
+
+// Hub build of runs without the mce.version and acm.version properties.
+const unknownHubBuild = "unknown"
+
+// SpecRun is one result of a spec read from a JUnit report.
+type SpecRun struct {
+	Spec      string    `json:"spec"`
+	Suite     string    `json:"suite"`
+	File      string    `json:"file"`
+	Build     string    `json:"build"`
+	Timestamp time.Time `json:"timestamp"`
+	Outcome   string    `json:"outcome"`
+	Seconds   float64   `json:"seconds"`
+}
+
+// SpecHistory is the history of a spec over all runs, oldest first.
+type SpecHistory struct {
+	Spec          string    `json:"spec"`
+	Runs          int       `json:"runs"`
+	Passed        int       `json:"passed"`
+	Failed        int       `json:"failed"`
+	Skipped       int       `json:"skipped"`
+	History       string    `json:"history"` // one letter per run: P passed, F failed, S skipped
+	Flips         int       `json:"flips"`   // changes between passed and failed, skips ignored
+	Flaky         bool      `json:"flaky"`
+	FlakyBuilds   []string  `json:"flakyBuilds,omitempty"` // hub builds the spec both passed and failed on
+	MeanSeconds   float64   `json:"meanSeconds"`
+	LastSeconds   float64   `json:"lastSeconds"`
+	DurationTrend float64   `json:"durationTrend"` // percent change of the mean duration of the newer half of the runs over the older half
+	LastRun       time.Time `json:"lastRun"`
+	LastOutcome   string    `json:"lastOutcome"`
+}
+
+// FlakyReport is the analysis of historical JUnit reports.
+type FlakyReport struct {
+	Files int           `json:"files"`
+	Runs  int           `json:"runs"`
+	Specs []SpecHistory `json:"specs"`
+}
+
+// ReadSpecRuns reads every spec result of the JUnit reports under dir. Files that are not JUnit
+// reports are ignored. A result present in several files, e.g. a stage report and a report merged
+// by junit-merge, is read once. The number of reports read is returned with the runs.
+func ReadSpecRuns(dir string) ([]SpecRun, int, error) {
+	runs := []SpecRun{}
+	seen := map[string]bool{}
+	files := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".xml" {
+			return nil
+		}
+		suites, err := ReadJUnitReport(path)
+		if err != nil {
+			fmt.Printf("Skipping %s: %v\n", path, err)
+			return nil
+		}
+		files++
+		for _, suite := range suites {
+			timestamp, _ := time.Parse("2006-01-02T15:04:05", suite.Timestamp)
+			build := hubBuild(suite.Properties)
+			for _, test := range suite.TestCases {
+				key := strings.Join([]string{suite.Name, suite.Timestamp, build, test.Name}, "\x00")
+				if suite.Timestamp == "" {
+					// without a start time, results of different files cannot be told apart
+					key += "\x00" + path
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				runs = append(runs, SpecRun{
+					Spec:      test.Name,
+					Suite:     suite.Name,
+					File:      path,
+					Build:     build,
+					Timestamp: timestamp,
+					Outcome:   testCaseOutcome(test),
+					Seconds:   test.Time,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, files, fmt.Errorf("ERROR failed to read the JUnit reports in %s: %v", dir, err)
+	}
+	return runs, files, nil
+}
+
+// hubBuild identifies the hub a suite ran on by its MCE and ACM versions.
+func hubBuild(properties *JUnitProperties) string {
+	if properties == nil {
+		return unknownHubBuild
+	}
+	versions := []string{}
+	for _, p := range properties.Properties {
+		if (p.Name == "mce.version" || p.Name == "acm.version") && p.Value != "" {
+			versions = append(versions, p.Name+"="+p.Value)
+		}
+	}
+	if len(versions) == 0 {
+		return unknownHubBuild
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ",")
+}
+
+func testCaseOutcome(test JUnitTestCase) string {
+	switch {
+	case test.Failure != nil || test.Error != nil:
+		return SpecOutcomeFailed
+	case test.Skipped != nil:
+		return SpecOutcomeSkipped
+	}
+	return SpecOutcomePassed
+}
+
+// AnalyzeSpecRuns computes the history of every spec. A spec is flaky when it both passed and
+// failed on the same hub build. Flaky specs come first, then the specs with the most failures.
+func AnalyzeSpecRuns(runs []SpecRun, files int) FlakyReport {
+	sorted := append([]SpecRun{}, runs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Timestamp.Equal(sorted[j].Timestamp) {
+			return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+		}
+		return sorted[i].File < sorted[j].File
+	})
+	bySpec := map[string][]SpecRun{}
+	for _, run := range sorted {
+		bySpec[run.Spec] = append(bySpec[run.Spec], run)
+	}
+
+	report := FlakyReport{Files: files, Runs: len(runs), Specs: []SpecHistory{}}
+	for spec, specRuns := range bySpec {
+		report.Specs = append(report.Specs, newSpecHistory(spec, specRuns))
+	}
+	sort.Slice(report.Specs, func(i, j int) bool {
+		a, b := report.Specs[i], report.Specs[j]
+		if a.Flaky != b.Flaky {
+			return a.Flaky
+		}
+		if a.Failed != b.Failed {
+			return a.Failed > b.Failed
+		}
+		return a.Spec < b.Spec
+	})
+	return report
+}
+
+func newSpecHistory(spec string, runs []SpecRun) SpecHistory {
+	history := SpecHistory{Spec: spec, Runs: len(runs)}
+	outcomes := map[string]map[string]bool{}
+	letters := []string{}
+	executed := []float64{}
+	last := ""
+	for _, run := range runs {
+		switch run.Outcome {
+		case SpecOutcomePassed:
+			history.Passed++
+		case SpecOutcomeFailed:
+			history.Failed++
+		case SpecOutcomeSkipped:
+			history.Skipped++
+		}
+		letters = append(letters, strings.ToUpper(run.Outcome[:1]))
+		if run.Outcome == SpecOutcomeSkipped {
+			continue
+		}
+		if last != "" && last != run.Outcome {
+			history.Flips++
+		}
+		last = run.Outcome
+		executed = append(executed, run.Seconds)
+		if outcomes[run.Build] == nil {
+			outcomes[run.Build] = map[string]bool{}
+		}
+		outcomes[run.Build][run.Outcome] = true
+	}
+	history.History = strings.Join(letters, "")
+	for build, seen := range outcomes {
+		if seen[SpecOutcomePassed] && seen[SpecOutcomeFailed] {
+			history.FlakyBuilds = append(history.FlakyBuilds, build)
+		}
+	}
+	sort.Strings(history.FlakyBuilds)
+	history.Flaky = len(history.FlakyBuilds) > 0
+
+	lastRun := runs[len(runs)-1]
+	history.LastRun, history.LastOutcome = lastRun.Timestamp, lastRun.Outcome
+	if len(executed) > 0 {
+		history.MeanSeconds = mean(executed)
+		history.LastSeconds = executed[len(executed)-1]
+	}
+	if len(executed) >= 2 {
+		older, newer := mean(executed[:len(executed)/2]), mean(executed[len(executed)/2:])
+		if older > 0 {
+			history.DurationTrend = math.Round((newer-older)/older*1000) / 10
+		}
+	}
+	return history
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Flaky returns the flaky specs.
+func (r FlakyReport) Flaky() []SpecHistory {
+	flaky := []SpecHistory{}
+	for _, spec := range r.Specs {
+		if spec.Flaky {
+			flaky = append(flaky, spec)
+		}
+	}
+	return flaky
+}
+
+// Markdown renders the report as a markdown document.
+func (r FlakyReport) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Spec history\n\n%d spec results of %d specs in %d JUnit reports, %d flaky.\n",
+		r.Runs, len(r.Specs), r.Files, len(r.Flaky()))
+	if flaky := r.Flaky(); len(flaky) > 0 {
+		b.WriteString("\n## Flaky specs\n\nSpecs that both passed and failed on the same hub build.\n\n")
+		b.WriteString("| Spec | History | Flips | Hub builds |\n|------|---------|-------|------------|\n")
+		for _, spec := range flaky {
+			fmt.Fprintf(&b, "| %s | `%s` | %d | %s |\n", markdownCell(spec.Spec), spec.History, spec.Flips, markdownCell(strings.Join(spec.FlakyBuilds, "; ")))
+		}
+	}
+	b.WriteString("\n## All specs\n\nHistory is oldest first: P passed, F failed, S skipped. Trend is the change of the mean duration of the newer half of the runs over the older half.\n\n")
+	b.WriteString("| Spec | Runs | Passed | Failed | Skipped | History | Mean | Last | Trend |\n")
+	b.WriteString("|------|------|--------|--------|---------|---------|------|------|-------|\n")
+	for _, spec := range r.Specs {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | `%s` | %s | %s | %+.1f%% |\n",
+			markdownCell(spec.Spec), spec.Runs, spec.Passed, spec.Failed, spec.Skipped, spec.History,
+			formatSeconds(spec.MeanSeconds), formatSeconds(spec.LastSeconds), spec.DurationTrend)
+	}
+	return b.String()
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
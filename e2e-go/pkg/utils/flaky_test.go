@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+// writeHistoricalRun writes the JUnit report of a run of the create spec and the e2e spec.
+func writeHistoricalRun(t *testing.T, dir string, day int, mceVersion string, create, e2e types.SpecState, createTime time.Duration) {
+	t.Helper()
+	report := types.Report{SuiteDescription: "Hypershift E2e Suite", StartTime: time.Date(2024, 4, day, 2, 0, 0, 0, time.UTC)}
+	report.SpecReports = types.SpecReports{
+		{LeafNodeType: types.NodeTypeIt, LeafNodeText: "Creates a FIPS AWS Hosted Cluster", LeafNodeLabels: []string{"create", TYPE_AWS}, State: create, RunTime: createTime},
+		{LeafNodeType: types.NodeTypeIt, LeafNodeText: "ServiceMonitor is correctly deployed", State: e2e, RunTime: time.Second},
+	}
+	dst := filepath.Join(dir, fmt.Sprintf("build-%d", day), "result.xml")
+	if err := GenerateJUnitReport(report, dst, RunMetadata{MCEVersion: mceVersion}.Properties()...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnalyzeSpecRuns(t *testing.T) {
+	dir := t.TempDir()
+	writeHistoricalRun(t, dir, 1, "2.6.0", types.SpecStatePassed, types.SpecStatePassed, 20*time.Minute)
+	writeHistoricalRun(t, dir, 2, "2.6.0", types.SpecStateFailed, types.SpecStateSkipped, 20*time.Minute)
+	writeHistoricalRun(t, dir, 3, "2.6.1", types.SpecStatePassed, types.SpecStateFailed, 30*time.Minute)
+	writeHistoricalRun(t, dir, 4, "2.6.1", types.SpecStatePassed, types.SpecStateFailed, 30*time.Minute)
+	// a merged report repeats the results of a run
+	if _, err := MergeJUnitReports(filepath.Join(dir, "merged", "junit.xml"), filepath.Join(dir, "build-4", "result.xml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "options.xml"), []byte("<options></options>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, files, err := ReadSpecRuns(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files != 5 || len(runs) != 8 {
+		t.Fatalf("expected 8 results in 5 reports, got %d in %d", len(runs), files)
+	}
+
+	report := AnalyzeSpecRuns(runs, files)
+	if len(report.Specs) != 2 {
+		t.Fatalf("expected 2 specs, got %+v", report.Specs)
+	}
+	create := report.Specs[0]
+	if !create.Flaky || !reflect.DeepEqual(create.FlakyBuilds, []string{"mce.version=2.6.0"}) {
+		t.Errorf("expected the create spec to be flaky on 2.6.0, got %+v", create)
+	}
+	if create.History != "PFPP" || create.Flips != 2 || create.Passed != 3 || create.Failed != 1 {
+		t.Errorf("unexpected create history: %+v", create)
+	}
+	if create.DurationTrend != 50 || create.LastSeconds != 1800 {
+		t.Errorf("expected the create spec to be 50%% slower, got %+v", create)
+	}
+
+	e2e := report.Specs[1]
+	if e2e.Flaky || e2e.History != "PSFF" || e2e.Flips != 1 || e2e.LastOutcome != SpecOutcomeFailed {
+		t.Errorf("expected the e2e spec to fail on a new build without being flaky, got %+v", e2e)
+	}
+
+	markdown := report.Markdown()
+	for _, want := range []string{
+		"8 spec results of 2 specs in 5 JUnit reports, 1 flaky.",
+		"| Creates a FIPS AWS Hosted Cluster [create, AWS] | `PFPP` | 2 | mce.version=2.6.0 |",
+		"| ServiceMonitor is correctly deployed | 4 | 1 | 2 | 1 | `PSFF` | 1s | 1s | +0.0% |",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected %q in the markdown report:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestFormatSeconds(t *testing.T) {
+	for seconds, want := range map[float64]string{0.25: "250ms", 1.5: "1.5s", 1800: "30m0s", 0.0004: "0s"} {
+		if got := formatSeconds(seconds); got != want {
+			t.Errorf("formatSeconds(%v): expected %s, got %s", seconds, want, got)
+		}
+	}
+}
@@ -1,8 +1,15 @@
 package utils
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
 
+	"github.com/onsi/ginkgo/v2"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
@@ -31,3 +38,292 @@ func GetConsoleCliDownload(hubClientDynamic dynamic.Interface, name string) (*un
 	}
 	return consoleDownload, err
 }
+
+// HCPResult is the outcome of a single hcp CLI invocation.
+type HCPResult struct {
+	Args     []string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+}
+
+// hcpFlag is a single value flag of an hcp command. An empty platforms list means the flag
+// applies to every platform, otherwise it is only valid for the listed platforms.
+type hcpFlag struct {
+	name      string
+	value     string
+	platforms []string
+}
+
+func (f hcpFlag) supports(platform string) bool {
+	if len(f.platforms) == 0 {
+		return true
+	}
+	for _, p := range f.platforms {
+		if strings.EqualFold(p, platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// HCPCreateCluster describes a `hcp create cluster <platform>` invocation.
+// Fields left empty are not passed to the CLI.
+type HCPCreateCluster struct {
+	// Platform is one of TYPE_AWS, TYPE_KUBEVIRT or TYPE_AGENT
+	Platform                       string
+	Name                           string
+	Namespace                      string
+	PullSecret                     string
+	ReleaseImage                   string
+	NodePoolReplicas               string
+	Arch                           string
+	InfraAvailabilityPolicy        string
+	ControlPlaneAvailabilityPolicy string
+	PausedUntil                    string
+	FIPS                           bool
+	GenerateSSH                    bool
+
+	// AWS only
+	STSCreds     string
+	RoleArn      string
+	SecretCreds  string
+	Region       string
+	InstanceType string
+
+	// AWS and Agent only
+	BaseDomain string
+
+	// KubeVirt only
+	Memory string
+	Cores  string
+
+	// Agent only
+	AgentNamespace string
+}
+
+func (c HCPCreateCluster) flags() []hcpFlag {
+	return []hcpFlag{
+		{name: "--name", value: c.Name},
+		{name: "--sts-creds", value: c.STSCreds, platforms: []string{TYPE_AWS}},
+		{name: "--role-arn", value: c.RoleArn, platforms: []string{TYPE_AWS}},
+		{name: "--secret-creds", value: c.SecretCreds, platforms: []string{TYPE_AWS}},
+		{name: "--pull-secret", value: c.PullSecret},
+		{name: "--base-domain", value: c.BaseDomain, platforms: []string{TYPE_AWS, TYPE_AGENT}},
+		{name: "--region", value: c.Region, platforms: []string{TYPE_AWS}},
+		{name: "--agent-namespace", value: c.AgentNamespace, platforms: []string{TYPE_AGENT}},
+		{name: "--memory", value: c.Memory, platforms: []string{TYPE_KUBEVIRT}},
+		{name: "--cores", value: c.Cores, platforms: []string{TYPE_KUBEVIRT}},
+		{name: "--node-pool-replicas", value: c.NodePoolReplicas},
+		{name: "--namespace", value: c.Namespace},
+		{name: "--instance-type", value: c.InstanceType, platforms: []string{TYPE_AWS}},
+		{name: "--release-image", value: c.ReleaseImage},
+		{name: "--arch", value: c.Arch},
+		{name: "--infra-availability-policy", value: c.InfraAvailabilityPolicy},
+		{name: "--control-plane-availability-policy", value: c.ControlPlaneAvailabilityPolicy},
+	}
+}
+
+// Validate checks that the required flags for the platform are set and that no flag
+// belonging to another platform is set.
+func (c HCPCreateCluster) Validate() error {
+	if err := validateHCPPlatform(c.Platform); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("ERROR: hcp create cluster requires --name")
+	}
+	if c.PullSecret == "" {
+		return fmt.Errorf("ERROR: hcp create cluster %s requires --pull-secret", strings.ToLower(c.Platform))
+	}
+	for _, f := range c.flags() {
+		if f.value != "" && !f.supports(c.Platform) {
+			return fmt.Errorf("ERROR: flag %s is not supported by hcp create cluster %s", f.name, strings.ToLower(c.Platform))
+		}
+	}
+
+	switch {
+	case strings.EqualFold(c.Platform, TYPE_AWS):
+		if err := validateAWSCreds(c.STSCreds, c.RoleArn, c.SecretCreds); err != nil {
+			return err
+		}
+		if c.BaseDomain == "" && c.SecretCreds == "" {
+			return fmt.Errorf("ERROR: hcp create cluster aws requires --base-domain")
+		}
+	case strings.EqualFold(c.Platform, TYPE_AGENT):
+		if c.AgentNamespace == "" {
+			return fmt.Errorf("ERROR: hcp create cluster agent requires --agent-namespace")
+		}
+	}
+	return nil
+}
+
+// Args validates the command and returns the hcp CLI arguments for it.
+func (c HCPCreateCluster) Args() ([]string, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	args := []string{"create", "cluster", strings.ToLower(c.Platform)}
+	for _, f := range c.flags() {
+		if f.value != "" {
+			args = append(args, f.name, f.value)
+		}
+	}
+	if c.FIPS {
+		args = append(args, "--fips")
+	}
+	if c.GenerateSSH {
+		args = append(args, "--generate-ssh")
+	}
+	if c.PausedUntil != "" {
+		args = append(args, "--pausedUntil", c.PausedUntil)
+	}
+	return args, nil
+}
+
+// Run executes the command with the hcp CLI. A non-zero exit code is returned as an error
+// together with the result so the output can still be inspected.
+func (c HCPCreateCluster) Run(ctx context.Context) (*HCPResult, error) {
+	args, err := c.Args()
+	if err != nil {
+		return nil, err
+	}
+	return RunHCP(ctx, args...)
+}
+
+// HCPDestroyCluster describes a `hcp destroy cluster <platform>` invocation.
+type HCPDestroyCluster struct {
+	// Platform is one of TYPE_AWS, TYPE_KUBEVIRT or TYPE_AGENT
+	Platform              string
+	Name                  string
+	Namespace             string
+	DestroyCloudResources bool
+
+	// AWS only
+	STSCreds    string
+	RoleArn     string
+	SecretCreds string
+}
+
+func (c HCPDestroyCluster) flags() []hcpFlag {
+	return []hcpFlag{
+		{name: "--name", value: c.Name},
+		{name: "--namespace", value: c.Namespace},
+		{name: "--sts-creds", value: c.STSCreds, platforms: []string{TYPE_AWS}},
+		{name: "--role-arn", value: c.RoleArn, platforms: []string{TYPE_AWS}},
+		{name: "--secret-creds", value: c.SecretCreds, platforms: []string{TYPE_AWS}},
+	}
+}
+
+// Validate checks that the required flags for the platform are set and that no flag
+// belonging to another platform is set.
+func (c HCPDestroyCluster) Validate() error {
+	if err := validateHCPPlatform(c.Platform); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("ERROR: hcp destroy cluster requires --name")
+	}
+	for _, f := range c.flags() {
+		if f.value != "" && !f.supports(c.Platform) {
+			return fmt.Errorf("ERROR: flag %s is not supported by hcp destroy cluster %s", f.name, strings.ToLower(c.Platform))
+		}
+	}
+	if strings.EqualFold(c.Platform, TYPE_AWS) {
+		return validateAWSCreds(c.STSCreds, c.RoleArn, c.SecretCreds)
+	}
+	return nil
+}
+
+// Args validates the command and returns the hcp CLI arguments for it.
+func (c HCPDestroyCluster) Args() ([]string, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	args := []string{"destroy", "cluster", strings.ToLower(c.Platform)}
+	for _, f := range c.flags() {
+		if f.value != "" {
+			args = append(args, f.name, f.value)
+		}
+	}
+	if c.DestroyCloudResources {
+		args = append(args, "--destroy-cloud-resources")
+	}
+	return args, nil
+}
+
+// Run executes the command with the hcp CLI. A non-zero exit code is returned as an error
+// together with the result so the output can still be inspected.
+func (c HCPDestroyCluster) Run(ctx context.Context) (*HCPResult, error) {
+	args, err := c.Args()
+	if err != nil {
+		return nil, err
+	}
+	return RunHCP(ctx, args...)
+}
+
+// RunHCP runs the hcp CLI with the given arguments, streaming its output to the GinkgoWriter
+// while capturing it in the returned result.
+func RunHCP(ctx context.Context, args ...string) (*HCPResult, error) {
+	fmt.Printf("Running cmd %s %s\n", HypershiftCLIName, strings.Join(args, " "))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, HypershiftCLIName, args...)
+	cmd.Stdout = io.MultiWriter(&stdout, ginkgo.GinkgoWriter)
+	cmd.Stderr = io.MultiWriter(&stderr, ginkgo.GinkgoWriter)
+
+	startTime := time.Now()
+	err := cmd.Run()
+	result := &HCPResult{
+		Args:     args,
+		ExitCode: -1,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(startTime),
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		subcommand := args
+		if len(subcommand) > 3 {
+			subcommand = subcommand[:3]
+		}
+		return result, fmt.Errorf("ERROR %s %s failed with exit code %d after %s: %v%s",
+			HypershiftCLIName, strings.Join(subcommand, " "), result.ExitCode, result.Duration, err, stderrTail(result.Stderr))
+	}
+	return result, nil
+}
+
+// stderrTail returns the last lines of the stderr so the failure message can be classified
+// on the reason the CLI gave.
+func stderrTail(stderr string) string {
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if len(lines) > 10 {
+		lines = lines[len(lines)-10:]
+	}
+	if tail := strings.Join(lines, "\n"); tail != "" {
+		return "\nstderr:\n" + tail
+	}
+	return ""
+}
+
+func validateHCPPlatform(platform string) error {
+	for _, p := range []string{TYPE_AWS, TYPE_KUBEVIRT, TYPE_AGENT} {
+		if strings.EqualFold(p, platform) {
+			return nil
+		}
+	}
+	return fmt.Errorf("ERROR: hcp platform %q is not supported", platform)
+}
+
+func validateAWSCreds(stsCreds, roleArn, secretCreds string) error {
+	if secretCreds != "" {
+		return nil
+	}
+	if stsCreds == "" || roleArn == "" {
+		return fmt.Errorf("ERROR: hcp aws commands require --sts-creds and --role-arn, or --secret-creds")
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHCPCreateClusterArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     HCPCreateCluster
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "aws with sts creds",
+			cmd: HCPCreateCluster{
+				Platform:                       TYPE_AWS,
+				Name:                           "acmqe-hc-1",
+				Namespace:                      "clusters",
+				STSCreds:                       "/tmp/sts.json",
+				RoleArn:                        "arn:aws:iam::123:role/hcp",
+				PullSecret:                     "/tmp/pull-secret",
+				BaseDomain:                     "example.com",
+				Region:                         "us-east-1",
+				NodePoolReplicas:               "2",
+				InstanceType:                   "m6a.xlarge",
+				ReleaseImage:                   "quay.io/openshift-release-dev/ocp-release:4.15.5-multi",
+				Arch:                           "amd64",
+				InfraAvailabilityPolicy:        "SingleReplica",
+				ControlPlaneAvailabilityPolicy: "SingleReplica",
+				FIPS:                           true,
+				GenerateSSH:                    true,
+				PausedUntil:                    "true",
+			},
+			want: []string{
+				"create", "cluster", "aws",
+				"--name", "acmqe-hc-1",
+				"--sts-creds", "/tmp/sts.json",
+				"--role-arn", "arn:aws:iam::123:role/hcp",
+				"--pull-secret", "/tmp/pull-secret",
+				"--base-domain", "example.com",
+				"--region", "us-east-1",
+				"--node-pool-replicas", "2",
+				"--namespace", "clusters",
+				"--instance-type", "m6a.xlarge",
+				"--release-image", "quay.io/openshift-release-dev/ocp-release:4.15.5-multi",
+				"--arch", "amd64",
+				"--infra-availability-policy", "SingleReplica",
+				"--control-plane-availability-policy", "SingleReplica",
+				"--fips",
+				"--generate-ssh",
+				"--pausedUntil", "true",
+			},
+		},
+		{
+			name: "kubevirt omits empty release image",
+			cmd: HCPCreateCluster{
+				Platform:         TYPE_KUBEVIRT,
+				Name:             "acmqe-hc-2",
+				PullSecret:       "/tmp/pull-secret",
+				Memory:           "10Gi",
+				Cores:            "2",
+				NodePoolReplicas: "2",
+				Namespace:        "clusters",
+			},
+			want: []string{
+				"create", "cluster", "kubevirt",
+				"--name", "acmqe-hc-2",
+				"--pull-secret", "/tmp/pull-secret",
+				"--memory", "10Gi",
+				"--cores", "2",
+				"--node-pool-replicas", "2",
+				"--namespace", "clusters",
+			},
+		},
+		{
+			name: "agent requires agent namespace",
+			cmd: HCPCreateCluster{
+				Platform:   TYPE_AGENT,
+				Name:       "acmqe-hc-3",
+				PullSecret: "/tmp/pull-secret",
+			},
+			wantErr: "--agent-namespace",
+		},
+		{
+			name: "aws requires creds",
+			cmd: HCPCreateCluster{
+				Platform:   TYPE_AWS,
+				Name:       "acmqe-hc-4",
+				PullSecret: "/tmp/pull-secret",
+				BaseDomain: "example.com",
+			},
+			wantErr: "--sts-creds",
+		},
+		{
+			name: "kubevirt rejects aws only flags",
+			cmd: HCPCreateCluster{
+				Platform:   TYPE_KUBEVIRT,
+				Name:       "acmqe-hc-5",
+				PullSecret: "/tmp/pull-secret",
+				Region:     "us-east-1",
+			},
+			wantErr: "--region",
+		},
+		{
+			name: "aws rejects kubevirt only flags",
+			cmd: HCPCreateCluster{
+				Platform:    TYPE_AWS,
+				Name:        "acmqe-hc-6",
+				PullSecret:  "/tmp/pull-secret",
+				SecretCreds: "aws-creds",
+				Memory:      "10Gi",
+			},
+			wantErr: "--memory",
+		},
+		{
+			name:    "unknown platform",
+			cmd:     HCPCreateCluster{Platform: "None", Name: "acmqe-hc-7", PullSecret: "/tmp/pull-secret"},
+			wantErr: "not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cmd.Args()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args mismatch\n got: %v\nwant: %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHCPDestroyClusterArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     HCPDestroyCluster
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "aws with secret creds",
+			cmd: HCPDestroyCluster{
+				Platform:              TYPE_AWS,
+				Name:                  "acmqe-hc-1",
+				Namespace:             "clusters",
+				SecretCreds:           "qe-hs-aws-secret",
+				DestroyCloudResources: true,
+			},
+			want: []string{
+				"destroy", "cluster", "aws",
+				"--name", "acmqe-hc-1",
+				"--namespace", "clusters",
+				"--secret-creds", "qe-hs-aws-secret",
+				"--destroy-cloud-resources",
+			},
+		},
+		{
+			name: "kubevirt",
+			cmd: HCPDestroyCluster{
+				Platform:  TYPE_KUBEVIRT,
+				Name:      "acmqe-hc-2",
+				Namespace: "clusters",
+			},
+			want: []string{"destroy", "cluster", "kubevirt", "--name", "acmqe-hc-2", "--namespace", "clusters"},
+		},
+		{
+			name:    "aws requires role arn with sts creds",
+			cmd:     HCPDestroyCluster{Platform: TYPE_AWS, Name: "acmqe-hc-3", STSCreds: "/tmp/sts.json"},
+			wantErr: "--role-arn",
+		},
+		{
+			name:    "kubevirt rejects aws creds",
+			cmd:     HCPDestroyCluster{Platform: TYPE_KUBEVIRT, Name: "acmqe-hc-4", SecretCreds: "qe-hs-aws-secret"},
+			wantErr: "--secret-creds",
+		},
+		{
+			name:    "name is required",
+			cmd:     HCPDestroyCluster{Platform: TYPE_KUBEVIRT},
+			wantErr: "--name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cmd.Args()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args mismatch\n got: %v\nwant: %v", got, tt.want)
+			}
+		})
+	}
+}
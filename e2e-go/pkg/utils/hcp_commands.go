@@ -0,0 +1,118 @@
+package utils
+
+// ClusterConfig is the hosted cluster configuration the create and destroy specs build their
+// hcp invocations from.
+type ClusterConfig struct {
+	ClusterName      string
+	InstanceType     string
+	BaseDomain       string
+	Region           string
+	NodePoolReplicas string
+	ReleaseImage     string
+	Namespace        string
+	PullSecret       string
+	AWSCreds         string
+	ExternalDNS      string
+	SecretCredsName  string
+	ClusterArch      string
+	AWSStsCreds      string
+	AWSRoleArn       string
+
+	// KubeVirt only
+	Memory string
+	Cores  string
+
+	// FIPS creates the cluster with FIPS enabled
+	FIPS bool
+	// Paused creates the cluster with pausedUntil true, for the ClusterCurator install to unpause
+	Paused bool
+}
+
+func (c ClusterConfig) pausedUntil() string {
+	if c.Paused {
+		return "true"
+	}
+	return ""
+}
+
+// AWSCreateCommand returns the `hcp create cluster aws` invocation of the AWS create specs: an
+// STS cluster with single replica control plane and infra.
+func AWSCreateCommand(c ClusterConfig) HCPCreateCluster {
+	return HCPCreateCluster{
+		Platform:                       TYPE_AWS,
+		Name:                           c.ClusterName,
+		STSCreds:                       c.AWSStsCreds,
+		RoleArn:                        c.AWSRoleArn,
+		PullSecret:                     c.PullSecret,
+		BaseDomain:                     c.BaseDomain,
+		Region:                         c.Region,
+		NodePoolReplicas:               c.NodePoolReplicas,
+		Namespace:                      c.Namespace,
+		InstanceType:                   c.InstanceType,
+		ReleaseImage:                   c.ReleaseImage,
+		Arch:                           c.ClusterArch,
+		InfraAvailabilityPolicy:        "SingleReplica",
+		ControlPlaneAvailabilityPolicy: "SingleReplica",
+		PausedUntil:                    c.pausedUntil(),
+		FIPS:                           c.FIPS,
+		GenerateSSH:                    true,
+	}
+}
+
+// KubeVirtCreateCommand returns the `hcp create cluster kubevirt` invocation of the KubeVirt
+// create spec. An empty release image is left to the hcp default.
+func KubeVirtCreateCommand(c ClusterConfig) HCPCreateCluster {
+	return HCPCreateCluster{
+		Platform:                       TYPE_KUBEVIRT,
+		Name:                           c.ClusterName,
+		PullSecret:                     c.PullSecret,
+		Memory:                         c.Memory,
+		Cores:                          c.Cores,
+		NodePoolReplicas:               c.NodePoolReplicas,
+		Namespace:                      c.Namespace,
+		InfraAvailabilityPolicy:        "SingleReplica",
+		ControlPlaneAvailabilityPolicy: "SingleReplica",
+		ReleaseImage:                   c.ReleaseImage,
+		PausedUntil:                    c.pausedUntil(),
+		FIPS:                           c.FIPS,
+		GenerateSSH:                    true,
+	}
+}
+
+// AWSDestroyCommand returns the `hcp destroy cluster aws` invocation of the AWS destroy-one spec,
+// which destroys with the AWS credentials secret.
+func AWSDestroyCommand(c ClusterConfig) HCPDestroyCluster {
+	return HCPDestroyCluster{
+		Platform:              TYPE_AWS,
+		Name:                  c.ClusterName,
+		SecretCreds:           c.SecretCredsName,
+		Namespace:             c.Namespace,
+		DestroyCloudResources: true,
+	}
+}
+
+// KubeVirtDestroyCommand returns the `hcp destroy cluster kubevirt` invocation of the KubeVirt
+// destroy-one spec.
+func KubeVirtDestroyCommand(c ClusterConfig) HCPDestroyCluster {
+	return HCPDestroyCluster{
+		Platform:              TYPE_KUBEVIRT,
+		Name:                  c.ClusterName,
+		Namespace:             c.Namespace,
+		DestroyCloudResources: true,
+	}
+}
+
+// AWSBulkDestroyCreds returns the credentials the AWS destroy spec passes to
+// DestroyHostedClusters: the STS credentials and role ARN.
+func AWSBulkDestroyCreds(c ClusterConfig) HCPDestroyCluster {
+	return HCPDestroyCluster{
+		STSCreds:              c.AWSStsCreds,
+		RoleArn:               c.AWSRoleArn,
+		DestroyCloudResources: true,
+	}
+}
+
+// KubeVirtBulkDestroyCreds returns what the KubeVirt destroy spec passes to DestroyHostedClusters.
+func KubeVirtBulkDestroyCreds() HCPDestroyCluster {
+	return HCPDestroyCluster{DestroyCloudResources: true}
+}
@@ -1,14 +1,17 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"sort"
 
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
-	libgounstructuredv1 "github.com/stolostron/library-go/pkg/apis/meta/v1/unstructured"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 )
 
@@ -70,31 +73,15 @@ func CheckHCPAvailable(hubClientDynamic dynamic.Interface, clusterName string, n
 	if err != nil {
 		return err
 	}
-
-	var condition map[string]interface{}
-	condition, err = libgounstructuredv1.GetConditionByType(hostedCluster, "Available")
-	if err != nil {
-		return err
-	}
-	fmt.Printf("HostedCluster %s: Condition %#v\n", clusterName, condition)
-
-	// Ensure the rest of the conditions have status set to true
-	if v, ok := condition["status"]; ok && v == string(metav1.ConditionTrue) &&
-		(condition["message"] == "The hosted control plane is available") &&
-		(condition["reason"] == "AsExpected") {
-		return nil
-	} else {
-		fmt.Printf("HostedCluster %s: Expected condition \"%s\" but got \"%v\"\n", clusterName, metav1.ConditionTrue, v)
-		return generateErrorMsg(UnknownError, UnknownErrorLink,
-			"HostedCluster control plane not available!",
-			"HostedCluster control plane not available!")
-	}
+	return CheckCondition(hostedCluster, HCPAvailableCondition)
 }
 
+// WaitForHCPAvailable watches the HostedCluster until its hosted control plane is available.
 func WaitForHCPAvailable(hubClientDynamic dynamic.Interface, clusterName string, namespace string) {
-	gomega.Eventually(func() error {
-		return CheckHCPAvailable(hubClientDynamic, clusterName, namespace)
-	}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeNil())
+	ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+	defer cancel()
+	_, err := WaitForCondition(ctx, hubClientDynamic, HostedClustersGVR, namespace, clusterName, HCPAvailableCondition)
+	gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
 	fmt.Printf("HostedCluster %s: The hosted control plane is available\n\n", clusterName)
 }
 
@@ -137,6 +124,28 @@ func GetHostedClusterSpecRelease(hubClientDynamic dynamic.Interface, clusterName
 	return "", nil
 }
 
+// CheckHostedClusterPaused checks that the HostedCluster and its NodePools still have
+// spec.pausedUntil set, e.g. while a curator install has not started the provisioning job.
+func CheckHostedClusterPaused(hubClientDynamic dynamic.Interface, clusterName, namespace string) error {
+	hc, err := GetResource(hubClientDynamic, HostedClustersGVR, namespace, clusterName)
+	if err != nil {
+		return err
+	}
+	if pausedUntil, _, _ := unstructured.NestedString(hc.Object, "spec", "pausedUntil"); pausedUntil == "" {
+		return fmt.Errorf("ERROR HostedCluster %s/%s is not paused", namespace, clusterName)
+	}
+	nodePools, err := ListNodePoolsForHostedCluster(hubClientDynamic, namespace, clusterName)
+	if err != nil {
+		return err
+	}
+	for _, np := range nodePools {
+		if pausedUntil, _, _ := unstructured.NestedString(np.Object, "spec", "pausedUntil"); pausedUntil == "" {
+			return fmt.Errorf("ERROR NodePool %s/%s of HostedCluster %s is not paused", namespace, np.GetName(), clusterName)
+		}
+	}
+	return nil
+}
+
 // GetHostedClusterChannel returns the HostedCluster spec.channel (PR 511 / ACM-26476).
 // Returns empty string if channel is not set.
 func GetHostedClusterChannel(hubClientDynamic dynamic.Interface, clusterName, namespace string) (string, error) {
@@ -228,3 +237,70 @@ func GetNodePoolSpecRelease(np *unstructured.Unstructured) (string, error) {
 	}
 	return "", nil
 }
+
+// GetNodePoolReplicaCounts returns the NodePool spec.replicas and status.replicas, 0 when not set.
+func GetNodePoolReplicaCounts(np *unstructured.Unstructured) (specReplicas, statusReplicas int64) {
+	replicas := func(fields ...string) int64 {
+		value, _, _ := unstructured.NestedFieldNoCopy(np.Object, fields...)
+		switch n := value.(type) {
+		case int64:
+			return n
+		case float64:
+			return int64(n)
+		}
+		return 0
+	}
+	return replicas("spec", "replicas"), replicas("status", "replicas")
+}
+
+// ScaleNodePool patches the NodePool spec.replicas, e.g. between the prehook and posthook of a
+// scale curation.
+func ScaleNodePool(hubClientDynamic dynamic.Interface, namespace, name string, replicas int64) error {
+	fmt.Printf("NodePool %s: Patching spec.replicas to %d in namespace %s\n", name, replicas, namespace)
+	payload := fmt.Sprintf(`{"spec": {"replicas": %d}}`, replicas)
+	_, err := hubClientDynamic.Resource(NodePoolsGVR).Namespace(namespace).Patch(context.TODO(), name, types.MergePatchType, []byte(payload), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("ERROR Failed to patch nodepool replicas: %v", err)
+	}
+	return nil
+}
+
+// WaitForNodePoolsScaled waits until the spec.replicas and status.replicas of each NodePool of
+// the HostedCluster in replicas, by NodePool name, are its replicas, e.g. after ScaleNodePool.
+func WaitForNodePoolsScaled(ctx context.Context, hubClientDynamic dynamic.Interface, namespace, clusterName string, replicas map[string]int64) error {
+	names := make([]string, 0, len(replicas))
+	for name := range replicas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, eventuallyInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := ListNodePoolsForHostedCluster(hubClientDynamic, namespace, clusterName)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		byName := map[string]*unstructured.Unstructured{}
+		for _, np := range current {
+			byName[np.GetName()] = np
+		}
+		for _, name := range names {
+			np, ok := byName[name]
+			if !ok {
+				return false, fmt.Errorf("ERROR NodePool %s/%s of HostedCluster %s not found", namespace, name, clusterName)
+			}
+			want := replicas[name]
+			specReplicas, statusReplicas := GetNodePoolReplicaCounts(np)
+			fmt.Printf("Cluster %s: NodePool %s has %d of %d replicas, want %d\n", clusterName, name, statusReplicas, specReplicas, want)
+			if specReplicas != want || statusReplicas != want {
+				lastErr = fmt.Errorf("NodePool %s has spec.replicas %d and status.replicas %d, want %d", name, specReplicas, statusReplicas, want)
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("ERROR timed out waiting for the NodePools of HostedCluster %s/%s to scale: %v", namespace, clusterName, lastErr)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// AllOwners as an owner of a JanitorPolicy selects the HostedClusters of every owner.
+const AllOwners = "*"
+
+// JanitorPolicy selects the stale HostedClusters created by the suite.
+type JanitorPolicy struct {
+	// MaxAge is the age from which a HostedCluster is stale
+	MaxAge time.Duration
+	// Owners restricts the HostedClusters to the owners; the owner of GetOwner when empty, and
+	// every owner only with AllOwners
+	Owners []string
+	// KeepRunIDs are runs, e.g. the ones still in progress, whose HostedClusters are kept
+	KeepRunIDs []string
+	// Selector is an extra label selector of the HostedClusters
+	Selector string
+}
+
+// StaleHostedCluster is a HostedCluster selected by a JanitorPolicy.
+type StaleHostedCluster struct {
+	Name      string
+	Namespace string
+	Platform  string
+	Owner     string
+	RunID     string
+	CreatedAt time.Time
+	Age       time.Duration
+}
+
+// DestroyCommand returns the hcp destroy invocation of the cluster. The AWS credentials of creds
+// are only kept for an AWS cluster.
+func (c StaleHostedCluster) DestroyCommand(creds HCPDestroyCluster) HCPDestroyCluster {
+	return hcpDestroyCommand(c.Platform, c.Namespace, c.Name, creds)
+}
+
+// FindStaleHostedClusters lists the HostedClusters carrying the owner label of the suite and
+// returns the ones the policy selects, oldest first. The age is taken from the created-at
+// annotation, else from the creation timestamp.
+func FindStaleHostedClusters(hubClientDynamic dynamic.Interface, policy JanitorPolicy, now time.Time) ([]StaleHostedCluster, error) {
+	selector := OwnerLabel
+	if policy.Selector != "" {
+		selector += "," + policy.Selector
+	}
+	hostedClusters, err := GetHostedClustersList(hubClientDynamic, "", selector)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to list the HostedClusters with %s: %v", selector, err)
+	}
+	owners := policy.Owners
+	if len(owners) == 0 {
+		owners = []string{GetOwner()}
+	}
+	allOwners := false
+	for _, owner := range owners {
+		allOwners = allOwners || owner == AllOwners
+	}
+
+	stale := []StaleHostedCluster{}
+	for _, hc := range hostedClusters {
+		cluster := StaleHostedCluster{
+			Name:      hc.GetName(),
+			Namespace: hc.GetNamespace(),
+			Owner:     hc.GetLabels()[OwnerLabel],
+			RunID:     hc.GetLabels()[RunIDLabel],
+			CreatedAt: hc.GetCreationTimestamp().Time,
+		}
+		cluster.Platform, _, _ = unstructured.NestedString(hc.Object, "spec", "platform", "type")
+		if createdAt, err := time.Parse(time.RFC3339, hc.GetAnnotations()[CreatedAtAnnotation]); err == nil {
+			cluster.CreatedAt = createdAt
+		}
+		cluster.Age = now.Sub(cluster.CreatedAt)
+
+		if cluster.Age < policy.MaxAge || (!allOwners && !containsLabelValue(owners, cluster.Owner)) ||
+			containsLabelValue(policy.KeepRunIDs, cluster.RunID) {
+			continue
+		}
+		stale = append(stale, cluster)
+	}
+	sort.SliceStable(stale, func(i, j int) bool { return stale[i].CreatedAt.Before(stale[j].CreatedAt) })
+	return stale, nil
+}
+
+// containsLabelValue reports whether value is one of values, compared as label values.
+func containsLabelValue(values []string, value string) bool {
+	for _, v := range values {
+		if LabelValue(v) == value {
+			return true
+		}
+	}
+	return false
+}
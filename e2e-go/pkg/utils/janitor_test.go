@@ -0,0 +1,69 @@
+package utils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLabelHostedClusterOwnership(t *testing.T) {
+	g := gomega.NewWithT(t)
+	t.Setenv(utils.AdditionalLabelsEnv, "team=acmqe-hypershift-auto, purpose=e2e")
+	t.Setenv(utils.RunIDEnv, "")
+
+	hub := fakehub.New(fakehub.Options{})
+	hc := fakehub.NewHostedCluster("clusters", "acmqe-hc-1", utils.TYPE_KUBEVIRT, "quay.io/openshift-release-dev/ocp-release:4.16.0-multi", "")
+	_, err := hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(context.TODO(), hc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(utils.LabelHostedClusterOwnership(hub.Dynamic, "clusters", "acmqe-hc-1")).To(gomega.Succeed())
+	labels, err := utils.GetResourceLabels(hub.Dynamic, utils.HostedClustersGVR, "clusters", "acmqe-hc-1")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(labels).To(gomega.HaveKeyWithValue("team", "acmqe-hypershift-auto"))
+	g.Expect(labels).To(gomega.HaveKeyWithValue("purpose", "e2e"))
+	g.Expect(labels).To(gomega.HaveKeyWithValue(utils.OwnerLabel, utils.LabelValue(utils.GetOwner())))
+	g.Expect(labels).To(gomega.HaveKeyWithValue(utils.RunIDLabel, utils.LabelValue(utils.GetRunID())))
+	annotations, err := utils.GetResourceAnnotations(hub.Dynamic, utils.HostedClustersGVR, "clusters", "acmqe-hc-1")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	createdAt, err := time.Parse(time.RFC3339, annotations[utils.CreatedAtAnnotation])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	// a fresh cluster is not stale, an old one of another owner is not selected
+	stale, err := utils.FindStaleHostedClusters(hub.Dynamic, utils.JanitorPolicy{MaxAge: time.Hour}, createdAt.Add(time.Minute))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(stale).To(gomega.BeEmpty())
+	stale, err = utils.FindStaleHostedClusters(hub.Dynamic, utils.JanitorPolicy{MaxAge: time.Hour, Owners: []string{"someone-else"}}, createdAt.Add(2*time.Hour))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(stale).To(gomega.BeEmpty())
+	stale, err = utils.FindStaleHostedClusters(hub.Dynamic, utils.JanitorPolicy{MaxAge: time.Hour, Owners: []string{utils.AllOwners}}, createdAt.Add(2*time.Hour))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(stale).To(gomega.HaveLen(1))
+
+	stale, err = utils.FindStaleHostedClusters(hub.Dynamic, utils.JanitorPolicy{MaxAge: time.Hour, Selector: "purpose=e2e"}, createdAt.Add(2*time.Hour))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(stale).To(gomega.HaveLen(1))
+	g.Expect(stale[0].Platform).To(gomega.Equal(utils.TYPE_KUBEVIRT))
+	g.Expect(stale[0].Age).To(gomega.Equal(2 * time.Hour))
+	cmd := stale[0].DestroyCommand(utils.HCPDestroyCluster{SecretCreds: "qe-hs-aws-secret"})
+	g.Expect(cmd.Args()).To(gomega.Equal([]string{"destroy", "cluster", "kubevirt", "--name", "acmqe-hc-1", "--namespace", "clusters"}))
+
+	g.Expect(utils.LabelValue("Jane Doe@example.com/")).To(gomega.Equal("Jane-Doe-example.com"))
+	t.Setenv(utils.AdditionalLabelsEnv, "owner")
+	_, err = utils.GetAdditionalLabels(utils.TYPE_KUBEVIRT)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("expected key=value")))
+
+	// each platform reads its own options
+	t.Setenv(utils.AdditionalLabelsEnv, "")
+	utils.TestOptions.Options.HostedCluster.AWS.AdditionalLabels = "team=aws"
+	utils.TestOptions.Options.HostedCluster.KubeVirt.AdditionalLabels = "team=kubevirt"
+	t.Cleanup(func() { utils.TestOptions.Options.HostedCluster = utils.Clusters{} })
+	g.Expect(utils.GetAdditionalLabels(utils.TYPE_AWS)).To(gomega.Equal(map[string]string{"team": "aws"}))
+	g.Expect(utils.GetAdditionalLabels(utils.TYPE_KUBEVIRT)).To(gomega.Equal(map[string]string{"team": "kubevirt"}))
+	_, err = utils.GetAdditionalLabels("Agent")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("no additional labels options")))
+}
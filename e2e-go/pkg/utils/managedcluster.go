@@ -6,7 +6,6 @@ import (
 
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
-	libgounstructuredv1 "github.com/stolostron/library-go/pkg/apis/meta/v1/unstructured"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
@@ -45,27 +44,15 @@ func CheckClusterImported(hubClientDynamic dynamic.Interface, clusterName string
 	if err != nil {
 		return err
 	}
-
-	var condition map[string]interface{}
-	condition, err = libgounstructuredv1.GetConditionByType(managedCluster, "ManagedClusterConditionAvailable")
-	if err != nil {
-		return err
-	}
-	fmt.Printf("Cluster %s: Condition %#v\n", clusterName, condition)
-
-	// Ensure the rest of the conditions have status set to true
-	if v, ok := condition["status"]; ok && v == string(metav1.ConditionTrue) {
-		return nil
-	} else {
-		fmt.Printf("Cluster %s: Current is not equal to \"%s\" but \"%v\"\n", clusterName, metav1.ConditionTrue, v)
-		return generateErrorMsg(UnknownError, UnknownErrorLink, "Import cluster fail, Cluster status in unknown", "Import cluster fail, Cluster status in unknown")
-	}
+	return CheckCondition(managedCluster, ClusterImportedCondition)
 }
 
+// WaitForClusterImported watches the ManagedCluster until it is available.
 func WaitForClusterImported(hubClientDynamic dynamic.Interface, clusterName string) {
-	gomega.Eventually(func() error {
-		return CheckClusterImported(hubClientDynamic, clusterName)
-	}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeNil())
+	ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+	defer cancel()
+	_, err := WaitForCondition(ctx, hubClientDynamic, ManagedClustersGVR, "", clusterName, ClusterImportedCondition)
+	gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
 	fmt.Printf("Cluster %s: successfully auto-imported!\n\n", clusterName)
 }
 
@@ -91,10 +78,10 @@ func WaitForClusterAddonsAvailable(hubClientDynamic dynamic.Interface, clusterNa
 	}
 
 	for _, addonName := range addonsToCheck {
-		gomega.Eventually(func() error {
-			fmt.Printf("Cluster %s: Checking Add-On %s is available...\n", clusterName, addonName)
-			return ValidateClusterAddOnAvailable(hubClientDynamic, clusterName, addonName)
-		}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeNil())
+		fmt.Printf("Cluster %s: Checking Add-On %s is available...\n", clusterName, addonName)
+		if err := waitForClusterAddOnAvailable(hubClientDynamic, clusterName, addonName); err != nil {
+			return err
+		}
 	}
 
 	fmt.Printf("Cluster %s: all add-ons are available!\n\n", clusterName)
@@ -108,32 +95,31 @@ func WaitForAllClusterAddonsAvailable(hubClientDynamic dynamic.Interface, cluste
 	gomega.Expect(len(managedClusterAddons.Items) > 0).Should(gomega.BeTrue())
 
 	for _, addon := range managedClusterAddons.Items {
-		if _, ok := addon.Object["metadata"]; ok {
-			addonName := addon.Object["metadata"].(map[string]interface{})["name"].(string)
-			gomega.Eventually(func() error {
-				fmt.Printf("Cluster %s: Checking Add-On %s is available...\n", clusterName, addonName)
-				return ValidateClusterAddOnAvailable(hubClientDynamic, clusterName, addonName)
-			}, eventuallyTimeout, eventuallyInterval).Should(gomega.BeNil())
-		}
+		fmt.Printf("Cluster %s: Checking Add-On %s is available...\n", clusterName, addon.GetName())
+		gomega.Expect(waitForClusterAddOnAvailable(hubClientDynamic, clusterName, addon.GetName())).Should(gomega.Succeed())
 	}
 	fmt.Printf("Cluster %s: all add-ons are available!\n\n", clusterName)
 }
 
-func ValidateClusterAddOnAvailable(dynamicClient dynamic.Interface, clusterName string, addOnName string) error {
-	managedClusterAddon, err := dynamicClient.Resource(ManagedClusterAddonGVR).Namespace(clusterName).Get(context.TODO(), addOnName, metav1.GetOptions{})
+func waitForClusterAddOnAvailable(hubClientDynamic dynamic.Interface, clusterName string, addOnName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), eventuallyTimeout)
+	defer cancel()
+	_, err := WaitForCondition(ctx, hubClientDynamic, ManagedClusterAddonGVR, clusterName, addOnName, AddOnAvailableCondition)
 	if err != nil {
 		return err
 	}
+	fmt.Printf("Cluster %s: Add-On %s is available! \n", clusterName, addOnName)
+	return nil
+}
 
-	var condition map[string]interface{}
-	condition, err = libgounstructuredv1.GetConditionByType(managedClusterAddon, "Available")
+func ValidateClusterAddOnAvailable(dynamicClient dynamic.Interface, clusterName string, addOnName string) error {
+	managedClusterAddon, err := dynamicClient.Resource(ManagedClusterAddonGVR).Namespace(clusterName).Get(context.TODO(), addOnName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
-	if v, ok := condition["status"]; ok && v == string(metav1.ConditionTrue) {
-		fmt.Printf("Cluster %s: Add-On %s is available! \n", clusterName, addOnName)
-		return nil
+	if err := CheckCondition(managedClusterAddon, AddOnAvailableCondition); err != nil {
+		return fmt.Errorf("cluster %s - Add-On %s: %v", clusterName, addOnName, err)
 	}
-	err = fmt.Errorf("cluster %s - Add-On %s: status not found or not true", clusterName, addOnName)
-	return err
+	fmt.Printf("Cluster %s: Add-On %s is available! \n", clusterName, addOnName)
+	return nil
 }
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	OcCLIName          = "oc"
+	HypershiftDumpFile = "hypershift-dump.tar.gz"
+)
+
+// MustGatherArchive lists the regular files of a must-gather archive.
+type MustGatherArchive struct {
+	Path  string
+	Files []string
+}
+
+// mustGatherExpectation is a piece of content the hypershift dump must contain. It is met when
+// at least one archive file matches one of the patterns; patterns are matched against the file
+// path from any directory level down, so a leading dump directory does not matter.
+type mustGatherExpectation struct {
+	description string
+	patterns    []string
+}
+
+func hypershiftDumpExpectations(hc HostedClusterRef) []mustGatherExpectation {
+	controlPlaneNamespace := hc.Namespace + "-" + hc.Name
+	return []mustGatherExpectation{
+		{
+			description: fmt.Sprintf("HostedCluster %s/%s YAML", hc.Namespace, hc.Name),
+			patterns:    []string{fmt.Sprintf("namespaces/%s/hypershift.openshift.io/hostedclusters/%s.yaml", hc.Namespace, hc.Name)},
+		},
+		{
+			description: fmt.Sprintf("NodePool YAML in %s", hc.Namespace),
+			patterns:    []string{fmt.Sprintf("namespaces/%s/hypershift.openshift.io/nodepools/*.yaml", hc.Namespace)},
+		},
+		{
+			description: fmt.Sprintf("control plane pod logs in %s", controlPlaneNamespace),
+			patterns: []string{
+				fmt.Sprintf("namespaces/%s/core/pods/logs/*.log", controlPlaneNamespace),
+				fmt.Sprintf("namespaces/%s/pods/*/*/*/logs/*.log", controlPlaneNamespace),
+			},
+		},
+		{
+			description: fmt.Sprintf("namespace manifest of %s", hc.Namespace),
+			patterns:    []string{fmt.Sprintf("namespaces/%s/%s.yaml", hc.Namespace, hc.Namespace)},
+		},
+		{
+			description: fmt.Sprintf("namespace manifest of %s", controlPlaneNamespace),
+			patterns:    []string{fmt.Sprintf("namespaces/%s/%s.yaml", controlPlaneNamespace, controlPlaneNamespace)},
+		},
+	}
+}
+
+// GetMustGatherImage returns the must-gather image.
+// Priority: MUST_GATHER_IMAGE env, then options.mustgather.image, else "".
+func GetMustGatherImage() string {
+	if v := os.Getenv("MUST_GATHER_IMAGE"); v != "" {
+		return v
+	}
+	return TestOptions.Options.MustGather.Image
+}
+
+// GetMustGatherHostedCluster returns the HostedCluster to run must-gather against.
+// Priority for the name: MUST_GATHER_HCP env, then GetClusterName. An empty name means any
+// HostedCluster on the hub. Priority for the namespace: MUST_GATHER_HCP_NS env, then GetNamespace.
+func GetMustGatherHostedCluster() (HostedClusterRef, error) {
+	ref := HostedClusterRef{Name: os.Getenv("MUST_GATHER_HCP"), Namespace: os.Getenv("MUST_GATHER_HCP_NS")}
+	if ref.Name == "" {
+		name, err := GetClusterName("aws")
+		if err != nil {
+			return ref, err
+		}
+		ref.Name = name
+	}
+	if ref.Namespace == "" {
+		namespace, err := GetNamespace(TYPE_AWS)
+		if err != nil {
+			return ref, err
+		}
+		ref.Namespace = namespace
+	}
+	return ref, nil
+}
+
+// SelectHostedCluster returns the given HostedCluster if it exists on the hub. When no name is
+// given, a random HostedCluster is picked from all namespaces.
+func SelectHostedCluster(hubClientDynamic dynamic.Interface, hc HostedClusterRef) (HostedClusterRef, error) {
+	if hc.Name != "" {
+		if _, err := GetResource(hubClientDynamic, HostedClustersGVR, hc.Namespace, hc.Name); err != nil {
+			return hc, fmt.Errorf("ERROR failed to get the HostedCluster %s/%s: %v", hc.Namespace, hc.Name, err)
+		}
+		return hc, nil
+	}
+
+	hostedClusters, err := GetHostedClustersList(hubClientDynamic, "", "")
+	if err != nil {
+		return hc, fmt.Errorf("ERROR failed to list the HostedClusters: %v", err)
+	}
+	if len(hostedClusters) == 0 {
+		return hc, fmt.Errorf("ERROR no HostedCluster found on the hub")
+	}
+	picked := hostedClusters[rand.New(rand.NewSource(time.Now().UnixNano())).Intn(len(hostedClusters))]
+	fmt.Printf("Picked HostedCluster %s/%s out of %d\n", picked.GetNamespace(), picked.GetName(), len(hostedClusters))
+	return HostedClusterRef{Namespace: picked.GetNamespace(), Name: picked.GetName()}, nil
+}
+
+// RunMustGather runs `oc adm must-gather` with the given image for the HostedCluster and writes
+// the result into destDir.
+func RunMustGather(ctx context.Context, image string, hc HostedClusterRef, destDir string) error {
+	if image == "" {
+		return fmt.Errorf("ERROR must-gather image is not set")
+	}
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return fmt.Errorf("ERROR failed to create the must-gather directory %s: %v", destDir, err)
+	}
+	args := []string{
+		"adm", "must-gather",
+		"--image=" + image,
+		"--dest-dir=" + destDir,
+		"--",
+		"/usr/bin/gather",
+		"hosted-cluster-namespace=" + hc.Namespace,
+		"hosted-cluster-name=" + hc.Name,
+	}
+	fmt.Printf("Running cmd %s %s\n", OcCLIName, strings.Join(args, " "))
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, OcCLIName, args...)
+	cmd.Stdout = ginkgo.GinkgoWriter
+	cmd.Stderr = io.MultiWriter(&stderr, ginkgo.GinkgoWriter)
+	startTime := time.Now()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ERROR %s adm must-gather failed after %s: %v: %s", OcCLIName, time.Since(startTime), err, stderr.String())
+	}
+	fmt.Printf("Time taken for must-gather to complete: %s\n", time.Since(startTime))
+	return nil
+}
+
+// FindHypershiftDump returns the path of the hypershift dump written by must-gather under destDir,
+// after checking the hostedcluster-<name> directory is next to it.
+func FindHypershiftDump(destDir string, hc HostedClusterRef) (string, error) {
+	var dumps []string
+	err := filepath.WalkDir(destDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == HypershiftDumpFile {
+			dumps = append(dumps, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("ERROR failed to read the must-gather directory %s: %v", destDir, err)
+	}
+	if len(dumps) == 0 {
+		return "", fmt.Errorf("ERROR %s not found under %s", HypershiftDumpFile, destDir)
+	}
+
+	dump := dumps[0]
+	hcDir := filepath.Join(filepath.Dir(dump), "hostedcluster-"+hc.Name)
+	if info, err := os.Stat(hcDir); err != nil || !info.IsDir() {
+		return dump, fmt.Errorf("ERROR must-gather directory %s not found", hcDir)
+	}
+	return dump, nil
+}
+
+// ParseMustGatherArchive reads a gzipped tar archive and lists its regular files.
+func ParseMustGatherArchive(archivePath string) (*MustGatherArchive, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to open %s: %v", archivePath, err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR %s is not a gzip archive: %v", archivePath, err)
+	}
+	defer gz.Close()
+
+	archive := &MustGatherArchive{Path: archivePath}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ERROR failed to read %s: %v", archivePath, err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			archive.Files = append(archive.Files, path.Clean(strings.TrimPrefix(header.Name, "./")))
+		}
+	}
+	sort.Strings(archive.Files)
+	return archive, nil
+}
+
+// Match returns the archive files matching the pattern at any directory level.
+func (a *MustGatherArchive) Match(pattern string) []string {
+	matches := []string{}
+	for _, file := range a.Files {
+		parts := strings.Split(file, "/")
+		for i := range parts {
+			if ok, _ := path.Match(pattern, strings.Join(parts[i:], "/")); ok {
+				matches = append(matches, file)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// ValidateHypershiftDump checks that the hypershift dump holds the HostedCluster and NodePool YAML,
+// the control plane pod logs and the namespace manifests of the HostedCluster. Every missing
+// piece is listed in the returned error.
+func ValidateHypershiftDump(archive *MustGatherArchive, hc HostedClusterRef) error {
+	missing := []string{}
+	for _, expectation := range hypershiftDumpExpectations(hc) {
+		found := false
+		for _, pattern := range expectation.patterns {
+			if len(archive.Match(pattern)) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, fmt.Sprintf("%s (%s)", expectation.description, strings.Join(expectation.patterns, " or ")))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("ERROR %s (%d files) is missing:\n  %s", archive.Path, len(archive.Files), strings.Join(missing, "\n  "))
+	}
+	fmt.Printf("Must-gather archive %s contains the expected content for %s/%s\n", archive.Path, hc.Namespace, hc.Name)
+	return nil
+}
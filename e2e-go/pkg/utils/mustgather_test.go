@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateHypershiftDump(t *testing.T) {
+	tests := []struct {
+		name        string
+		archive     string
+		hc          HostedClusterRef
+		wantMissing []string
+	}{
+		{
+			name:    "complete dump",
+			archive: "testdata/must-gather/hypershift-dump.tar.gz",
+			hc:      HostedClusterRef{Namespace: "clusters", Name: "acmqe-hc-1"},
+		},
+		{
+			name:        "dump without nodepools and logs",
+			archive:     "testdata/must-gather/hypershift-dump-incomplete.tar.gz",
+			hc:          HostedClusterRef{Namespace: "clusters", Name: "acmqe-hc-1"},
+			wantMissing: []string{"NodePool YAML in clusters", "control plane pod logs in clusters-acmqe-hc-1"},
+		},
+		{
+			name:    "dump of another hosted cluster",
+			archive: "testdata/must-gather/hypershift-dump.tar.gz",
+			hc:      HostedClusterRef{Namespace: "clusters", Name: "acmqe-hc-2"},
+			wantMissing: []string{
+				"HostedCluster clusters/acmqe-hc-2 YAML",
+				"control plane pod logs in clusters-acmqe-hc-2",
+				"namespace manifest of clusters-acmqe-hc-2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := ParseMustGatherArchive(tt.archive)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err = ValidateHypershiftDump(archive, tt.hc)
+			if len(tt.wantMissing) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error listing %v", tt.wantMissing)
+			}
+			for _, missing := range tt.wantMissing {
+				if !strings.Contains(err.Error(), missing) {
+					t.Errorf("expected error to mention %q, got %v", missing, err)
+				}
+			}
+			if strings.Count(err.Error(), "\n  ") != len(tt.wantMissing) {
+				t.Errorf("expected %d missing items, got %v", len(tt.wantMissing), err)
+			}
+		})
+	}
+}
+
+func TestParseMustGatherArchive(t *testing.T) {
+	archive, err := ParseMustGatherArchive("testdata/must-gather/hypershift-dump.tar.gz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(archive.Files) != 6 {
+		t.Errorf("expected 6 regular files, got %d: %v", len(archive.Files), archive.Files)
+	}
+	want := []string{
+		"hypershift-dump/namespaces/clusters-acmqe-hc-1/core/pods/logs/etcd-0-etcd-current.log",
+		"hypershift-dump/namespaces/clusters-acmqe-hc-1/core/pods/logs/kube-apiserver-7d9f-kube-apiserver-current.log",
+	}
+	if got := archive.Match("namespaces/clusters-acmqe-hc-1/core/pods/logs/*.log"); !reflect.DeepEqual(got, want) {
+		t.Errorf("match mismatch\n got: %v\nwant: %v", got, want)
+	}
+
+	notGzip := filepath.Join(t.TempDir(), HypershiftDumpFile)
+	if err := os.WriteFile(notGzip, []byte("not an archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseMustGatherArchive(notGzip); err == nil || !strings.Contains(err.Error(), "not a gzip archive") {
+		t.Errorf("expected a gzip error, got %v", err)
+	}
+}
+
+func TestFindHypershiftDump(t *testing.T) {
+	hc := HostedClusterRef{Namespace: "clusters", Name: "acmqe-hc-1"}
+	destDir := t.TempDir()
+	imageDir := filepath.Join(destDir, "quay-io-stolostron-must-gather-sha256-abc")
+	if err := os.MkdirAll(imageDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := FindHypershiftDump(destDir, hc); err == nil || !strings.Contains(err.Error(), "not found under") {
+		t.Errorf("expected the missing dump to be reported, got %v", err)
+	}
+
+	dump := filepath.Join(imageDir, HypershiftDumpFile)
+	if err := os.WriteFile(dump, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindHypershiftDump(destDir, hc); err == nil || !strings.Contains(err.Error(), "hostedcluster-acmqe-hc-1") {
+		t.Errorf("expected the missing hostedcluster directory to be reported, got %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(imageDir, "hostedcluster-acmqe-hc-1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FindHypershiftDump(destDir, hc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != dump {
+		t.Errorf("expected %s, got %s", dump, got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"sigs.k8s.io/yaml"
@@ -40,13 +41,41 @@ type TestOptionsT struct {
 	HostedCluster   Clusters            `json:"clusters"`
 	CloudConnection CloudConnection     `json:"credentials,omitempty"`
 	ClusterCurator  ClusterCuratorOpts  `json:"clustercurator,omitempty"`
+	MustGather      MustGatherOpts      `json:"mustgather,omitempty"`
+	Phases          PhaseOpts           `json:"phases,omitempty"`
+	Polarion        PolarionOpts        `json:"polarion,omitempty"`
+	FailureCatalog  string              `json:"failureCatalog,omitempty"` // known failure signatures; default resources/failure_catalog.yaml
+	QuarantineFile  string              `json:"quarantineFile,omitempty"` // known broken specs to skip; default resources/quarantine.yaml
+	DestroySelector string              `json:"destroySelector,omitempty"` // label selector of the clusters the destroy all specs destroy; default the owner's clusters, '*' for every cluster
 }
 
 // ClusterCuratorOpts holds options for ClusterCurator tests (e.g. channel-upgrade, control-plane-upgrade).
 type ClusterCuratorOpts struct {
-	Channel       string `json:"channel,omitempty"`
-	UpgradeType   string `json:"upgradeType,omitempty"`   // ControlPlane, NodePools, or empty for both (control-plane-upgrade test)
-	DesiredUpdate string `json:"desiredUpdate,omitempty"` // Target OCP version for upgrade (e.g. 4.19.22); maps to spec.upgrade.desiredUpdate
+	Channel        string `json:"channel,omitempty"`
+	UpgradeType    string `json:"upgradeType,omitempty"`    // ControlPlane, NodePools, or empty for both (control-plane-upgrade test)
+	DesiredUpdate  string `json:"desiredUpdate,omitempty"`  // Target OCP version for upgrade (e.g. 4.19.22); maps to spec.upgrade.desiredUpdate
+	TowerStubHost  string `json:"towerStubHost,omitempty"`  // URL of a running tower-stub the curator hooks use instead of AAP
+	TowerStubImage string `json:"towerStubImage,omitempty"` // tower-stub image deployed in the cluster namespace for the curator hooks instead of AAP
+	ScaleReplicas  int64  `json:"scaleReplicas,omitempty"`  // NodePool replicas of the curator-scale test; default one more than the current replicas
+}
+
+// MustGatherOpts holds options for the must-gather test.
+type MustGatherOpts struct {
+	Image string `json:"image,omitempty"` // must-gather image; the must-gather test is skipped when empty
+}
+
+// PhaseOpts holds the phase duration budgets of the create/destroy specs.
+type PhaseOpts struct {
+	Mode       string            `json:"mode,omitempty"`       // warn (default) or fail when a phase exceeds its threshold
+	Thresholds map[string]string `json:"thresholds,omitempty"` // phase or <platform>/<phase> to a duration (e.g. hcp-available: 20m)
+}
+
+// PolarionOpts holds the test run settings of the Polarion XUnit importer file.
+type PolarionOpts struct {
+	ProjectID         string `json:"projectID,omitempty"` // default RHACM4K; also the prefix of the test case IDs
+	TestRunTemplateID string `json:"testRunTemplateID,omitempty"`
+	TestRunTitle      string `json:"testRunTitle,omitempty"`
+	TestRunID         string `json:"testRunID,omitempty"`
 }
 
 // Hub ...
@@ -63,7 +92,8 @@ type Hub struct {
 // Clusters ...
 // Define the shape of clusters
 type Clusters struct {
-	AWS Cluster `json:"aws"`
+	AWS      Cluster `json:"aws"`
+	KubeVirt Cluster `json:"kubevirt,omitempty"`
 }
 
 // Cluster ...
@@ -91,6 +121,7 @@ type Cluster struct {
 	AWSCreds           string `json:"awsCreds,omitempty"`
 	GenerateSSHKey     bool   `json:"generateSSH,omitempty"`
 	InstanceType       string `json:"instanceType,omitempty"`
+	AdditionalLabels   string `json:"additionalLabels,omitempty"` // key=value pairs, comma separated, added to the created HostedClusters
 }
 
 // CloudConnection struct for bits having to do with Connections
@@ -186,25 +217,40 @@ func GetFileName(path, prefix string) ([]string, error) {
 	return m, nil
 }
 
-// GetClusterName returns the ClusterName for the supported providers suppled in the options.yaml file
+// GetClusterName returns the ClusterName for the supported providers suppled in the options.yaml file,
+// falling back to the latest cluster of the provider in the run state
 func GetClusterName(provider string) (string, error) {
 	if os.Getenv("HCP_CLUSTER_NAME") != "" {
 		return os.Getenv("HCP_CLUSTER_NAME"), nil
 	}
-	provider = strings.ToLower(provider)
-	switch provider {
+	name := ""
+	switch strings.ToLower(provider) {
 	case "aws":
-		return TestOptions.Options.HostedCluster.AWS.Name, nil
+		name = TestOptions.Options.HostedCluster.AWS.Name
+	case "kubevirt", "":
 	default:
 		return "", fmt.Errorf("options provider %s is not supported", provider)
 	}
+	// fall back to the cluster created earlier in the run
+	if cluster := GetRunState().Latest(provider); name == "" && cluster != nil {
+		fmt.Printf("Using cluster %s/%s from the run state %s\n", cluster.Namespace, cluster.Name, GetRunStateFile())
+		name = cluster.Name
+	}
+	return name, nil
 }
 
-// GetNamespace returns the namespace set in the env variable HCP_NAMESPACE or defaults to "clusters"
+// GetNamespace returns the namespace set in the env variable HCP_NAMESPACE, then the namespace of
+// the run state cluster of the provider when HCP_CLUSTER_NAME is not set or names it, or defaults
+// to "clusters"
 func GetNamespace(provider string) (string, error) {
 	if os.Getenv("HCP_NAMESPACE") != "" {
 		return os.Getenv("HCP_NAMESPACE"), nil
 	}
+	if cluster := GetRunState().Latest(provider); cluster != nil && cluster.Namespace != "" {
+		if name := os.Getenv("HCP_CLUSTER_NAME"); name == "" || name == cluster.Name {
+			return cluster.Namespace, nil
+		}
+	}
 	return "clusters", nil
 }
 
@@ -404,6 +450,19 @@ func GetClusterCuratorDesiredUpdate() string {
 	return TestOptions.Options.ClusterCurator.DesiredUpdate
 }
 
+// GetClusterCuratorScaleReplicas returns the replicas the curator-scale test scales the NodePools to.
+// Priority: HCP_SCALE_REPLICAS env, then options.clustercurator.scaleReplicas, else 0 (one more than the current replicas).
+func GetClusterCuratorScaleReplicas() (int64, error) {
+	if v := os.Getenv("HCP_SCALE_REPLICAS"); v != "" {
+		replicas, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || replicas < 0 {
+			return 0, fmt.Errorf("ERROR HCP_SCALE_REPLICAS must be a number of replicas, got %q", v)
+		}
+		return replicas, nil
+	}
+	return TestOptions.Options.ClusterCurator.ScaleReplicas, nil
+}
+
 // GetFIPSEnabled returns if we want to enable FIPS in cluster creation
 func GetFIPSEnabled() (string, error) {
 	if os.Getenv("FIPS_ENABLED") != "" {
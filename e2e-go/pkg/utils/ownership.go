@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// OwnerLabel and RunIDLabel identify the HostedClusters created by the suite, so stale ones
+	// can be found on a shared hub
+	OwnerLabel = "hypershift-e2e.open-cluster-management.io/owner"
+	RunIDLabel = "hypershift-e2e.open-cluster-management.io/run-id"
+	// CreatedAtAnnotation is the RFC 3339 time the suite created the HostedCluster
+	CreatedAtAnnotation = "hypershift-e2e.open-cluster-management.io/created-at"
+
+	RunIDEnv            = "RUN_ID"
+	AdditionalLabelsEnv = "HCP_ADDITIONAL_LABELS"
+)
+
+var (
+	runID     string
+	runIDOnce sync.Once
+
+	invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// GetRunID returns the ID of the test run in the following priority:
+// 1. RUN_ID env
+// 2. BUILD_ID env, set by Jenkins and Prow
+// 3. A timestamp generated once per process
+func GetRunID() string {
+	runIDOnce.Do(func() {
+		runID = os.Getenv(RunIDEnv)
+		if runID == "" {
+			runID = os.Getenv("BUILD_ID")
+		}
+		if runID == "" {
+			runID = time.Now().UTC().Format("20060102-150405")
+		}
+	})
+	return runID
+}
+
+// GetAdditionalLabels returns the extra labels of the HostedClusters created on the platform, as
+// comma separated key=value pairs, in the following priority:
+// 1. HCP_ADDITIONAL_LABELS env
+// 2. options.clusters.<platform>.additionalLabels, for aws and kubevirt
+func GetAdditionalLabels(platform string) (map[string]string, error) {
+	value := os.Getenv(AdditionalLabelsEnv)
+	if value == "" {
+		switch strings.ToLower(platform) {
+		case "aws":
+			value = TestOptions.Options.HostedCluster.AWS.AdditionalLabels
+		case "kubevirt":
+			value = TestOptions.Options.HostedCluster.KubeVirt.AdditionalLabels
+		default:
+			return nil, fmt.Errorf("ERROR no additional labels options for the platform %q", platform)
+		}
+	}
+	labels := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("ERROR invalid additional label %q, expected key=value", pair)
+		}
+		labels[key] = val
+	}
+	return labels, nil
+}
+
+// LabelValue turns s into a valid label value: characters that are not allowed are replaced by
+// dashes, the value is cut to 63 characters and starts and ends with an alphanumeric character.
+func LabelValue(s string) string {
+	value := invalidLabelValueChars.ReplaceAllString(s, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "-_.")
+}
+
+// OwnershipLabels returns the labels the suite puts on the HostedClusters it creates on the
+// platform: the additional labels, the owner and the run ID.
+func OwnershipLabels(platform string) (map[string]string, error) {
+	labels, err := GetAdditionalLabels(platform)
+	if err != nil {
+		return nil, err
+	}
+	labels[OwnerLabel] = LabelValue(GetOwner())
+	labels[RunIDLabel] = LabelValue(GetRunID())
+	return labels, nil
+}
+
+// LabelHostedClusterOwnership adds the ownership labels of its platform and the creation time
+// annotation to a HostedCluster created by the suite. The creation time already set on it is kept.
+func LabelHostedClusterOwnership(hubClientDynamic dynamic.Interface, namespace, name string) error {
+	client := hubClientDynamic.Resource(HostedClustersGVR).Namespace(namespace)
+	hc, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("ERROR failed to get the HostedCluster %s/%s: %v", namespace, name, err)
+	}
+	platform, _, _ := unstructured.NestedString(hc.Object, "spec", "platform", "type")
+	labels, err := OwnershipLabels(platform)
+	if err != nil {
+		return err
+	}
+	metadata := map[string]interface{}{"labels": labels}
+	if hc.GetAnnotations()[CreatedAtAnnotation] == "" {
+		createdAt := hc.GetCreationTimestamp().Time
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		metadata["annotations"] = map[string]string{CreatedAtAnnotation: createdAt.UTC().Format(time.RFC3339)}
+	}
+	// a merge patch does not conflict with the hypershift operator updating the HostedCluster
+	payload, err := json.Marshal(map[string]interface{}{"metadata": metadata})
+	if err != nil {
+		return fmt.Errorf("ERROR failed to marshal the HostedCluster labels: %v", err)
+	}
+	if _, err := client.Patch(context.TODO(), name, types.MergePatchType, payload, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("ERROR failed to label the HostedCluster %s/%s: %v", namespace, name, err)
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key+"="+labels[key])
+	}
+	sort.Strings(keys)
+	fmt.Printf("Cluster %s: labelled %s\n", name, strings.Join(keys, ","))
+	return nil
+}
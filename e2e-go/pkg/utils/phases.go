@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"github.com/onsi/gomega"
+)
+
+// Phases timed by the create and destroy specs.
+const (
+	PhaseHCPCLI          = "hcp-cli"
+	PhaseHCPAvailable    = "hcp-available"
+	PhaseImport          = "import"
+	PhaseAddonsAvailable = "addons-available"
+	PhaseHCPDestroyed    = "hcp-destroyed"
+	PhaseDetach          = "detach"
+)
+
+const (
+	// PhaseDurationsReportEntry is the report entry holding the PhaseResults of a spec.
+	PhaseDurationsReportEntry = "phase-durations"
+	// PhaseDurationsFile is the base name of the per run phase summary, written as .json and .csv.
+	PhaseDurationsFile = "phase-durations"
+
+	// PhaseThresholdWarn prints a warning when a phase exceeds its threshold.
+	PhaseThresholdWarn = "warn"
+	// PhaseThresholdFail fails the spec when a phase exceeds its threshold.
+	PhaseThresholdFail = "fail"
+)
+
+// PhaseThresholds are the phase duration budgets from the options file.
+type PhaseThresholds struct {
+	Mode       string
+	Thresholds map[string]time.Duration
+}
+
+// GetPhaseThresholds returns the phase duration budgets from options.phases.thresholds, keyed by
+// phase name or by <platform>/<phase> to override a phase for one platform.
+// Priority for the mode: PHASE_THRESHOLD_MODE env, then options.phases.mode, else "warn".
+func GetPhaseThresholds() (PhaseThresholds, error) {
+	thresholds := PhaseThresholds{Mode: os.Getenv("PHASE_THRESHOLD_MODE"), Thresholds: map[string]time.Duration{}}
+	if thresholds.Mode == "" {
+		thresholds.Mode = TestOptions.Options.Phases.Mode
+	}
+	if thresholds.Mode == "" {
+		thresholds.Mode = PhaseThresholdWarn
+	}
+	if thresholds.Mode != PhaseThresholdWarn && thresholds.Mode != PhaseThresholdFail {
+		return thresholds, fmt.Errorf("ERROR phase threshold mode %q is not one of %s, %s", thresholds.Mode, PhaseThresholdWarn, PhaseThresholdFail)
+	}
+	for phase, value := range TestOptions.Options.Phases.Thresholds {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return thresholds, fmt.Errorf("ERROR invalid threshold %q for phase %s: %v", value, phase, err)
+		}
+		thresholds.Thresholds[strings.ToLower(phase)] = d
+	}
+	return thresholds, nil
+}
+
+// For returns the threshold of the phase on the platform, or 0 if the phase has none.
+func (p PhaseThresholds) For(platform, phase string) time.Duration {
+	if d, ok := p.Thresholds[strings.ToLower(platform+"/"+phase)]; ok {
+		return d
+	}
+	return p.Thresholds[strings.ToLower(phase)]
+}
+
+// PhaseResult is the measured duration of one phase.
+type PhaseResult struct {
+	Spec             string    `json:"spec,omitempty"`
+	Platform         string    `json:"platform"`
+	Cluster          string    `json:"cluster"`
+	Phase            string    `json:"phase"`
+	Start            time.Time `json:"start"`
+	Seconds          float64   `json:"seconds"`
+	ThresholdSeconds float64   `json:"thresholdSeconds,omitempty"`
+	Exceeded         bool      `json:"exceeded,omitempty"`
+}
+
+func (r PhaseResult) String() string {
+	msg := fmt.Sprintf("%s took %s", r.Phase, secondsToDuration(r.Seconds))
+	if r.ThresholdSeconds > 0 {
+		msg += fmt.Sprintf(" (threshold %s)", secondsToDuration(r.ThresholdSeconds))
+	}
+	return msg
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second)
+}
+
+// PhaseTimer measures the named phases of a spec against their thresholds.
+type PhaseTimer struct {
+	platform   string
+	cluster    string
+	thresholds PhaseThresholds
+
+	mu      sync.Mutex
+	started map[string]time.Time
+	results []PhaseResult
+}
+
+// NewPhaseTimer returns a PhaseTimer for the HostedCluster on the platform.
+func NewPhaseTimer(platform, clusterName string, thresholds PhaseThresholds) *PhaseTimer {
+	return &PhaseTimer{platform: platform, cluster: clusterName, thresholds: thresholds, started: map[string]time.Time{}}
+}
+
+// Start starts timing the phase.
+func (t *PhaseTimer) Start(phase string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.started[phase] = time.Now()
+}
+
+// Stop stops timing a started phase and records it. It returns 0 if the phase was not started.
+func (t *PhaseTimer) Stop(phase string) time.Duration {
+	t.mu.Lock()
+	start, ok := t.started[phase]
+	delete(t.started, phase)
+	t.mu.Unlock()
+	if !ok {
+		fmt.Printf("Cluster %s: phase %s was stopped without being started\n", t.cluster, phase)
+		return 0
+	}
+	d := time.Since(start)
+	t.Record(phase, start, d)
+	return d
+}
+
+// Time runs fn as the phase.
+func (t *PhaseTimer) Time(phase string, fn func()) {
+	t.Start(phase)
+	defer t.Stop(phase)
+	fn()
+}
+
+// Record records a phase measured elsewhere, e.g. the duration of an hcp command.
+func (t *PhaseTimer) Record(phase string, start time.Time, d time.Duration) PhaseResult {
+	result := PhaseResult{
+		Platform: t.platform,
+		Cluster:  t.cluster,
+		Phase:    phase,
+		Start:    start,
+		Seconds:  d.Seconds(),
+	}
+	if threshold := t.thresholds.For(t.platform, phase); threshold > 0 {
+		result.ThresholdSeconds = threshold.Seconds()
+		result.Exceeded = d > threshold
+	}
+
+	t.mu.Lock()
+	t.results = append(t.results, result)
+	t.mu.Unlock()
+
+	fmt.Printf("Cluster %s: phase %s\n", t.cluster, result)
+	if result.Exceeded {
+		fmt.Printf("WARNING Cluster %s: phase %s exceeded its threshold\n", t.cluster, phase)
+	}
+	return result
+}
+
+// Results returns the recorded phases in the order they were recorded.
+func (t *PhaseTimer) Results() []PhaseResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]PhaseResult{}, t.results...)
+}
+
+// CheckThresholds returns an error listing every phase over its threshold.
+func (t *PhaseTimer) CheckThresholds() error {
+	exceeded := []string{}
+	for _, result := range t.Results() {
+		if result.Exceeded {
+			exceeded = append(exceeded, result.String())
+		}
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	return fmt.Errorf("ERROR cluster %s: %d phase(s) exceeded their threshold:\n  %s", t.cluster, len(exceeded), strings.Join(exceeded, "\n  "))
+}
+
+// StartPhaseTimer returns a PhaseTimer using the thresholds from the options file. When the spec
+// ends, the results are attached to the spec report and the thresholds are checked: in "fail" mode
+// a phase over its threshold fails the spec, in "warn" mode it is only reported.
+func StartPhaseTimer(platform, clusterName string) *PhaseTimer {
+	thresholds, err := GetPhaseThresholds()
+	gomega.Expect(err).ShouldNot(gomega.HaveOccurred())
+	timer := NewPhaseTimer(platform, clusterName, thresholds)
+
+	ginkgo.DeferCleanup(func() {
+		results := timer.Results()
+		if len(results) == 0 {
+			return
+		}
+		ginkgo.AddReportEntry(PhaseDurationsReportEntry, results, ginkgo.ReportEntryVisibilityNever)
+		if err := timer.CheckThresholds(); err != nil {
+			if thresholds.Mode == PhaseThresholdFail {
+				ginkgo.Fail(err.Error())
+			}
+			fmt.Printf("WARNING %v\n", err)
+		}
+	})
+	return timer
+}
+
+// PhaseResultsFromReport returns the phases recorded by every spec of the report.
+func PhaseResultsFromReport(report types.Report) []PhaseResult {
+	all := []PhaseResult{}
+	for _, spec := range report.SpecReports {
+		for _, entry := range spec.ReportEntries {
+			if entry.Name != PhaseDurationsReportEntry {
+				continue
+			}
+			results, ok := entry.GetRawValue().([]PhaseResult)
+			if !ok && json.Unmarshal([]byte(entry.Value.AsJSON), &results) != nil {
+				continue
+			}
+			for _, result := range results {
+				result.Spec = spec.FullText()
+				all = append(all, result)
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Start.Before(all[j].Start) })
+	return all
+}
+
+// WritePhaseSummary writes the phases to phase-durations.json and phase-durations.csv in dir
+// and returns the paths.
+func WritePhaseSummary(results []PhaseResult, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ERROR failed to create the directory %s: %v", dir, err)
+	}
+
+	jsonPath := filepath.Join(dir, PhaseDurationsFile+".json")
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to encode the phase durations: %v", err)
+	}
+	if err := os.WriteFile(jsonPath, data, 0o644); err != nil {
+		return nil, fmt.Errorf("ERROR failed to write %s: %v", jsonPath, err)
+	}
+
+	csvPath := filepath.Join(dir, PhaseDurationsFile+".csv")
+	f, err := os.Create(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to create %s: %v", csvPath, err)
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"spec", "platform", "cluster", "phase", "start", "seconds", "threshold_seconds", "exceeded"})
+	for _, r := range results {
+		threshold := ""
+		if r.ThresholdSeconds > 0 {
+			threshold = strconv.FormatFloat(r.ThresholdSeconds, 'f', 0, 64)
+		}
+		_ = w.Write([]string{
+			r.Spec, r.Platform, r.Cluster, r.Phase, r.Start.Format(time.RFC3339),
+			strconv.FormatFloat(r.Seconds, 'f', 1, 64), threshold, strconv.FormatBool(r.Exceeded),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("ERROR failed to write %s: %v", csvPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("ERROR failed to write %s: %v", csvPath, err)
+	}
+	return []string{jsonPath, csvPath}, nil
+}
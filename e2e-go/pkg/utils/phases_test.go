@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+func TestGetPhaseThresholds(t *testing.T) {
+	saved := TestOptions
+	t.Cleanup(func() { TestOptions = saved })
+
+	tests := []struct {
+		name      string
+		envMode   string
+		opts      PhaseOpts
+		wantMode  string
+		wantErr   string
+		platform  string
+		phase     string
+		threshold time.Duration
+	}{
+		{
+			name:     "defaults to warn without thresholds",
+			wantMode: PhaseThresholdWarn,
+			platform: TYPE_AWS,
+			phase:    PhaseHCPAvailable,
+		},
+		{
+			name:      "phase threshold",
+			opts:      PhaseOpts{Mode: PhaseThresholdFail, Thresholds: map[string]string{"hcp-available": "20m"}},
+			wantMode:  PhaseThresholdFail,
+			platform:  TYPE_AWS,
+			phase:     PhaseHCPAvailable,
+			threshold: 20 * time.Minute,
+		},
+		{
+			name:      "platform threshold overrides the phase threshold",
+			envMode:   PhaseThresholdFail,
+			opts:      PhaseOpts{Thresholds: map[string]string{"hcp-available": "20m", "kubevirt/hcp-available": "25m"}},
+			wantMode:  PhaseThresholdFail,
+			platform:  TYPE_KUBEVIRT,
+			phase:     PhaseHCPAvailable,
+			threshold: 25 * time.Minute,
+		},
+		{
+			name:    "invalid duration",
+			opts:    PhaseOpts{Thresholds: map[string]string{"import": "ten minutes"}},
+			wantErr: "invalid threshold",
+		},
+		{
+			name:    "invalid mode",
+			opts:    PhaseOpts{Mode: "error"},
+			wantErr: "is not one of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PHASE_THRESHOLD_MODE", tt.envMode)
+			TestOptions.Options.Phases = tt.opts
+			thresholds, err := GetPhaseThresholds()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if thresholds.Mode != tt.wantMode {
+				t.Errorf("expected mode %s, got %s", tt.wantMode, thresholds.Mode)
+			}
+			if got := thresholds.For(tt.platform, tt.phase); got != tt.threshold {
+				t.Errorf("expected threshold %s, got %s", tt.threshold, got)
+			}
+		})
+	}
+}
+
+func TestPhaseTimer(t *testing.T) {
+	thresholds := PhaseThresholds{Mode: PhaseThresholdWarn, Thresholds: map[string]time.Duration{
+		PhaseHCPCLI:       time.Minute,
+		PhaseHCPAvailable: time.Millisecond,
+	}}
+	timer := NewPhaseTimer(TYPE_AWS, "acmqe-hc-1", thresholds)
+
+	timer.Record(PhaseHCPCLI, time.Now(), 30*time.Second)
+	timer.Time(PhaseHCPAvailable, func() { time.Sleep(5 * time.Millisecond) })
+	timer.Start(PhaseImport)
+	if d := timer.Stop(PhaseImport); d <= 0 {
+		t.Errorf("expected a positive duration, got %s", d)
+	}
+	if d := timer.Stop(PhaseAddonsAvailable); d != 0 {
+		t.Errorf("expected a phase that was not started to be ignored, got %s", d)
+	}
+
+	results := timer.Results()
+	if len(results) != 3 {
+		t.Fatalf("expected 3 phases, got %v", results)
+	}
+	if results[0].Exceeded || results[0].ThresholdSeconds != 60 {
+		t.Errorf("expected hcp-cli within its threshold, got %+v", results[0])
+	}
+	if !results[1].Exceeded {
+		t.Errorf("expected hcp-available over its threshold, got %+v", results[1])
+	}
+	if results[2].Exceeded || results[2].ThresholdSeconds != 0 {
+		t.Errorf("expected import without a threshold, got %+v", results[2])
+	}
+
+	err := timer.CheckThresholds()
+	if err == nil || !strings.Contains(err.Error(), "1 phase(s)") || !strings.Contains(err.Error(), PhaseHCPAvailable+" took") {
+		t.Errorf("expected hcp-available to be reported, got %v", err)
+	}
+}
+
+func TestWritePhaseSummary(t *testing.T) {
+	start := time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)
+	create := []PhaseResult{
+		{Platform: TYPE_AWS, Cluster: "acmqe-hc-1", Phase: PhaseHCPCLI, Start: start, Seconds: 95.5, ThresholdSeconds: 600},
+		{Platform: TYPE_AWS, Cluster: "acmqe-hc-1", Phase: PhaseHCPAvailable, Start: start.Add(2 * time.Minute), Seconds: 1500, ThresholdSeconds: 1200, Exceeded: true},
+	}
+	encoded, err := json.Marshal([]PhaseResult{{Platform: TYPE_AWS, Cluster: "acmqe-hc-1", Phase: PhaseDetach, Start: start.Add(time.Hour), Seconds: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	destroy := types.WrapEntryValue(nil)
+	destroy.AsJSON = string(encoded)
+
+	report := types.Report{SpecReports: types.SpecReports{
+		{
+			LeafNodeText:  "Destroy a AWS hosted cluster on the hub",
+			ReportEntries: types.ReportEntries{{Name: PhaseDurationsReportEntry, Value: destroy}},
+		},
+		{
+			LeafNodeText: "Creates a FIPS AWS Hosted Cluster using STS Creds",
+			ReportEntries: types.ReportEntries{
+				{Name: HostedClusterReportEntry, Value: types.WrapEntryValue("clusters/acmqe-hc-1")},
+				{Name: PhaseDurationsReportEntry, Value: types.WrapEntryValue(create)},
+			},
+		},
+	}}
+
+	results := PhaseResultsFromReport(report)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 phases, got %v", results)
+	}
+	if results[0].Phase != PhaseHCPCLI || results[0].Spec != "Creates a FIPS AWS Hosted Cluster using STS Creds" || results[2].Phase != PhaseDetach {
+		t.Errorf("expected the phases in start order with their spec, got %+v", results)
+	}
+
+	files, err := WritePhaseSummary(results, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []PhaseResult
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded) != 3 {
+		t.Errorf("expected 3 phases in %s, got %v (%v)", files[0], decoded, err)
+	}
+
+	f, err := os.Open(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Creates a FIPS AWS Hosted Cluster using STS Creds", TYPE_AWS, "acmqe-hc-1", PhaseHCPAvailable, "2024-04-01T10:02:00Z", "1500.0", "1200", "true"}
+	if len(rows) != 4 || strings.Join(rows[2], ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected csv rows: %v", rows)
+	}
+}
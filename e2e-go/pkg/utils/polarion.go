@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+const (
+	PolarionReportFileEnv    = "POLARION_REPORT_FILE"
+	defaultPolarionProjectID = "RHACM4K"
+)
+
+// PolarionTestSuites is a Polarion XUnit importer document. Test cases are matched to Polarion
+// work items by their polarion-testcase-id property.
+type PolarionTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Properties JUnitProperties  `xml:"properties"`
+	Warning    string           `xml:",comment"`
+	TestSuites []JUnitTestSuite `xml:"testsuite"`
+}
+
+// GetPolarionOptions returns the Polarion test run settings.
+// Priority: POLARION_PROJECT_ID, POLARION_TESTRUN_TEMPLATE_ID, POLARION_TESTRUN_TITLE and
+// POLARION_TESTRUN_ID env, then options.polarion, else the RHACM4K project and no test run settings.
+func GetPolarionOptions() PolarionOpts {
+	opts := TestOptions.Options.Polarion
+	for env, field := range map[string]*string{
+		"POLARION_PROJECT_ID":          &opts.ProjectID,
+		"POLARION_TESTRUN_TEMPLATE_ID": &opts.TestRunTemplateID,
+		"POLARION_TESTRUN_TITLE":       &opts.TestRunTitle,
+		"POLARION_TESTRUN_ID":          &opts.TestRunID,
+	} {
+		if v := os.Getenv(env); v != "" {
+			*field = v
+		}
+	}
+	if opts.ProjectID == "" {
+		opts.ProjectID = defaultPolarionProjectID
+	}
+	return opts
+}
+
+// PolarionTestCaseIDs returns the test case IDs of the project (e.g. RHACM4K-39628) found in the
+// spec text and labels, in the order they appear.
+func PolarionTestCaseIDs(spec types.SpecReport, projectID string) []string {
+	pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(projectID) + `-\d+\b`)
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, text := range append([]string{spec.FullText()}, spec.Labels()...) {
+		for _, match := range pattern.FindAllString(text, -1) {
+			id := strings.ToUpper(match)
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// GeneratePolarionReport writes the report to dst as a Polarion XUnit importer file with one test
+// case per test case ID. A spec with several IDs is reported once per ID. Specs without an ID are
+// left out, listed in a comment at the top of the file and returned.
+func GeneratePolarionReport(report types.Report, dst string, opts PolarionOpts) ([]string, error) {
+	suite := JUnitTestSuite{
+		Name:      report.SuiteDescription,
+		Package:   report.SuitePath,
+		Time:      report.RunTime.Seconds(),
+		Timestamp: report.StartTime.Format("2006-01-02T15:04:05"),
+	}
+	withoutID := []string{}
+	for _, spec := range report.SpecReports {
+		if spec.FullText() == "" {
+			continue
+		}
+		ids := PolarionTestCaseIDs(spec, opts.ProjectID)
+		if len(ids) == 0 {
+			withoutID = append(withoutID, spec.FullText())
+			continue
+		}
+		for _, id := range ids {
+			test := newJUnitTestCase(report, spec)
+			test.Properties = &JUnitProperties{Properties: []JUnitProperty{{Name: "polarion-testcase-id", Value: id}}}
+			suite.addTestCase(spec, test)
+		}
+	}
+
+	polarion := PolarionTestSuites{TestSuites: []JUnitTestSuite{suite}}
+	for _, property := range []JUnitProperty{
+		{Name: "polarion-project-id", Value: opts.ProjectID},
+		{Name: "polarion-lookup-method", Value: "id"},
+		{Name: "polarion-testrun-template-id", Value: opts.TestRunTemplateID},
+		{Name: "polarion-testrun-title", Value: opts.TestRunTitle},
+		{Name: "polarion-testrun-id", Value: opts.TestRunID},
+	} {
+		if property.Value != "" {
+			polarion.Properties.Properties = append(polarion.Properties.Properties, property)
+		}
+	}
+	if len(withoutID) > 0 {
+		warning := fmt.Sprintf("WARNING %d spec(s) without a %s test case ID:\n  %s\n", len(withoutID), opts.ProjectID, strings.Join(withoutID, "\n  "))
+		// "--" is not allowed in an XML comment
+		polarion.Warning = " " + strings.ReplaceAll(warning, "--", "- -")
+		fmt.Print(warning)
+	}
+
+	if err := writeJUnitFile(dst, polarion); err != nil {
+		return withoutID, err
+	}
+	return withoutID, nil
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+func TestPolarionTestCaseIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		spec types.SpecReport
+		want []string
+	}{
+		{
+			name: "id in the spec text",
+			spec: types.SpecReport{
+				ContainerHierarchyTexts: []string{"Hypershift Add-on Metrics Tests:"},
+				LeafNodeText:            "Hypershift: RHACM4K-39628: ServiceMonitor is correctly deployed",
+			},
+			want: []string{"RHACM4K-39628"},
+		},
+		{
+			name: "id in the container text and the labels",
+			spec: types.SpecReport{
+				ContainerHierarchyTexts:  []string{"RHACM4K-21843: Hypershift: Hypershift Addon should detect changes in S3 secret"},
+				ContainerHierarchyLabels: [][]string{{"e2e", "RHACM4K-21843", "rhacm4k-21844"}},
+				LeafNodeText:             "should re-install the hypershift operator",
+			},
+			want: []string{"RHACM4K-21843", "RHACM4K-21844"},
+		},
+		{
+			name: "no id",
+			spec: types.SpecReport{LeafNodeText: "Creates a FIPS AWS Hosted Cluster using STS Creds", LeafNodeLabels: []string{"create"}},
+			want: []string{},
+		},
+		{
+			name: "id of another project",
+			spec: types.SpecReport{LeafNodeText: "OCPBUGS-1234: RHACM4K-1234x is not an id"},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PolarionTestCaseIDs(tt.spec, "RHACM4K"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGeneratePolarionReport(t *testing.T) {
+	t.Setenv("POLARION_TESTRUN_TITLE", "MCE 2.6 hypershift nightly")
+	opts := GetPolarionOptions()
+	if opts.ProjectID != "RHACM4K" {
+		t.Fatalf("expected the default project, got %s", opts.ProjectID)
+	}
+
+	report := types.Report{SuiteDescription: "Hypershift E2e Suite", SpecReports: types.SpecReports{
+		{LeafNodeType: types.NodeTypeIt, LeafNodeText: "Hypershift: RHACM4K-39628: ServiceMonitor is correctly deployed", State: types.SpecStatePassed},
+		{LeafNodeType: types.NodeTypeIt, LeafNodeText: "RHACM4K-39627 and RHACM4K-39474: metrics", State: types.SpecStateFailed, Failure: types.Failure{Message: "no metrics"}},
+		{LeafNodeType: types.NodeTypeIt, LeafNodeText: "Creates a FIPS AWS Hosted Cluster using STS Creds", State: types.SpecStateSkipped},
+		{LeafNodeType: types.NodeTypeBeforeSuite, State: types.SpecStatePassed},
+	}}
+
+	dst := filepath.Join(t.TempDir(), "polarion.xml")
+	withoutID, err := GeneratePolarionReport(report, dst, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(withoutID, []string{"Creates a FIPS AWS Hosted Cluster using STS Creds"}) {
+		t.Errorf("unexpected specs without id: %v", withoutID)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<!-- WARNING 1 spec(s) without a RHACM4K test case ID:\n  Creates a FIPS AWS Hosted Cluster using STS Creds") {
+		t.Errorf("expected the specs without id in a comment:\n%s", data)
+	}
+
+	var polarion PolarionTestSuites
+	if err := xml.Unmarshal(data, &polarion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantProperties := []JUnitProperty{
+		{Name: "polarion-project-id", Value: "RHACM4K"},
+		{Name: "polarion-lookup-method", Value: "id"},
+		{Name: "polarion-testrun-title", Value: "MCE 2.6 hypershift nightly"},
+	}
+	if !reflect.DeepEqual(polarion.Properties.Properties, wantProperties) {
+		t.Errorf("unexpected properties: %v", polarion.Properties.Properties)
+	}
+
+	suite := polarion.TestSuites[0]
+	if suite.Tests != 3 || suite.Failures != 2 {
+		t.Errorf("expected 3 test cases with 2 failures, got %d and %d", suite.Tests, suite.Failures)
+	}
+	ids := []string{}
+	for _, test := range suite.TestCases {
+		ids = append(ids, test.Properties.Properties[0].Value)
+	}
+	if !reflect.DeepEqual(ids, []string{"RHACM4K-39628", "RHACM4K-39627", "RHACM4K-39474"}) {
+		t.Errorf("unexpected test case ids: %v", ids)
+	}
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/ginkgo/v2/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	QuarantineFileEnv         = "QUARANTINE_FILE"
+	defaultQuarantineFilePath = "../resources/quarantine.yaml"
+	quarantineDateLayout      = "2006-01-02"
+)
+
+// QuarantineEntry skips the specs matching Spec or one of Labels while the entry has not expired,
+// on the MCE versions in MCEVersions.
+type QuarantineEntry struct {
+	Spec        string   `json:"spec,omitempty"`        // regex on the full text of the spec
+	Labels      []string `json:"labels,omitempty"`      // labels of the spec, case insensitive
+	Reason      string   `json:"reason"`                // why the spec is quarantined
+	Issue       string   `json:"issue"`                 // tracking issue, e.g. ACM-12345
+	MCEVersions string   `json:"mceVersions,omitempty"` // semver constraint, e.g. '>= 2.6.0, < 2.6.3'; all versions when empty
+	Expires     string   `json:"expires,omitempty"`     // YYYY-MM-DD; the entry is ignored with a warning from this day on
+
+	spec        *regexp.Regexp
+	mceVersions *semver.Constraints
+	expires     time.Time
+}
+
+// Quarantine is the list of known broken specs.
+type Quarantine struct {
+	Entries []QuarantineEntry `json:"quarantine"`
+}
+
+// LoadQuarantine reads the quarantine from a YAML file.
+func LoadQuarantine(path string) (*Quarantine, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to read the quarantine file %s: %v", path, err)
+	}
+	quarantine, err := ParseQuarantine(data)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR invalid quarantine file %s: %v", path, err)
+	}
+	return quarantine, nil
+}
+
+// ParseQuarantine parses and validates a YAML quarantine.
+func ParseQuarantine(data []byte) (*Quarantine, error) {
+	quarantine := &Quarantine{}
+	if err := yaml.UnmarshalStrict(data, quarantine); err != nil {
+		return nil, err
+	}
+	for i := range quarantine.Entries {
+		entry := &quarantine.Entries[i]
+		name := fmt.Sprintf("entry %d", i)
+		if entry.Issue != "" {
+			name = fmt.Sprintf("entry %d (%s)", i, entry.Issue)
+		}
+		if entry.Spec == "" && len(entry.Labels) == 0 {
+			return nil, fmt.Errorf("%s has no spec or labels to match", name)
+		}
+		if entry.Reason == "" || entry.Issue == "" {
+			return nil, fmt.Errorf("%s must have a reason and a tracking issue", name)
+		}
+		var err error
+		if entry.Spec != "" {
+			if entry.spec, err = regexp.Compile(entry.Spec); err != nil {
+				return nil, fmt.Errorf("%s: invalid spec pattern: %v", name, err)
+			}
+		}
+		if entry.MCEVersions != "" {
+			if entry.mceVersions, err = semver.NewConstraint(entry.MCEVersions); err != nil {
+				return nil, fmt.Errorf("%s: invalid MCE version range %q: %v", name, entry.MCEVersions, err)
+			}
+		}
+		if entry.Expires != "" {
+			if entry.expires, err = time.Parse(quarantineDateLayout, entry.Expires); err != nil {
+				return nil, fmt.Errorf("%s: invalid expiry date %q, expected YYYY-MM-DD", name, entry.Expires)
+			}
+		}
+	}
+	return quarantine, nil
+}
+
+var (
+	quarantine     *Quarantine
+	quarantineOnce sync.Once
+)
+
+// GetQuarantine returns the quarantine, loaded once.
+// Priority: QUARANTINE_FILE env, then options.quarantineFile, else resources/quarantine.yaml.
+// A missing default file means no spec is quarantined; any other error is reported and also
+// quarantines nothing. Expired entries are printed as warnings when it is loaded.
+func GetQuarantine() *Quarantine {
+	quarantineOnce.Do(func() {
+		path := os.Getenv(QuarantineFileEnv)
+		if path == "" {
+			path = TestOptions.Options.QuarantineFile
+		}
+		if path == "" {
+			path = defaultQuarantineFilePath
+		}
+		q := &Quarantine{}
+		if _, err := os.Stat(path); path != defaultQuarantineFilePath || !errors.Is(err, fs.ErrNotExist) {
+			if q, err = LoadQuarantine(path); err != nil {
+				fmt.Printf("WARNING no spec is quarantined: %v\n", err)
+				q = &Quarantine{}
+			}
+		}
+		for _, entry := range q.Expired(time.Now()) {
+			fmt.Printf("WARNING quarantine of %s for %s expired on %s and is ignored; fix the spec or extend the entry\n",
+				entry.pattern(), entry.Issue, entry.Expires)
+		}
+		quarantine = q
+	})
+	return quarantine
+}
+
+// Expired returns the entries that expired at now.
+func (q *Quarantine) Expired(now time.Time) []QuarantineEntry {
+	expired := []QuarantineEntry{}
+	for _, entry := range q.Entries {
+		if entry.expired(now) {
+			expired = append(expired, entry)
+		}
+	}
+	return expired
+}
+
+// Match returns the entry quarantining the spec on the MCE version, or nil. An empty mceVersion
+// matches only the entries without a version range.
+func (q *Quarantine) Match(spec types.SpecReport, mceVersion string, now time.Time) *QuarantineEntry {
+	for i := range q.Entries {
+		entry := &q.Entries[i]
+		if !entry.expired(now) && entry.matchesSpec(spec) && entry.matchesMCEVersion(mceVersion) {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (e QuarantineEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+func (e QuarantineEntry) matchesSpec(spec types.SpecReport) bool {
+	if e.spec != nil && e.spec.MatchString(spec.FullText()) {
+		return true
+	}
+	for _, label := range e.Labels {
+		for _, specLabel := range spec.Labels() {
+			if strings.EqualFold(label, specLabel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e QuarantineEntry) matchesMCEVersion(mceVersion string) bool {
+	if e.mceVersions == nil {
+		return true
+	}
+	version, err := semver.NewVersion(mceVersion)
+	if err != nil {
+		return false
+	}
+	// downstream builds carry a pre-release suffix, which constraints would otherwise exclude
+	release, _ := version.SetPrerelease("")
+	return e.mceVersions.Check(&release)
+}
+
+func (e QuarantineEntry) pattern() string {
+	patterns := []string{}
+	if e.Spec != "" {
+		patterns = append(patterns, fmt.Sprintf("spec %q", e.Spec))
+	}
+	if len(e.Labels) > 0 {
+		patterns = append(patterns, "labels "+strings.Join(e.Labels, ", "))
+	}
+	return strings.Join(patterns, " or ")
+}
+
+// SkipMessage is the reason given to ginkgo.Skip, recorded in the JUnit skipped message.
+func (e QuarantineEntry) SkipMessage() string {
+	return fmt.Sprintf("quarantined: %s (%s)", e.Reason, e.Issue)
+}
+
+// SkipIfQuarantined skips the current spec when the quarantine matches it on the MCE version.
+// Call it from a BeforeEach.
+func SkipIfQuarantined(mceVersion string) {
+	if entry := GetQuarantine().Match(ginkgo.CurrentSpecReport(), mceVersion, time.Now()); entry != nil {
+		ginkgo.Skip(entry.SkipMessage())
+	}
+}
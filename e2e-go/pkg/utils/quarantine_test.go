@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+const testQuarantine = `
+quarantine:
+  - spec: 'Console CLI Download .* hcp CLI link'
+    reason: 'hcp CLI download link is missing from the console'
+    issue: 'ACM-10001'
+  - labels: ['Nodepool-Upgrade']
+    reason: 'NodePools do not reach the desired version'
+    issue: 'ACM-10002'
+    mceVersions: '>= 2.6.0, < 2.6.3'
+  - labels: ['metrics']
+    reason: 'ServiceMonitor is not created'
+    issue: 'ACM-10003'
+    expires: '2024-04-01'
+`
+
+func TestQuarantineMatch(t *testing.T) {
+	quarantine, err := ParseQuarantine([]byte(testQuarantine))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		spec       types.SpecReport
+		mceVersion string
+		wantIssue  string
+	}{
+		{
+			name:      "spec text",
+			spec:      types.SpecReport{ContainerHierarchyTexts: []string{"Console CLI Download"}, LeafNodeText: "should have the hcp CLI link"},
+			wantIssue: "ACM-10001",
+		},
+		{
+			name:       "label in the MCE version range",
+			spec:       types.SpecReport{LeafNodeText: "upgrades the NodePools", LeafNodeLabels: []string{"nodepool-upgrade"}},
+			mceVersion: "2.6.2-DOWNSTREAM-2024-03-28",
+			wantIssue:  "ACM-10002",
+		},
+		{
+			name:       "label outside the MCE version range",
+			spec:       types.SpecReport{LeafNodeText: "upgrades the NodePools", LeafNodeLabels: []string{"nodepool-upgrade"}},
+			mceVersion: "2.6.3",
+		},
+		{
+			name: "unknown MCE version",
+			spec: types.SpecReport{LeafNodeText: "upgrades the NodePools", LeafNodeLabels: []string{"nodepool-upgrade"}},
+		},
+		{
+			name: "expired entry",
+			spec: types.SpecReport{LeafNodeText: "ServiceMonitor is correctly deployed", LeafNodeLabels: []string{"metrics"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := quarantine.Match(tt.spec, tt.mceVersion, now)
+			switch {
+			case tt.wantIssue == "" && entry != nil:
+				t.Errorf("expected the spec to run, got quarantined by %s", entry.Issue)
+			case tt.wantIssue != "" && (entry == nil || entry.Issue != tt.wantIssue):
+				t.Errorf("expected the spec to be quarantined by %s, got %+v", tt.wantIssue, entry)
+			}
+		})
+	}
+
+	expired := quarantine.Expired(now)
+	if len(expired) != 1 || expired[0].Issue != "ACM-10003" {
+		t.Errorf("expected the metrics entry to be expired, got %+v", expired)
+	}
+	if msg := quarantine.Entries[0].SkipMessage(); msg != "quarantined: hcp CLI download link is missing from the console (ACM-10001)" {
+		t.Errorf("unexpected skip message: %s", msg)
+	}
+}
+
+func TestParseQuarantineInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		quarantine string
+		wantErr    string
+	}{
+		{name: "nothing to match", quarantine: "quarantine:\n- reason: broken\n  issue: ACM-1\n", wantErr: "no spec or labels"},
+		{name: "no issue", quarantine: "quarantine:\n- labels: [e2e]\n  reason: broken\n", wantErr: "must have a reason and a tracking issue"},
+		{name: "invalid range", quarantine: "quarantine:\n- labels: [e2e]\n  reason: broken\n  issue: ACM-1\n  mceVersions: 'after 2.6'\n", wantErr: "invalid MCE version range"},
+		{name: "invalid date", quarantine: "quarantine:\n- labels: [e2e]\n  reason: broken\n  issue: ACM-1\n  expires: '30/06/2024'\n", wantErr: "invalid expiry date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseQuarantine([]byte(tt.quarantine)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := LoadQuarantine(defaultQuarantineFilePath); err != nil {
+		t.Errorf("expected the default quarantine file to be valid, got %v", err)
+	}
+}
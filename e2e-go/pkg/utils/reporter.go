@@ -3,7 +3,9 @@ package utils
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -30,10 +32,37 @@ type JUnitTestSuite struct {
 	Time float64 `xml:"time,attr"`
 	// Timestamp is the ISO 8601 formatted start-time of the suite - maps onto Report.StartTime
 	Timestamp string `xml:"timestamp,attr"`
+	// Properties holds the run metadata - see RunMetadata
+	Properties *JUnitProperties `xml:"properties,omitempty"`
 	// TestCases capture the individual specs
 	TestCases []JUnitTestCase `xml:"testcase"`
 }
 
+type JUnitTestSuites struct {
+	XMLName xml.Name `xml:"testsuites"`
+	// Name is the name of the merged report
+	Name string `xml:"name,attr,omitempty"`
+	// Tests, Disabled, Skipped, Errors and Failures are the sums over all test suites
+	Tests    int `xml:"tests,attr"`
+	Disabled int `xml:"disabled,attr"`
+	Skipped  int `xml:"skipped,attr"`
+	Errors   int `xml:"errors,attr"`
+	Failures int `xml:"failures,attr"`
+	// Time is the sum of the time in seconds of all test suites
+	Time float64 `xml:"time,attr"`
+	// TestSuites are the merged test suites, in the order of the merged files
+	TestSuites []JUnitTestSuite `xml:"testsuite"`
+}
+
+type JUnitProperties struct {
+	Properties []JUnitProperty `xml:"property"`
+}
+
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
 type JUnitTestCase struct {
 	// Name maps onto the full text of the spec - equivalent to "[SpecReport.LeafNodeType] SpecReport.FullText()"
 	Name string `xml:"name,attr"`
@@ -43,6 +72,8 @@ type JUnitTestCase struct {
 	Status string `xml:"status,attr"`
 	// Time is the time in seconds to execute the spec - maps onto SpecReport.RunTime
 	Time float64 `xml:"time,attr"`
+	// Properties holds test case properties, e.g. the Polarion test case id
+	Properties *JUnitProperties `xml:"properties,omitempty"`
 	// Skipped is populated with a message if the test was skipped or pending
 	Skipped *JUnitSkipped `xml:"skipped,omitempty"`
 	// Error is populated if the test panicked or was interrupted
@@ -78,89 +109,228 @@ type JUnitFailure struct {
 	Description string `xml:",chardata"`
 }
 
-func GenerateJUnitReport(report types.Report, dst string) error {
+// GenerateJUnitReport writes the report to dst as a JUnit <testsuite>. The properties are added
+// to the suite <properties>, skipping the ones with an empty value.
+func GenerateJUnitReport(report types.Report, dst string, properties ...JUnitProperty) error {
 	suite := JUnitTestSuite{
 		Name:      report.SuiteDescription,
 		Package:   report.SuitePath,
 		Time:      report.RunTime.Seconds(),
 		Timestamp: report.StartTime.Format("2006-01-02T15:04:05"),
 	}
+	for _, property := range properties {
+		if property.Value == "" {
+			continue
+		}
+		if suite.Properties == nil {
+			suite.Properties = &JUnitProperties{}
+		}
+		suite.Properties.Properties = append(suite.Properties.Properties, property)
+	}
 	for _, spec := range report.SpecReports {
 		if spec.FullText() != "" {
-			name := spec.FullText()
-			labels := spec.Labels()
-			if len(labels) > 0 {
-				name = name + " [" + strings.Join(labels, ", ") + "]"
-			}
+			suite.addTestCase(spec, newJUnitTestCase(report, spec))
+		}
+	}
 
-			test := JUnitTestCase{
-				Name:      name,
-				Classname: report.SuiteDescription,
-				Status:    spec.State.String(),
-				Time:      spec.RunTime.Seconds(),
-				SystemOut: systemOutForUnstructureReporters(spec),
-				SystemErr: spec.CapturedGinkgoWriterOutput,
-			}
+	return writeJUnitFile(dst, []JUnitTestSuite{suite})
+}
 
-			suite.Tests += 1
-
-			switch spec.State {
-			case types.SpecStateSkipped:
-				message := "skipped"
-				if spec.Failure.Message != "" {
-					message += " - " + spec.Failure.Message
-				}
-				test.Skipped = &JUnitSkipped{Message: message}
-				suite.Skipped += 1
-			case types.SpecStatePending:
-				test.Skipped = &JUnitSkipped{Message: "pending"}
-				suite.Disabled += 1
-			case types.SpecStateFailed:
-				test.Failure = &JUnitFailure{
-					Message:     spec.Failure.Message,
-					Type:        "failed",
-					Description: fmt.Sprintf("%s\n%s", spec.Failure.Location.String(), spec.Failure.Location.FullStackTrace),
-				}
-				suite.Failures += 1
-			case types.SpecStateInterrupted:
-				test.Error = &JUnitError{
-					Message:     "interrupted",
-					Type:        "interrupted",
-					Description: spec.Failure.Message,
-				}
-				suite.Errors += 1
-			case types.SpecStateAborted:
-				test.Failure = &JUnitFailure{
-					Message:     spec.Failure.Message,
-					Type:        "aborted",
-					Description: fmt.Sprintf("%s\n%s", spec.Failure.Location.String(), spec.Failure.Location.FullStackTrace),
-				}
-				suite.Errors += 1
-			case types.SpecStatePanicked:
-				test.Error = &JUnitError{
-					Message:     spec.Failure.ForwardedPanic,
-					Type:        "panicked",
-					Description: fmt.Sprintf("%s\n%s", spec.Failure.Location.String(), spec.Failure.Location.FullStackTrace),
-				}
-				suite.Errors += 1
-			}
+func newJUnitTestCase(report types.Report, spec types.SpecReport) JUnitTestCase {
+	name := spec.FullText()
+	labels := spec.Labels()
+	if len(labels) > 0 {
+		name = name + " [" + strings.Join(labels, ", ") + "]"
+	}
+
+	test := JUnitTestCase{
+		Name:      name,
+		Classname: report.SuiteDescription,
+		Status:    spec.State.String(),
+		Time:      spec.RunTime.Seconds(),
+		SystemOut: systemOutForUnstructureReporters(spec),
+		SystemErr: spec.CapturedGinkgoWriterOutput,
+	}
+	classification := FailureClassification{}
+	if spec.Failed() {
+		test.SystemOut += failureArtifactsLink(spec)
+		classification = ClassifySpecFailure(spec)
+	}
 
-			suite.TestCases = append(suite.TestCases, test)
+	switch spec.State {
+	case types.SpecStateSkipped:
+		message := "skipped"
+		if spec.Failure.Message != "" {
+			message += " - " + spec.Failure.Message
+		}
+		test.Skipped = &JUnitSkipped{Message: message}
+	case types.SpecStatePending:
+		test.Skipped = &JUnitSkipped{Message: "pending"}
+	case types.SpecStateFailed:
+		test.Failure = &JUnitFailure{
+			Message:     taggedFailureMessage(classification, spec.Failure.Message),
+			Type:        "failed",
+			Description: fmt.Sprintf("%s%s\n%s", possibleSolution(classification), spec.Failure.Location.String(), spec.Failure.Location.FullStackTrace),
+		}
+	case types.SpecStateInterrupted:
+		test.Error = &JUnitError{
+			Message:     taggedFailureMessage(classification, "interrupted"),
+			Type:        "interrupted",
+			Description: possibleSolution(classification) + spec.Failure.Message,
+		}
+	case types.SpecStateAborted:
+		test.Failure = &JUnitFailure{
+			Message:     taggedFailureMessage(classification, spec.Failure.Message),
+			Type:        "aborted",
+			Description: fmt.Sprintf("%s%s\n%s", possibleSolution(classification), spec.Failure.Location.String(), spec.Failure.Location.FullStackTrace),
+		}
+	case types.SpecStatePanicked:
+		test.Error = &JUnitError{
+			Message:     taggedFailureMessage(classification, spec.Failure.ForwardedPanic),
+			Type:        "panicked",
+			Description: fmt.Sprintf("%s%s\n%s", possibleSolution(classification), spec.Failure.Location.String(), spec.Failure.Location.FullStackTrace),
 		}
 	}
+	return test
+}
+
+// taggedFailureMessage prefixes the message with the tag of the failure, unless the condition
+// checkers already tagged it.
+func taggedFailureMessage(classification FailureClassification, message string) string {
+	if classification.Tag == "" || strings.Contains(message, "tag: "+classification.Tag) {
+		return message
+	}
+	return classification.Tag + " " + message
+}
 
-	junitReport := []JUnitTestSuite{suite}
+func possibleSolution(classification FailureClassification) string {
+	if classification.Tag == "" {
+		return ""
+	}
+	return fmt.Sprintf("Failure tag: %s, Possible Solution: %s\n", classification.Tag, classification.PossibleSolution())
+}
 
+// addTestCase appends the test case of the spec and counts it by the spec state.
+func (suite *JUnitTestSuite) addTestCase(spec types.SpecReport, test JUnitTestCase) {
+	suite.Tests += 1
+	switch spec.State {
+	case types.SpecStateSkipped:
+		suite.Skipped += 1
+	case types.SpecStatePending:
+		suite.Disabled += 1
+	case types.SpecStateFailed:
+		suite.Failures += 1
+	case types.SpecStateInterrupted, types.SpecStateAborted, types.SpecStatePanicked:
+		suite.Errors += 1
+	}
+	suite.TestCases = append(suite.TestCases, test)
+}
+
+// MergeJUnitReports combines the JUnit files, each holding one or more <testsuite> or a
+// <testsuites>, into a single <testsuites> document written to dst.
+func MergeJUnitReports(dst string, srcs ...string) (*JUnitTestSuites, error) {
+	if len(srcs) == 0 {
+		return nil, fmt.Errorf("ERROR no JUnit report to merge")
+	}
+	merged := &JUnitTestSuites{}
+	for _, src := range srcs {
+		suites, err := ReadJUnitReport(src)
+		if err != nil {
+			return nil, err
+		}
+		for _, suite := range suites {
+			merged.Tests += suite.Tests
+			merged.Disabled += suite.Disabled
+			merged.Skipped += suite.Skipped
+			merged.Errors += suite.Errors
+			merged.Failures += suite.Failures
+			merged.Time += suite.Time
+			merged.TestSuites = append(merged.TestSuites, suite)
+		}
+	}
+	if len(merged.TestSuites) > 0 {
+		merged.Name = merged.TestSuites[0].Name
+	}
+	if err := writeJUnitFile(dst, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// ReadJUnitReport returns the test suites of a JUnit file, whether its root is a <testsuite>,
+// a <testsuites> or a sequence of <testsuite> as written by GenerateJUnitReport.
+func ReadJUnitReport(path string) ([]JUnitTestSuite, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to open the JUnit report %s: %v", path, err)
+	}
+	defer f.Close()
+
+	suites := []JUnitTestSuite{}
+	decoder := xml.NewDecoder(f)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ERROR failed to parse the JUnit report %s: %v", path, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "testsuites":
+			var testSuites JUnitTestSuites
+			if err := decoder.DecodeElement(&testSuites, &start); err != nil {
+				return nil, fmt.Errorf("ERROR failed to parse the JUnit report %s: %v", path, err)
+			}
+			suites = append(suites, testSuites.TestSuites...)
+		case "testsuite":
+			var suite JUnitTestSuite
+			if err := decoder.DecodeElement(&suite, &start); err != nil {
+				return nil, fmt.Errorf("ERROR failed to parse the JUnit report %s: %v", path, err)
+			}
+			suites = append(suites, suite)
+		default:
+			return nil, fmt.Errorf("ERROR %s is not a JUnit report: unexpected <%s>", path, start.Name.Local)
+		}
+	}
+	if len(suites) == 0 {
+		return nil, fmt.Errorf("ERROR %s has no test suite", path)
+	}
+	return suites, nil
+}
+
+func writeJUnitFile(dst string, v interface{}) error {
+	if dir := filepath.Dir(dst); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("ERROR failed to create the directory of %s: %v", dst, err)
+		}
+	}
 	f, err := os.Create(dst)
 	if err != nil {
-		return err
+		return fmt.Errorf("ERROR failed to create %s: %v", dst, err)
+	}
+	if _, err := f.WriteString(xml.Header); err != nil {
+		f.Close()
+		return fmt.Errorf("ERROR failed to write %s: %v", dst, err)
 	}
-	f.WriteString(xml.Header)
 	encoder := xml.NewEncoder(f)
 	encoder.Indent("  ", "    ")
-	encoder.Encode(junitReport)
-
-	return f.Close()
+	if err := encoder.Encode(v); err != nil {
+		f.Close()
+		return fmt.Errorf("ERROR failed to encode %s: %v", dst, err)
+	}
+	if err := encoder.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("ERROR failed to write %s: %v", dst, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("ERROR failed to write %s: %v", dst, err)
+	}
+	return nil
 }
 
 func systemOutForUnstructureReporters(spec types.SpecReport) string {
@@ -179,3 +349,16 @@ func systemOutForUnstructureReporters(spec types.SpecReport) string {
 	}
 	return systemOut
 }
+
+// failureArtifactsLink points at the artifacts collected by CollectFailureArtifacts for the spec,
+// if there are any.
+func failureArtifactsLink(spec types.SpecReport) string {
+	dir := SpecArtifactsDir(spec)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+	if absDir, err := filepath.Abs(dir); err == nil {
+		dir = absDir
+	}
+	return fmt.Sprintf("\nFailure artifacts: %s\n", dir)
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+func testReport(suite string, states ...types.SpecState) types.Report {
+	report := types.Report{SuiteDescription: suite, StartTime: time.Date(2024, 4, 1, 10, 0, 0, 0, time.UTC), RunTime: time.Minute}
+	for i, state := range states {
+		report.SpecReports = append(report.SpecReports, types.SpecReport{
+			LeafNodeType:   types.NodeTypeIt,
+			LeafNodeText:   suite + " spec " + string(rune('a'+i)),
+			LeafNodeLabels: []string{TYPE_AWS},
+			State:          state,
+			RunTime:        10 * time.Second,
+		})
+	}
+	return report
+}
+
+func TestGenerateJUnitReportProperties(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "results", "create_cluster_result.xml")
+	metadata := RunMetadata{HubAPIURL: "https://api.hub.example.com:6443", MCEVersion: "2.6.0", ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0-multi"}
+	report := testReport("Hypershift E2e Suite", types.SpecStatePassed, types.SpecStateSkipped)
+	report.SpecReports[0].ReportEntries = types.ReportEntries{{Name: HostedClusterReportEntry, Value: types.WrapEntryValue("clusters/acmqe-hc-1")}}
+
+	if err := GenerateJUnitReport(report, dst, metadata.WithReport(report).Properties()...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	suites, err := ReadJUnitReport(dst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suites) != 1 || suites[0].Properties == nil {
+		t.Fatalf("expected one suite with properties, got %+v", suites)
+	}
+	got := map[string]string{}
+	for _, p := range suites[0].Properties.Properties {
+		got[p.Name] = p.Value
+	}
+	want := map[string]string{
+		"hub.apiURL":    metadata.HubAPIURL,
+		"mce.version":   "2.6.0",
+		"release.image": metadata.ReleaseImage,
+		"platforms":     TYPE_AWS,
+		"clusters":      "clusters/acmqe-hc-1",
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected empty properties to be left out, got %v", got)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("expected property %s=%q, got %q", name, value, got[name])
+		}
+	}
+}
+
+func TestGenerateJUnitReportWriteError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "results"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := GenerateJUnitReport(testReport("Hypershift E2e Suite"), filepath.Join(dir, "results", "result.xml"))
+	if err == nil {
+		t.Fatal("expected an error when the report cannot be written")
+	}
+}
+
+func TestMergeJUnitReports(t *testing.T) {
+	dir := t.TempDir()
+	create := filepath.Join(dir, "create_cluster_result.xml")
+	e2e := filepath.Join(dir, "e2e_result.xml")
+	destroy := filepath.Join(dir, "destroy_cluster_result.xml")
+	if err := GenerateJUnitReport(testReport("create", types.SpecStatePassed, types.SpecStateFailed), create); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateJUnitReport(testReport("e2e", types.SpecStatePassed, types.SpecStateSkipped, types.SpecStatePanicked), e2e); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateJUnitReport(testReport("destroy", types.SpecStatePassed), destroy); err != nil {
+		t.Fatal(err)
+	}
+
+	// merging a merged report keeps the suites
+	first := filepath.Join(dir, "merged", "create-e2e.xml")
+	if _, err := MergeJUnitReports(first, create, e2e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dst := filepath.Join(dir, "merged", "junit.xml")
+	merged, err := MergeJUnitReports(dst, first, destroy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged.Tests != 6 || merged.Failures != 1 || merged.Errors != 1 || merged.Skipped != 1 || merged.Time != 180 {
+		t.Errorf("unexpected aggregate counts: %+v", merged)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `<testsuites name="create" tests="6" disabled="0" skipped="1" errors="1" failures="1" time="180">`) {
+		t.Errorf("unexpected merged report:\n%s", data)
+	}
+	suites, err := ReadJUnitReport(dst)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, suite := range suites {
+		names = append(names, suite.Name)
+	}
+	if strings.Join(names, ",") != "create,e2e,destroy" {
+		t.Errorf("expected the suites in input order, got %v", names)
+	}
+
+	if _, err := MergeJUnitReports(dst); err == nil {
+		t.Error("expected an error without input")
+	}
+	notJUnit := filepath.Join(dir, "options.xml")
+	if err := os.WriteFile(notJUnit, []byte("<options></options>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MergeJUnitReports(dst, create, notJUnit); err == nil || !strings.Contains(err.Error(), "is not a JUnit report") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
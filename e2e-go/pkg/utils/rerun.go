@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+// RerunFailedFromEnv lists the JUnit reports, comma separated, whose failed specs are the only
+// ones to run.
+const RerunFailedFromEnv = "RERUN_FAILED_FROM"
+
+// Characters ginkgo does not allow in a label, so a name suffix containing them is spec text.
+const invalidLabelChars = "&|!,()/[]"
+
+// JUnitSpec is a spec identified by a test case of a report written by GenerateJUnitReport.
+type JUnitSpec struct {
+	Suite  string
+	Text   string
+	Labels []string
+}
+
+// ParseJUnitTestCaseName splits a test case name written by GenerateJUnitReport into the full
+// text of the spec and the labels it appends as " [label, ...]".
+func ParseJUnitTestCaseName(name string) (string, []string) {
+	i := strings.LastIndex(name, " [")
+	if i < 0 || !strings.HasSuffix(name, "]") {
+		return name, nil
+	}
+	labels := strings.Split(name[i+2:len(name)-1], ", ")
+	for _, label := range labels {
+		if label == "" || strings.TrimSpace(label) != label || strings.ContainsAny(label, invalidLabelChars) {
+			return name, nil
+		}
+	}
+	return name[:i], labels
+}
+
+// FailedJUnitSpecs returns the specs that failed, panicked, were interrupted or aborted in the
+// JUnit reports, once each.
+func FailedJUnitSpecs(paths ...string) ([]JUnitSpec, error) {
+	specs := []JUnitSpec{}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		suites, err := ReadJUnitReport(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, suite := range suites {
+			for _, test := range suite.TestCases {
+				if testCaseOutcome(test) != SpecOutcomeFailed || seen[test.Classname+"\x00"+test.Name] {
+					continue
+				}
+				seen[test.Classname+"\x00"+test.Name] = true
+				text, labels := ParseJUnitTestCaseName(test.Name)
+				specs = append(specs, JUnitSpec{Suite: test.Classname, Text: text, Labels: labels})
+			}
+		}
+	}
+	return specs, nil
+}
+
+// RerunFocus returns a ginkgo focus regex matching exactly the specs. Ginkgo matches it against
+// the suite description followed by the full text of the spec, without the labels. The label
+// suffix is optional in the regex so a spec text ending in brackets also matches.
+func RerunFocus(specs []JUnitSpec) string {
+	alternatives := []string{}
+	for _, spec := range specs {
+		alternative := regexp.QuoteMeta(spec.Suite + " " + spec.Text)
+		if len(spec.Labels) > 0 {
+			alternative += "(?:" + regexp.QuoteMeta(" ["+strings.Join(spec.Labels, ", ")+"]") + ")?"
+		}
+		alternatives = append(alternatives, alternative)
+	}
+	return "^(?:" + strings.Join(alternatives, "|") + ")$"
+}
+
+// FocusFailedSpecs replaces the focus of the suite configuration with the specs that failed in
+// the JUnit reports listed in RERUN_FAILED_FROM. It returns nil when the env is not set and an
+// empty list, leaving the focus untouched, when no spec failed.
+func FocusFailedSpecs(suiteConfig *types.SuiteConfig) ([]JUnitSpec, error) {
+	from := os.Getenv(RerunFailedFromEnv)
+	if from == "" {
+		return nil, nil
+	}
+	paths := strings.Split(from, ",")
+	for _, path := range paths {
+		// ginkgo runs the suite in the directory of the test package, not where it was invoked
+		if _, err := os.Stat(path); err != nil && !filepath.IsAbs(path) {
+			wd, _ := os.Getwd()
+			return nil, fmt.Errorf("ERROR the JUnit report %s of %s is resolved against the suite directory %s, pass an absolute path (e.g. $(pwd)/%s): %v",
+				path, RerunFailedFromEnv, wd, path, err)
+		}
+	}
+	specs, err := FailedJUnitSpecs(paths...)
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return specs, nil
+	}
+	suiteConfig.FocusStrings = []string{RerunFocus(specs)}
+	fmt.Printf("Re-running %d failed spec(s) from %s with focus %s\n", len(specs), from, suiteConfig.FocusStrings[0])
+	return specs, nil
+}
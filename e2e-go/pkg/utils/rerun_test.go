@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/onsi/ginkgo/v2/types"
+)
+
+func TestParseJUnitTestCaseName(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantText   string
+		wantLabels []string
+	}{
+		{name: "Create AWS hosted cluster Creates an AWS Hosted Cluster [e2e, create, aws]", wantText: "Create AWS hosted cluster Creates an AWS Hosted Cluster", wantLabels: []string{"e2e", "create", "aws"}},
+		{name: "RHACM4K-21843: Hypershift: S3 secret (upgrade)", wantText: "RHACM4K-21843: Hypershift: S3 secret (upgrade)"},
+		{name: "Metrics are exported [a|b]", wantText: "Metrics are exported [a|b]"},
+		{name: "Metrics are exported []", wantText: "Metrics are exported []"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, labels := ParseJUnitTestCaseName(tt.name)
+			if text != tt.wantText || !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("expected %q %v, got %q %v", tt.wantText, tt.wantLabels, text, labels)
+			}
+		})
+	}
+}
+
+func TestRerunFocus(t *testing.T) {
+	dir := t.TempDir()
+	suite := "Hypershift E2e Suite"
+	specs := types.SpecReports{
+		{LeafNodeType: types.NodeTypeIt, ContainerHierarchyTexts: []string{"Create AWS hosted cluster"}, LeafNodeText: "Creates an AWS Hosted Cluster (STS)", LeafNodeLabels: []string{"create", "aws"}, State: types.SpecStateFailed},
+		{LeafNodeType: types.NodeTypeIt, ContainerHierarchyTexts: []string{"Create AWS hosted cluster"}, LeafNodeText: "Creates an AWS Hosted Cluster (STS) with FIPS", State: types.SpecStatePassed},
+		{LeafNodeType: types.NodeTypeIt, LeafNodeText: "Upgrades the NodePools [4.16]", State: types.SpecStatePanicked},
+		{LeafNodeType: types.NodeTypeIt, LeafNodeText: "Destroys the hosted cluster", State: types.SpecStateSkipped},
+	}
+	first := filepath.Join(dir, "create_cluster_result.xml")
+	if err := GenerateJUnitReport(types.Report{SuiteDescription: suite, SpecReports: specs}, first); err != nil {
+		t.Fatal(err)
+	}
+	// the same failure in a second report is re-run once
+	second := filepath.Join(dir, "merged", "junit.xml")
+	if _, err := MergeJUnitReports(second, first); err != nil {
+		t.Fatal(err)
+	}
+
+	failed, err := FailedJUnitSpecs(first, second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []JUnitSpec{
+		{Suite: suite, Text: "Create AWS hosted cluster Creates an AWS Hosted Cluster (STS)", Labels: []string{"create", "aws"}},
+		// a text ending in a valid label list cannot be told apart, the focus matches both
+		{Suite: suite, Text: "Upgrades the NodePools", Labels: []string{"4.16"}},
+	}
+	if !reflect.DeepEqual(failed, want) {
+		t.Fatalf("expected %+v, got %+v", want, failed)
+	}
+
+	focus := regexp.MustCompile(RerunFocus(failed))
+	for _, spec := range specs {
+		// ginkgo matches the focus against the suite description and the spec text
+		if got, want := focus.MatchString(suite+" "+spec.FullText()), spec.Failed(); got != want {
+			t.Errorf("expected focus %s to match %q: %t", focus, spec.FullText(), want)
+		}
+	}
+}
+
+func TestFocusFailedSpecsRelativePath(t *testing.T) {
+	t.Setenv(RerunFailedFromEnv, "results/missing_result.xml")
+	suiteConfig := types.SuiteConfig{}
+	_, err := FocusFailedSpecs(&suiteConfig)
+	if err == nil || !strings.Contains(err.Error(), "pass an absolute path (e.g. $(pwd)/results/missing_result.xml)") {
+		t.Fatalf("expected the relative path to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onsi/ginkgo/v2/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Platforms a spec can be labelled with.
+var platformLabels = []string{TYPE_AWS, TYPE_KUBEVIRT, TYPE_AGENT}
+
+// RunMetadata describes the hub and the hosted clusters of a test run. It is attached to the
+// JUnit report as properties.
+type RunMetadata struct {
+	HubAPIURL     string
+	MCEVersion    string
+	ACMVersion    string
+	HCPCLIVersion string
+	ReleaseImage  string
+	Platforms     []string
+	Clusters      []string
+}
+
+// GetRunMetadata reads the MCE and ACM versions from the hub and the version of the hcp CLI.
+// Anything that cannot be read is left empty.
+func GetRunMetadata(dynamicClient dynamic.Interface, hubAPIURL, releaseImage string) RunMetadata {
+	metadata := RunMetadata{HubAPIURL: hubAPIURL, ReleaseImage: releaseImage}
+	if mce, err := GetDynamicResource(dynamicClient, MultiClusterEngineGVR); err == nil {
+		metadata.MCEVersion, _, _ = unstructured.NestedString(mce.Object, "status", "currentVersion")
+	}
+	if mch, err := GetDynamicResource(dynamicClient, MultiClusterHubGVR); err == nil {
+		metadata.ACMVersion, _, _ = unstructured.NestedString(mch.Object, "status", "currentVersion")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, HypershiftCLIName, "version").Output(); err == nil {
+		metadata.HCPCLIVersion, _, _ = strings.Cut(strings.TrimSpace(string(out)), "\n")
+	} else {
+		fmt.Printf("Failed to get the %s version: %v\n", HypershiftCLIName, err)
+	}
+	return metadata
+}
+
+// WithReport adds the platforms of the specs that ran and the HostedClusters they reported.
+func (m RunMetadata) WithReport(report types.Report) RunMetadata {
+	platforms := map[string]bool{}
+	clusters := map[string]bool{}
+	for _, spec := range report.SpecReports {
+		if spec.State.Is(types.SpecStateSkipped | types.SpecStatePending) {
+			continue
+		}
+		for _, label := range spec.Labels() {
+			for _, platform := range platformLabels {
+				if label == platform {
+					platforms[platform] = true
+				}
+			}
+		}
+		for _, hc := range HostedClustersFromReport(spec) {
+			clusters[hc.Namespace+"/"+hc.Name] = true
+		}
+	}
+	m.Platforms = sortedKeys(platforms, m.Platforms)
+	m.Clusters = sortedKeys(clusters, m.Clusters)
+	return m
+}
+
+func sortedKeys(set map[string]bool, existing []string) []string {
+	for _, key := range existing {
+		set[key] = true
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Properties returns the metadata as JUnit properties.
+func (m RunMetadata) Properties() []JUnitProperty {
+	return []JUnitProperty{
+		{Name: "hub.apiURL", Value: m.HubAPIURL},
+		{Name: "mce.version", Value: m.MCEVersion},
+		{Name: "acm.version", Value: m.ACMVersion},
+		{Name: "hcp.version", Value: m.HCPCLIVersion},
+		{Name: "release.image", Value: m.ReleaseImage},
+		{Name: "platforms", Value: strings.Join(m.Platforms, ",")},
+		{Name: "clusters", Value: strings.Join(m.Clusters, ",")},
+	}
+}
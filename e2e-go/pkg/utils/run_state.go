@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	RunStateFileEnv  = "RUN_STATE_FILE"
+	RunStateFileName = "state.json"
+)
+
+// ClusterState is a hosted cluster created by the suite.
+type ClusterState struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	Platform     string    `json:"platform"`
+	ReleaseImage string    `json:"releaseImage,omitempty"`
+	InfraID      string    `json:"infraID,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Owner        string    `json:"owner,omitempty"`
+}
+
+// RunState records the hosted clusters created by the create stage so the upgrade and destroy
+// stages of the same run find them without HCP_CLUSTER_NAME.
+type RunState struct {
+	Clusters []ClusterState `json:"clusters"`
+}
+
+// serializes the read-modify-write of the state file by the specs of this process, the lock
+// file of lockRunState by the other processes
+var runStateMutex sync.Mutex
+
+// GetRunStateFile returns the path of the run state.
+// Priority: RUN_STATE_FILE env, else state.json in GetArtifactsDir.
+func GetRunStateFile() string {
+	if path := os.Getenv(RunStateFileEnv); path != "" {
+		return path
+	}
+	return filepath.Join(GetArtifactsDir(), RunStateFileName)
+}
+
+// LoadRunState reads the run state. A missing file is an empty state.
+func LoadRunState(path string) (*RunState, error) {
+	state := &RunState{Clusters: []ClusterState{}}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to read the run state %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("ERROR invalid run state %s: %v", path, err)
+	}
+	return state, nil
+}
+
+// Save writes the run state through a temporary file so a reader never sees a partial state.
+func (s *RunState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ERROR failed to encode the run state: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("ERROR failed to create the run state directory: %v", err)
+	}
+	// a temporary file of its own, so a concurrent Save never renames a partial state
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("ERROR failed to create the run state temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("ERROR failed to write the run state %s: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("ERROR failed to write the run state %s: %v", path, err)
+	}
+	return nil
+}
+
+// Put adds the cluster, replacing a cluster of the same namespace and name.
+func (s *RunState) Put(cluster ClusterState) {
+	s.Remove(cluster.Namespace, cluster.Name)
+	s.Clusters = append(s.Clusters, cluster)
+}
+
+// Remove removes the cluster and reports whether it was in the state.
+func (s *RunState) Remove(namespace, name string) bool {
+	for i, cluster := range s.Clusters {
+		if cluster.Namespace == namespace && cluster.Name == name {
+			s.Clusters = append(s.Clusters[:i], s.Clusters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Latest returns the most recently created cluster of the platform, or of any platform when
+// platform is empty, or nil.
+func (s *RunState) Latest(platform string) *ClusterState {
+	var latest *ClusterState
+	for i, cluster := range s.Clusters {
+		if platform != "" && !strings.EqualFold(cluster.Platform, platform) {
+			continue
+		}
+		if latest == nil || !cluster.CreatedAt.Before(latest.CreatedAt) {
+			latest = &s.Clusters[i]
+		}
+	}
+	return latest
+}
+
+// GetRunState returns the run state from GetRunStateFile. A state that cannot be read is
+// reported and treated as empty.
+func GetRunState() *RunState {
+	state, err := LoadRunState(GetRunStateFile())
+	if err != nil {
+		fmt.Printf("Ignoring the run state: %v\n", err)
+		return &RunState{Clusters: []ClusterState{}}
+	}
+	return state
+}
+
+// updateRunState saves the state when update reports a change. The state is locked from the
+// load to the save, so the parallel ginkgo processes of a stage do not lose each other's clusters.
+func updateRunState(update func(state *RunState) bool) error {
+	runStateMutex.Lock()
+	defer runStateMutex.Unlock()
+
+	path := GetRunStateFile()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("ERROR failed to create the run state directory: %v", err)
+	}
+	unlock, err := lockRunState(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := LoadRunState(path)
+	if err != nil {
+		return err
+	}
+	if !update(state) {
+		return nil
+	}
+	return state.Save(path)
+}
+
+// RecordClusterState adds a created hosted cluster to the run state, with the infra ID of its
+// HostedCluster when it can be read.
+func RecordClusterState(hubClientDynamic dynamic.Interface, platform, namespace, name, releaseImage string) error {
+	cluster := ClusterState{
+		Name:         name,
+		Namespace:    namespace,
+		Platform:     platform,
+		ReleaseImage: releaseImage,
+		CreatedAt:    time.Now().UTC(),
+		Owner:        GetOwner(),
+	}
+	if hc, err := hubClientDynamic.Resource(HostedClustersGVR).Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+		cluster.InfraID, _, _ = unstructured.NestedString(hc.Object, "spec", "infraID")
+		if releaseImage == "" {
+			cluster.ReleaseImage, _, _ = unstructured.NestedString(hc.Object, "spec", "release", "image")
+		}
+	}
+	if err := updateRunState(func(state *RunState) bool {
+		state.Put(cluster)
+		return true
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("Cluster %s: recorded in the run state %s\n", name, GetRunStateFile())
+	return nil
+}
+
+// RemoveClusterState removes a destroyed hosted cluster from the run state.
+func RemoveClusterState(namespace, name string) error {
+	removed := false
+	if err := updateRunState(func(state *RunState) bool {
+		removed = state.Remove(namespace, name)
+		return removed
+	}); err != nil {
+		return err
+	}
+	if removed {
+		fmt.Printf("Cluster %s: removed from the run state %s\n", name, GetRunStateFile())
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package utils_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRunState(t *testing.T) {
+	g := gomega.NewWithT(t)
+	t.Setenv(utils.ArtifactsDirEnv, t.TempDir())
+	t.Setenv("HCP_CLUSTER_NAME", "")
+	t.Setenv("HCP_NAMESPACE", "")
+	saved := utils.TestOptions
+	t.Cleanup(func() { utils.TestOptions = saved })
+	utils.TestOptions.Options.HostedCluster.AWS.Name = ""
+
+	// nothing recorded yet
+	name, err := utils.GetClusterName("aws")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.BeEmpty())
+	g.Expect(utils.RemoveClusterState("clusters", "acmqe-hc-1")).To(gomega.Succeed())
+	_, err = os.Stat(utils.GetRunStateFile())
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue(), "removing from an empty state does not write it")
+
+	hub := fakehub.New(fakehub.Options{})
+	releaseImage := "quay.io/openshift-release-dev/ocp-release:4.15.5-multi"
+	hc := fakehub.NewHostedCluster("clusters-aws", "acmqe-hc-1", utils.TYPE_AWS, releaseImage, "")
+	g.Expect(unstructured.SetNestedField(hc.Object, "acmqe-hc-1-x7k2p", "spec", "infraID")).To(gomega.Succeed())
+	_, err = hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters-aws").Create(context.TODO(), hc, metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Expect(utils.RecordClusterState(hub.Dynamic, utils.TYPE_AWS, "clusters-aws", "acmqe-hc-1", "")).To(gomega.Succeed())
+	g.Expect(utils.RecordClusterState(hub.Dynamic, utils.TYPE_KUBEVIRT, "clusters", "acmqe-hc-2", releaseImage)).To(gomega.Succeed())
+
+	state, err := utils.LoadRunState(filepath.Join(os.Getenv(utils.ArtifactsDirEnv), utils.RunStateFileName))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state.Clusters).To(gomega.HaveLen(2))
+	aws := state.Latest("aws")
+	g.Expect(aws).NotTo(gomega.BeNil())
+	g.Expect(aws.InfraID).To(gomega.Equal("acmqe-hc-1-x7k2p"))
+	g.Expect(aws.ReleaseImage).To(gomega.Equal(releaseImage))
+	g.Expect(aws.Owner).NotTo(gomega.BeEmpty())
+	g.Expect(state.Latest("").Name).To(gomega.Equal("acmqe-hc-2"))
+
+	// the getters fall back to the state
+	name, err = utils.GetClusterName("aws")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.Equal("acmqe-hc-1"))
+	namespace, err := utils.GetNamespace(utils.TYPE_AWS)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(namespace).To(gomega.Equal("clusters-aws"))
+	name, err = utils.GetClusterName(utils.TYPE_KUBEVIRT)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.Equal("acmqe-hc-2"))
+
+	// env and options still win
+	t.Setenv("HCP_CLUSTER_NAME", "acmqe-hc-3")
+	name, err = utils.GetClusterName("aws")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.Equal("acmqe-hc-3"))
+	namespace, err = utils.GetNamespace(utils.TYPE_AWS)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(namespace).To(gomega.Equal("clusters"), "the state namespace belongs to another cluster")
+
+	g.Expect(utils.RemoveClusterState("clusters-aws", "acmqe-hc-1")).To(gomega.Succeed())
+	t.Setenv("HCP_CLUSTER_NAME", "")
+	name, err = utils.GetClusterName("aws")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.BeEmpty())
+}
+
+// runStateWriterEnv makes the test binary a run state writer process for
+// TestRunStateParallelProcesses.
+const runStateWriterEnv = "RUN_STATE_TEST_WRITER"
+
+// TestRunStateParallelProcesses records clusters from several processes at once, like the
+// processes of ginkgo -p, and expects none of them to be lost.
+func TestRunStateParallelProcesses(t *testing.T) {
+	if writer := os.Getenv(runStateWriterEnv); writer != "" {
+		hub := fakehub.New(fakehub.Options{})
+		for i := 0; i < 10; i++ {
+			if err := utils.RecordClusterState(hub.Dynamic, utils.TYPE_AWS, "clusters", fmt.Sprintf("acmqe-hc-%s-%d", writer, i), ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return
+	}
+
+	g := gomega.NewWithT(t)
+	path := filepath.Join(t.TempDir(), "results", utils.RunStateFileName)
+	cmds := []*exec.Cmd{}
+	for writer := 0; writer < 4; writer++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestRunStateParallelProcesses$")
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%d", runStateWriterEnv, writer), utils.RunStateFileEnv+"="+path)
+		g.Expect(cmd.Start()).To(gomega.Succeed())
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		g.Expect(cmd.Wait()).To(gomega.Succeed())
+	}
+
+	state, err := utils.LoadRunState(path)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(state.Clusters).To(gomega.HaveLen(40))
+	leftovers, err := filepath.Glob(path + ".*.tmp")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(leftovers).To(gomega.BeEmpty())
+}
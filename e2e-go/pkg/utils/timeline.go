@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onsi/ginkgo/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ConditionTimelineReportEntry is the report entry holding the ConditionTimeline of a spec.
+	ConditionTimelineReportEntry = "condition-timeline"
+	// ConditionTimelineFile is the name of the timeline JSON written next to the failure artifacts.
+	ConditionTimelineFile = "timeline.json"
+	// TimelineDeleted is the event type recorded when a watched object is deleted.
+	TimelineDeleted = "Deleted"
+
+	// timelineSyncTimeout bounds the initial list of the watched objects, which is retried
+	// forever when the resources are not served or not readable.
+	timelineSyncTimeout = time.Minute
+)
+
+// TimelineEvent is a condition transition observed by a TimelineRecorder. Time is when the
+// recorder saw the transition.
+type TimelineEvent struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// ConditionTimeline is every condition transition of a HostedCluster, its NodePools and its
+// ManagedCluster between Start and End, in the order they were observed.
+type ConditionTimeline struct {
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+	Start     time.Time       `json:"start"`
+	End       time.Time       `json:"end"`
+	Events    []TimelineEvent `json:"events"`
+}
+
+// String renders one line per event with the offset from the start of the timeline.
+func (t ConditionTimeline) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Condition timeline of HostedCluster %s/%s (%s)\n", t.Namespace, t.Name, t.End.Sub(t.Start).Round(time.Second))
+	for _, e := range t.Events {
+		object := e.Kind + " " + e.Name
+		if e.Namespace != "" {
+			object = e.Kind + " " + e.Namespace + "/" + e.Name
+		}
+		fmt.Fprintf(&b, "+%-8s %s %s", e.Time.Sub(t.Start).Round(time.Second), object, e.Type)
+		if e.Status != "" {
+			fmt.Fprintf(&b, "=%s", e.Status)
+		}
+		if e.Reason != "" {
+			fmt.Fprintf(&b, " (%s)", e.Reason)
+		}
+		if e.Message != "" {
+			fmt.Fprintf(&b, " %q", e.Message)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// WriteJSON writes the timeline to dir/timeline.json and returns the file path.
+func (t ConditionTimeline) WriteJSON(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("ERROR failed to create the directory %s: %v", dir, err)
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("ERROR failed to encode the condition timeline: %v", err)
+	}
+	path := filepath.Join(dir, ConditionTimelineFile)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("ERROR failed to write %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// TimelineRecorder watches a HostedCluster, the NodePools with a matching spec.clusterName and
+// the ManagedCluster of the same name, and records every change of the status or reason of
+// their conditions.
+type TimelineRecorder struct {
+	hc     HostedClusterRef
+	start  time.Time
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	end    time.Time
+	events []TimelineEvent
+	last   map[string]TimelineEvent
+}
+
+// StartTimelineRecorder starts watching the HostedCluster and the objects belonging to it. The
+// HostedCluster does not need to exist yet. It fails when the watches have not synced within a
+// minute or before ctx is done. Call Stop to end the recording.
+func StartTimelineRecorder(ctx context.Context, hubClientDynamic dynamic.Interface, namespace, name string) (*TimelineRecorder, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &TimelineRecorder{
+		hc:     HostedClusterRef{Namespace: namespace, Name: name},
+		start:  time.Now(),
+		cancel: cancel,
+		last:   map[string]TimelineEvent{},
+	}
+
+	byName := func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+	watches := []struct {
+		gvr       schema.GroupVersionResource
+		namespace string
+		tweak     dynamicinformer.TweakListOptionsFunc
+		belongs   func(obj *unstructured.Unstructured) bool
+	}{
+		{HostedClustersGVR, namespace, byName, func(obj *unstructured.Unstructured) bool {
+			return obj.GetName() == name
+		}},
+		{NodePoolsGVR, namespace, nil, func(obj *unstructured.Unstructured) bool {
+			clusterName, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterName")
+			return clusterName == name
+		}},
+		{ManagedClustersGVR, metav1.NamespaceAll, byName, func(obj *unstructured.Unstructured) bool {
+			return obj.GetName() == name
+		}},
+	}
+
+	synced := []cache.InformerSynced{}
+	for _, w := range watches {
+		belongs := w.belongs
+		informer := dynamicinformer.NewFilteredDynamicInformer(hubClientDynamic, w.gvr, w.namespace, 0, cache.Indexers{}, w.tweak).Informer()
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				r.observe(obj, belongs, false)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				r.observe(obj, belongs, false)
+			},
+			DeleteFunc: func(obj interface{}) {
+				r.observe(obj, belongs, true)
+			},
+		})
+		if err != nil {
+			cancel()
+			r.wg.Wait()
+			return nil, fmt.Errorf("ERROR failed to watch %s: %v", w.gvr.Resource, err)
+		}
+		synced = append(synced, informer.HasSynced)
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			informer.Run(ctx.Done())
+		}()
+	}
+
+	syncCtx, cancelSync := context.WithTimeout(ctx, timelineSyncTimeout)
+	defer cancelSync()
+	if !cache.WaitForCacheSync(syncCtx.Done(), synced...) {
+		cancel()
+		r.wg.Wait()
+		return nil, fmt.Errorf("ERROR failed to start the condition timeline of HostedCluster %s/%s: %v", namespace, name, syncCtx.Err())
+	}
+	return r, nil
+}
+
+func (r *TimelineRecorder) observe(obj interface{}, belongs func(*unstructured.Unstructured) bool, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || !belongs(u) {
+		return
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.end.IsZero() {
+		return
+	}
+	prefix := strings.Join([]string{u.GetKind(), u.GetNamespace(), u.GetName()}, "/") + "/"
+	if deleted {
+		r.events = append(r.events, TimelineEvent{Time: now, Kind: u.GetKind(), Namespace: u.GetNamespace(), Name: u.GetName(), Type: TimelineDeleted})
+		for key := range r.last {
+			if strings.HasPrefix(key, prefix) {
+				delete(r.last, key)
+			}
+		}
+		return
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		event := TimelineEvent{Time: now, Kind: u.GetKind(), Namespace: u.GetNamespace(), Name: u.GetName()}
+		event.Type, _ = condition["type"].(string)
+		event.Status, _ = condition["status"].(string)
+		event.Reason, _ = condition["reason"].(string)
+		event.Message, _ = condition["message"].(string)
+
+		key := prefix + event.Type
+		if last, seen := r.last[key]; seen && last.Status == event.Status && last.Reason == event.Reason {
+			continue
+		}
+		r.last[key] = event
+		r.events = append(r.events, event)
+	}
+}
+
+// newNoopTimelineRecorder returns a stopped recorder with an empty timeline.
+func newNoopTimelineRecorder(namespace, name string) *TimelineRecorder {
+	now := time.Now()
+	return &TimelineRecorder{
+		hc:     HostedClusterRef{Namespace: namespace, Name: name},
+		start:  now,
+		end:    now,
+		cancel: func() {},
+		last:   map[string]TimelineEvent{},
+	}
+}
+
+// Timeline returns the transitions recorded so far.
+func (r *TimelineRecorder) Timeline() ConditionTimeline {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	end := r.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	events := append([]TimelineEvent{}, r.events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return ConditionTimeline{Namespace: r.hc.Namespace, Name: r.hc.Name, Start: r.start, End: end, Events: events}
+}
+
+// Stop ends the recording and returns the timeline. It is safe to call more than once.
+func (r *TimelineRecorder) Stop() ConditionTimeline {
+	r.cancel()
+	r.wg.Wait()
+	r.mu.Lock()
+	if r.end.IsZero() {
+		r.end = time.Now()
+	}
+	r.mu.Unlock()
+	return r.Timeline()
+}
+
+// RecordConditionTimeline records the condition timeline of the HostedCluster for the rest of the
+// current spec. When the spec ends, the timeline is attached to the spec report and written to
+// <spec artifacts dir>/<name>/timeline.json. The timeline is a diagnostic, so when the recorder
+// cannot start it prints a warning and returns a recorder that records nothing.
+func RecordConditionTimeline(hubClientDynamic dynamic.Interface, namespace, name string) *TimelineRecorder {
+	recorder, err := StartTimelineRecorder(context.Background(), hubClientDynamic, namespace, name)
+	if err != nil {
+		fmt.Printf("WARNING HostedCluster %s: no condition timeline is recorded: %v\n", name, err)
+		return newNoopTimelineRecorder(namespace, name)
+	}
+
+	ginkgo.DeferCleanup(func() {
+		timeline := recorder.Stop()
+		ginkgo.AddReportEntry(ConditionTimelineReportEntry, timeline, ginkgo.ReportEntryVisibilityNever)
+		path, err := timeline.WriteJSON(filepath.Join(SpecArtifactsDir(ginkgo.CurrentSpecReport()), name))
+		if err != nil {
+			fmt.Printf("HostedCluster %s: failed to write the condition timeline: %v\n", name, err)
+			return
+		}
+		fmt.Printf("HostedCluster %s: %d condition transitions written to %s\n", name, len(timeline.Events), path)
+	})
+	return recorder
+}
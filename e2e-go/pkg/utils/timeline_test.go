@@ -0,0 +1,117 @@
+package utils_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestTimelineRecorder(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := fakehub.New(fakehub.Options{StepDelay: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		hub.Wait()
+	})
+	g.Expect(hub.Start(ctx)).To(gomega.Succeed())
+
+	recorder, err := utils.StartTimelineRecorder(ctx, hub.Dynamic, "clusters", "acmqe-hc-1")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	releaseImage := "quay.io/openshift-release-dev/ocp-release:4.15.5-multi"
+	_, err = hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(ctx,
+		fakehub.NewHostedCluster("clusters", "acmqe-hc-1", utils.TYPE_AWS, releaseImage, ""), metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = hub.Dynamic.Resource(utils.NodePoolsGVR).Namespace("clusters").Create(ctx,
+		fakehub.NewNodePool("clusters", "acmqe-hc-1", "acmqe-hc-1", utils.TYPE_AWS, releaseImage, 2, ""), metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Create(ctx,
+		fakehub.NewHostedCluster("clusters", "acmqe-hc-2", utils.TYPE_AWS, releaseImage, ""), metav1.CreateOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	g.Eventually(func() error {
+		return utils.CheckClusterImported(hub.Dynamic, "acmqe-hc-1")
+	}, 10*time.Second, 20*time.Millisecond).Should(gomega.BeNil())
+	g.Eventually(func() []string {
+		return transitions(recorder.Timeline())
+	}, 10*time.Second, 20*time.Millisecond).Should(gomega.ContainElement("NodePool clusters/acmqe-hc-1 Ready=True"))
+
+	g.Expect(hub.Dynamic.Resource(utils.HostedClustersGVR).Namespace("clusters").Delete(ctx, "acmqe-hc-1", metav1.DeleteOptions{})).To(gomega.Succeed())
+	g.Eventually(func() []string {
+		return transitions(recorder.Timeline())
+	}, 10*time.Second, 20*time.Millisecond).Should(gomega.ContainElement("HostedCluster clusters/acmqe-hc-1 Deleted="))
+
+	timeline := recorder.Stop()
+	g.Expect(recorder.Stop().Events).To(gomega.HaveLen(len(timeline.Events)))
+	got := transitions(timeline)
+	g.Expect(got).To(gomega.ContainElements(
+		"HostedCluster clusters/acmqe-hc-1 ValidReleaseImage=True",
+		"HostedCluster clusters/acmqe-hc-1 EtcdAvailable=True",
+		"HostedCluster clusters/acmqe-hc-1 InfrastructureReady=True",
+		"HostedCluster clusters/acmqe-hc-1 Available=False",
+		"HostedCluster clusters/acmqe-hc-1 Available=True",
+		"ManagedCluster /acmqe-hc-1 ManagedClusterConditionAvailable=True",
+	))
+	g.Expect(got).NotTo(gomega.ContainElement(gomega.ContainSubstring("acmqe-hc-2")))
+	g.Expect(indexOf(got, "HostedCluster clusters/acmqe-hc-1 Available=True")).To(
+		gomega.BeNumerically("<", indexOf(got, "ManagedCluster /acmqe-hc-1 ManagedClusterConditionAvailable=True")))
+	g.Expect(timeline.String()).To(gomega.ContainSubstring("HostedCluster clusters/acmqe-hc-1 Available=True (AsExpected)"))
+
+	path, err := timeline.WriteJSON(t.TempDir())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	data, err := os.ReadFile(path)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	var decoded utils.ConditionTimeline
+	g.Expect(json.Unmarshal(data, &decoded)).To(gomega.Succeed())
+	g.Expect(filepath.Base(path)).To(gomega.Equal(utils.ConditionTimelineFile))
+	g.Expect(decoded.Name).To(gomega.Equal("acmqe-hc-1"))
+	g.Expect(decoded.Events).To(gomega.HaveLen(len(timeline.Events)))
+}
+
+// TestTimelineRecorderNotSynced checks that the recorder gives up instead of waiting forever when
+// the HostedClusters cannot be listed.
+func TestTimelineRecorderNotSynced(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := fakehub.New(fakehub.Options{})
+	hub.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("list", "hostedclusters", func(clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewForbidden(utils.HostedClustersGVR.GroupResource(), "", fmt.Errorf("denied"))
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := utils.StartTimelineRecorder(ctx, hub.Dynamic, "clusters", "acmqe-hc-1")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to start the condition timeline")))
+	g.Expect(time.Since(start)).To(gomega.BeNumerically("<", 5*time.Second))
+}
+
+func transitions(timeline utils.ConditionTimeline) []string {
+	got := []string{}
+	for _, e := range timeline.Events {
+		got = append(got, e.Kind+" "+e.Namespace+"/"+e.Name+" "+e.Type+"="+e.Status)
+	}
+	return got
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/towerstub"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	TowerStubHostEnv  = "TOWER_STUB_HOST"
+	TowerStubImageEnv = "TOWER_STUB_IMAGE"
+	TowerStubTokenEnv = "TOWER_STUB_TOKEN"
+
+	// TowerStubName is the name of the Deployment, Service and script ConfigMap of the tower stub deployed by DeployTowerStub
+	TowerStubName = "tower-stub"
+	// TowerStubScriptFile is the script of the job outcomes the tower stub runs with
+	TowerStubScriptFile = "../resources/towerstub/script.yaml"
+
+	// Templates of TowerStubScriptFile whose jobs fail, and run for longer than any jobMonitorTimeout
+	TowerStubFailingTemplate = "e2e-failing-hook"
+	TowerStubSlowTemplate    = "e2e-slow-hook"
+
+	towerStubPort         = 8080
+	towerStubScriptDir    = "/etc/tower-stub"
+	towerStubReadyTimeout = 5 * time.Minute
+)
+
+// GetTowerStubHost returns the URL of a running tower stub the curator hooks use instead of AAP,
+// from TOWER_STUB_HOST, else options.clustercurator.towerStubHost. Empty when not set.
+func GetTowerStubHost() string {
+	if v := os.Getenv(TowerStubHostEnv); v != "" {
+		return v
+	}
+	return TestOptions.Options.ClusterCurator.TowerStubHost
+}
+
+// GetTowerStubImage returns the tower stub image DeployTowerStub deploys for the curator hooks,
+// from TOWER_STUB_IMAGE, else options.clustercurator.towerStubImage. Empty when not set.
+func GetTowerStubImage() string {
+	if v := os.Getenv(TowerStubImageEnv); v != "" {
+		return v
+	}
+	return TestOptions.Options.ClusterCurator.TowerStubImage
+}
+
+// GetTowerStubToken returns the token of the tower stub, from TOWER_STUB_TOKEN, else the default token of the stub.
+func GetTowerStubToken() string {
+	if v := os.Getenv(TowerStubTokenEnv); v != "" {
+		return v
+	}
+	return towerstub.DefaultToken
+}
+
+// NewTowerStubObjects returns the Deployment and Service of the tower stub running image in
+// namespace, and the ConfigMap holding the script it runs with.
+func NewTowerStubObjects(namespace, image, token string, script []byte) (*appsv1.Deployment, *corev1.Service, *corev1.ConfigMap) {
+	labels := map[string]string{"app": TowerStubName}
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: TowerStubName, Namespace: namespace, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  TowerStubName,
+						Image: image,
+						Args:  []string{"-addr", fmt.Sprintf(":%d", towerStubPort), "-script", towerStubScriptDir + "/script.yaml"},
+						Env:   []corev1.EnvVar{{Name: TowerStubTokenEnv, Value: token}},
+						Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: towerStubPort}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{
+								HTTPGet: &corev1.HTTPGetAction{Path: "/api/v2/ping/", Port: intstr.FromInt(towerStubPort)},
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{{Name: "script", MountPath: towerStubScriptDir, ReadOnly: true}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "script",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: TowerStubName}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: TowerStubName, Namespace: namespace, Labels: labels},
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports:    []corev1.ServicePort{{Name: "http", Port: towerStubPort, TargetPort: intstr.FromInt(towerStubPort)}},
+		},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: TowerStubName, Namespace: namespace, Labels: labels},
+		Data:       map[string]string{"script.yaml": string(script)},
+	}
+	return deployment, service, configMap
+}
+
+// ReadTowerStubScript reads and validates the tower stub script at path.
+func ReadTowerStubScript(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR failed to read the tower stub script: %v", err)
+	}
+	script := towerstub.Script{}
+	if err := yaml.UnmarshalStrict(data, &script); err != nil {
+		return nil, fmt.Errorf("ERROR failed to parse the tower stub script %s: %v", path, err)
+	}
+	if err := script.Validate(); err != nil {
+		return nil, fmt.Errorf("ERROR invalid tower stub script %s: %v", path, err)
+	}
+	return data, nil
+}
+
+// DeployTowerStub deploys the tower stub image in namespace with the TowerStubScriptFile script,
+// or updates it, waits until it is ready and returns its in-cluster URL.
+func DeployTowerStub(ctx context.Context, kubeClient kubernetes.Interface, namespace, image, token string) (string, error) {
+	script, err := ReadTowerStubScript(TowerStubScriptFile)
+	if err != nil {
+		return "", err
+	}
+	deployment, service, configMap := NewTowerStubObjects(namespace, image, token, script)
+	_, err = kubeClient.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = kubeClient.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return "", fmt.Errorf("ERROR failed to create the tower stub script: %v", err)
+	}
+	_, err = kubeClient.AppsV1().Deployments(namespace).Create(ctx, deployment, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		_, err = kubeClient.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return "", fmt.Errorf("ERROR failed to deploy the tower stub: %v", err)
+	}
+	_, err = kubeClient.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("ERROR failed to create the tower stub service: %v", err)
+	}
+
+	err = wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		current, err := kubeClient.AppsV1().Deployments(namespace).Get(ctx, TowerStubName, metav1.GetOptions{})
+		if err != nil {
+			fmt.Printf("Tower stub: %v\n", err)
+			return false, nil
+		}
+		return current.Status.ReadyReplicas > 0, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("ERROR the tower stub in %s is not ready: %v", namespace, err)
+	}
+	host := fmt.Sprintf("http://%s.%s.svc:%d", TowerStubName, namespace, towerStubPort)
+	fmt.Printf("Tower stub: ready at %s\n", host)
+	return host, nil
+}
+
+// CreateOrUpdateCuratorTowerSecret creates the tower secret the curator hooks run with. It points at:
+//  1. the tower stub deployed in namespace from GetTowerStubImage, when set
+//  2. the tower stub at GetTowerStubHost, when set
+//  3. else AAP, from AAP_HOST and AAP_TOKEN
+func CreateOrUpdateCuratorTowerSecret(clientClient client.Client, kubeClient kubernetes.Interface, secretName, namespace string) error {
+	if image := GetTowerStubImage(); image != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), towerStubReadyTimeout)
+		defer cancel()
+		host, err := DeployTowerStub(ctx, kubeClient, namespace, image, GetTowerStubToken())
+		if err != nil {
+			return err
+		}
+		return CreateOrUpdateAnsibleTowerSecret(clientClient, secretName, namespace, host, GetTowerStubToken())
+	}
+	if host := GetTowerStubHost(); host != "" {
+		return CreateOrUpdateAnsibleTowerSecret(clientClient, secretName, namespace, host, GetTowerStubToken())
+	}
+	return CreateOrUpdateAnsibleTowerSecret(clientClient, secretName, namespace, "", "")
+}
@@ -0,0 +1,49 @@
+package utils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/fakehub"
+	"github.com/stolostron/hypershift-addon-e2e-tests/e2e-go/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeployTowerStub(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := fakehub.New(fakehub.Options{})
+
+	// the fake hub runs no pods, so the stub never gets ready
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	_, err := utils.DeployTowerStub(ctx, hub.Kube, "clusters", "quay.io/acmqe/tower-stub:latest", "stub-token")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("ERROR the tower stub in clusters is not ready")))
+
+	deployment, err := hub.Kube.AppsV1().Deployments("clusters").Get(context.TODO(), utils.TowerStubName, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	container := deployment.Spec.Template.Spec.Containers[0]
+	g.Expect(container.Image).To(gomega.Equal("quay.io/acmqe/tower-stub:latest"))
+	g.Expect(container.Env[0].Name).To(gomega.Equal(utils.TowerStubTokenEnv))
+	g.Expect(container.Env[0].Value).To(gomega.Equal("stub-token"))
+	g.Expect(container.Args).To(gomega.ContainElement("/etc/tower-stub/script.yaml"))
+	_, err = hub.Kube.CoreV1().Services("clusters").Get(context.TODO(), utils.TowerStubName, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	configMap, err := hub.Kube.CoreV1().ConfigMaps("clusters").Get(context.TODO(), utils.TowerStubName, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configMap.Data["script.yaml"]).To(gomega.ContainSubstring(utils.TowerStubFailingTemplate))
+	g.Expect(configMap.Data["script.yaml"]).To(gomega.ContainSubstring(utils.TowerStubSlowTemplate))
+
+	// deploying again updates the image
+	ctx, cancel = context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	_, err = utils.DeployTowerStub(ctx, hub.Kube, "clusters", "quay.io/acmqe/tower-stub:v2", "stub-token")
+	g.Expect(err).To(gomega.HaveOccurred())
+	deployment, err = hub.Kube.AppsV1().Deployments("clusters").Get(context.TODO(), utils.TowerStubName, metav1.GetOptions{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(gomega.Equal("quay.io/acmqe/tower-stub:v2"))
+
+	t.Setenv(utils.TowerStubTokenEnv, "")
+	g.Expect(utils.GetTowerStubToken()).To(gomega.Equal("tower-stub-token"))
+}
@@ -348,14 +348,6 @@ func GenerateClusterName(prefix string) (string, error) {
 	return uniqueID[:25], nil
 }
 
-func generateErrorMsg(tag, solution, reason, errmsg string) error {
-	return fmt.Errorf("tag: %v, "+
-		"Possible Solution: %v, "+
-		"Reason: %v, "+
-		"Error message: %v,",
-		tag, solution, reason, errmsg)
-}
-
 /*
 - This function returns the Pods list in a namespace
 */